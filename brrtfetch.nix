@@ -15,7 +15,7 @@ stdenv.mkDerivation rec {
 
   buildPhase = ''
     export GOCACHE=$TMPDIR/go-cache
-    go build -o brrtfetch ./go/main.go
+    go build -o brrtfetch ./go
   '';
 
   installPhase = ''
@@ -0,0 +1,116 @@
+// Package bubbletea wraps a brrtfetch animation in a Bubble Tea model, to put
+// the animated art in a TUI of your own. The frames are rendered up front and
+// played with tea.Tick, every model keeps its own time.
+//
+//	anim, err := decode.Open("nyan.gif", decode.VideoOptions{})
+//	if err != nil {
+//		return err
+//	}
+//	logo, err := bubbletea.New(anim, render.DefaultConfig(), nil)
+//	if err != nil {
+//		return err
+//	}
+//
+// Call logo.Init from the Init of the model holding it, hand it every message
+// in Update and put logo.View where the art goes.
+package bubbletea
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/decode"
+	"github.com/ferrebarrat/brrtfetch/pkg/render"
+)
+
+// Every model gets an id, so a program with several of them only advances
+// the one a FrameMsg is meant for
+var lastID int64
+
+// FrameMsg tells a Model its current frame has been shown long enough
+type FrameMsg struct {
+	id  int64
+	tag int
+}
+
+// Model plays an animation as a Bubble Tea component
+type Model struct {
+	frames [][]string
+	delays []time.Duration
+
+	// Loops is how many times the animation plays before it stops on its
+	// last frame, 0 = forever. New takes it from the input.
+	Loops int
+
+	id     int64
+	tag    int // Counts the ticks, a late FrameMsg of an old tick is dropped
+	frame  int
+	played int
+}
+
+// New renders every frame of anim with cfg. sysInfo is placed next to the
+// art like brrtfetch does, nil leaves just the art.
+func New(anim *decode.Animation, cfg render.Config, sysInfo []string) (Model, error) {
+	art, delays, err := render.Prerender(anim, cfg, 0)
+	if err != nil {
+		return Model{}, err
+	}
+	frames := art
+	if sysInfo != nil {
+		frames = render.ComposeAll(art, cfg, sysInfo)
+	}
+	for i := range delays {
+		delays[i] = render.FrameDelay(cfg, delays[i])
+	}
+	return Model{
+		frames: frames,
+		delays: delays,
+		Loops:  anim.Loops,
+		id:     atomic.AddInt64(&lastID, 1),
+	}, nil
+}
+
+// Init starts playing from the first frame
+func (m Model) Init() tea.Cmd {
+	return m.tick()
+}
+
+// Update moves on to the next frame when its FrameMsg arrives, anything else
+// is left alone
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	frameMsg, ok := msg.(FrameMsg)
+	if !ok || frameMsg.id != m.id || frameMsg.tag != m.tag {
+		return m, nil
+	}
+	if m.frame == len(m.frames)-1 {
+		m.played++
+		if m.Loops > 0 && m.played >= m.Loops {
+			return m, nil // The last frame stays
+		}
+	}
+	m.frame = (m.frame + 1) % len(m.frames)
+	m.tag++
+	return m, m.tick()
+}
+
+// View returns the lines of the current frame
+func (m Model) View() string {
+	if len(m.frames) == 0 {
+		return ""
+	}
+	return strings.Join(m.frames[m.frame], "\n")
+}
+
+// tick schedules the FrameMsg that ends the current frame
+func (m Model) tick() tea.Cmd {
+	if len(m.frames) == 0 {
+		return nil
+	}
+	id, tag := m.id, m.tag
+	return tea.Tick(m.delays[m.frame], func(time.Time) tea.Msg {
+		return FrameMsg{id: id, tag: tag}
+	})
+}
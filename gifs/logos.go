@@ -0,0 +1,34 @@
+// Package gifs bundles the distro logos brrtfetch plays when it's started
+// without an input. Only the small ones are embedded, the rest of this
+// directory is there to pick from by hand.
+package gifs
+
+import "embed"
+
+//go:embed distro/linux/arch-purple-glitch-transparent.gif distro/linux/debian.gif distro/linux/ubuntu-rotating.gif distro/windows/win11.gif
+var logos embed.FS
+
+// Logo file of every distro id (the ID of /etc/os-release) with one
+var distroLogos = map[string]string{
+	"arch":    "distro/linux/arch-purple-glitch-transparent.gif",
+	"debian":  "distro/linux/debian.gif",
+	"ubuntu":  "distro/linux/ubuntu-rotating.gif",
+	"windows": "distro/windows/win11.gif",
+}
+
+// Logo returns the bundled logo of the first of ids that has one, with the
+// id it was found for
+func Logo(ids []string) (id string, gif []byte, ok bool) {
+	for _, id := range ids {
+		name, found := distroLogos[id]
+		if !found {
+			continue
+		}
+		data, err := logos.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		return id, data, true
+	}
+	return "", nil, false
+}
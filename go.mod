@@ -0,0 +1,10 @@
+module github.com/ferrebarrat/brrtfetch
+
+go 1.20
+
+require (
+	github.com/creack/pty v1.1.24
+	github.com/fsnotify/fsnotify v1.8.0
+)
+
+require golang.org/x/sys v0.13.0 // indirect
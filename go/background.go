@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/render"
+)
+
+// Values of -bg besides a color
+const (
+	BackgroundAuto = "auto" // ask the terminal
+	BackgroundNone = "none" // draw partly transparent pixels fully opaque
+)
+
+// Values of -matte besides colors
+const (
+	MatteNone    = "none"
+	MatteChecker = "checker"
+)
+
+// parseMatte reads a -matte value into the colors of Config.Matte
+func parseMatte(value string) ([]color.RGBA, error) {
+	switch value {
+	case MatteNone:
+		return nil, nil
+	case MatteChecker:
+		return render.CheckerMatte, nil
+	}
+	var matte []color.RGBA
+	for _, part := range strings.Split(value, ",") {
+		c, err := parseHexColor(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		matte = append(matte, c)
+	}
+	if len(matte) > 2 {
+		return nil, fmt.Errorf("a matte has at most 2 colors")
+	}
+	return matte, nil
+}
+
+// hexColor writes a color as #rrggbb
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// parseHexColor reads a color written as #rrggbb or #rgb
+func parseHexColor(value string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(value, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("%q is not a color, use #rrggbb", value)
+	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}, nil
+}
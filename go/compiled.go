@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Compiled .brrt files start with the magic and the format version. The
+// rest is gzip compressed:
+//
+//	width, height, loops         uvarint
+//	renderer, color mode         string
+//	frame count                  uvarint
+//	every frame:
+//	  delay in microseconds      uvarint
+//	  line count                 uvarint
+//	  every line                 string
+//
+// Strings are their length in bytes as uvarint, followed by the bytes.
+const (
+	compiledMagic   = "BRRT"
+	compiledVersion = 1
+)
+
+// Frames more than this are taken for a broken file, not allocated
+const maxCompiledCount = 1 << 20
+
+// compiledAnimation is the art brrtfetch compile rendered, played without
+// decoding or rendering anything. The delays are those of the input,
+// -speed and -fps still apply when playing.
+type compiledAnimation struct {
+	Width, Height       int
+	Renderer, ColorMode string
+	Loops               int
+	Frames              [][]string
+	Delays              []time.Duration
+}
+
+// render returns the frames like the render cache has them
+func (c *compiledAnimation) render() cachedRender {
+	return cachedRender{Frames: c.Frames, Delays: c.Delays, Loops: c.Loops}
+}
+
+// saveCompiled writes c to the .brrt file at path
+func saveCompiled(path string, c compiledAnimation) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = writeCompiled(f, c)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// writeCompiled writes c in the .brrt format
+func writeCompiled(w io.Writer, c compiledAnimation) error {
+	if _, err := io.WriteString(w, compiledMagic); err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte{compiledVersion}); err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(w)
+	bw := bufio.NewWriter(zw)
+	var buf [binary.MaxVarintLen64]byte
+	putUint := func(v uint64) {
+		bw.Write(buf[:binary.PutUvarint(buf[:], v)])
+	}
+	putString := func(s string) {
+		putUint(uint64(len(s)))
+		bw.WriteString(s)
+	}
+
+	putUint(uint64(c.Width))
+	putUint(uint64(c.Height))
+	putUint(uint64(c.Loops))
+	putString(c.Renderer)
+	putString(c.ColorMode)
+	putUint(uint64(len(c.Frames)))
+	for i, frame := range c.Frames {
+		putUint(uint64(c.Delays[i] / time.Microsecond))
+		putUint(uint64(len(frame)))
+		for _, line := range frame {
+			putString(line)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// loadCompiled reads the .brrt file at path. It returns nil without an
+// error when path is something else, like a GIF.
+func loadCompiled(path string) (*compiledAnimation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header := make([]byte, len(compiledMagic)+1)
+	if _, err := io.ReadFull(f, header); err != nil || string(header[:len(compiledMagic)]) != compiledMagic {
+		return nil, nil
+	}
+	if header[len(compiledMagic)] != compiledVersion {
+		return nil, fmt.Errorf("%s: compiled by another version of brrtfetch, compile it again", path)
+	}
+	c, err := readCompiled(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: broken .brrt file: %v", path, err)
+	}
+	return c, nil
+}
+
+// readCompiled reads what follows the header of a .brrt file
+func readCompiled(r io.Reader) (*compiledAnimation, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(zr)
+	var readErr error
+	getUint := func() uint64 {
+		if readErr != nil {
+			return 0
+		}
+		var v uint64
+		v, readErr = binary.ReadUvarint(br)
+		return v
+	}
+	getCount := func() int {
+		n := getUint()
+		if n > maxCompiledCount && readErr == nil {
+			readErr = errors.New("count out of range")
+		}
+		if readErr != nil {
+			return 0
+		}
+		return int(n)
+	}
+	getString := func() string {
+		b := make([]byte, getCount())
+		if readErr == nil {
+			_, readErr = io.ReadFull(br, b)
+		}
+		return string(b)
+	}
+
+	c := &compiledAnimation{Width: getCount(), Height: getCount(), Loops: getCount()}
+	c.Renderer, c.ColorMode = getString(), getString()
+	for i, n := 0, getCount(); i < n && readErr == nil; i++ {
+		delay := time.Duration(getUint()) * time.Microsecond
+		frame := make([]string, getCount())
+		for j := range frame {
+			frame[j] = getString()
+		}
+		c.Frames = append(c.Frames, frame)
+		c.Delays = append(c.Delays, delay)
+	}
+	if readErr == io.EOF {
+		readErr = io.ErrUnexpectedEOF
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+	if len(c.Frames) == 0 {
+		return nil, errors.New("no frames")
+	}
+	return c, nil
+}
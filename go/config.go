@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ConfigFile holds the settings from ~/.config/brrtfetch/config.toml. Keys
+// are the names of the command line flags, plus "gif" for the input:
+//
+//	gif = "~/Pictures/brrtfetch/gifs/defaults/brrt.gif"
+//	width = 60
+//	info = "fastfetch --logo-type none"
+//
+//	[profile.work]
+//	renderer = "halfblock"
+//	color = false
+//
+//	[source.quote]
+//	command = "fortune -s"
+//	color = "#ebcb8b"
+//
+//	[module.weather]
+//	key = "Weather"
+//	exec = "curl -s wttr.in/?format=3"
+//
+// Only the small subset of TOML needed for that is understood: comments,
+// key = value pairs with quoted strings, numbers and booleans, and
+// [profile.<name>], [source.<name>] and [module.<name>] tables.
+type ConfigFile struct {
+	Values   map[string]string
+	Profiles map[string]map[string]string
+
+	// Extra sysinfo sources in the order of the file, see infoSources
+	Sources []ConfigTable
+
+	// Custom sysinfo modules, see customModules
+	Modules []ConfigTable
+}
+
+// ConfigTable is a [source.<name>] or [module.<name>] table of the config
+// file
+type ConfigTable struct {
+	Name   string
+	Values map[string]string
+}
+
+// defaultConfigPath returns where the config file lives when -config isn't given
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "brrtfetch", "config.toml")
+}
+
+// loadConfigFile parses a config file
+func loadConfigFile(path string) (*ConfigFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cf := &ConfigFile{Values: map[string]string{}, Profiles: map[string]map[string]string{}}
+	section := cf.Values
+	scanner := bufio.NewScanner(f)
+	lineNr := 0
+	for scanner.Scan() {
+		lineNr++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			name, ok := strings.CutSuffix(stripComment(line), "]")
+			name = strings.TrimSpace(strings.TrimPrefix(name, "["))
+			if source, isSource := strings.CutPrefix(name, "source."); ok && isSource && source != "" {
+				section = map[string]string{}
+				cf.Sources = append(cf.Sources, ConfigTable{Name: unquoteKey(source), Values: section})
+				continue
+			}
+			if module, isModule := strings.CutPrefix(name, "module."); ok && isModule && module != "" {
+				section = map[string]string{}
+				cf.Modules = append(cf.Modules, ConfigTable{Name: unquoteKey(module), Values: section})
+				continue
+			}
+			profile, isProfile := strings.CutPrefix(name, "profile.")
+			if !ok || !isProfile || profile == "" {
+				return nil, fmt.Errorf("%s:%d: expected [profile.<name>], [source.<name>] or [module.<name>]", path, lineNr)
+			}
+			profile = unquoteKey(profile)
+			if cf.Profiles[profile] == nil {
+				cf.Profiles[profile] = map[string]string{}
+			}
+			section = cf.Profiles[profile]
+			continue
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected key = value", path, lineNr)
+		}
+		value, err := parseConfigValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, lineNr, err)
+		}
+		section[unquoteKey(strings.TrimSpace(key))] = value
+	}
+	return cf, scanner.Err()
+}
+
+// parseConfigValue turns a TOML value into the string form flag.Set expects
+func parseConfigValue(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		end := closingQuote(raw)
+		if end < 0 {
+			return "", errors.New("unterminated string")
+		}
+		return strconv.Unquote(raw[:end+1])
+	case strings.HasPrefix(raw, "'"):
+		// Literal strings, no escapes
+		end := strings.Index(raw[1:], "'")
+		if end < 0 {
+			return "", errors.New("unterminated string")
+		}
+		return raw[1 : end+1], nil
+	default:
+		value := strings.TrimSpace(stripComment(raw))
+		if value == "" {
+			return "", errors.New("missing value")
+		}
+		return value, nil
+	}
+}
+
+// closingQuote finds the index of the quote ending a basic string
+func closingQuote(raw string) int {
+	for i := 1; i < len(raw); i++ {
+		switch raw[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+func stripComment(s string) string {
+	if i := strings.Index(s, "#"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+func unquoteKey(key string) string {
+	if unquoted, err := strconv.Unquote(key); err == nil {
+		return unquoted
+	}
+	return key
+}
+
+// applyConfig sets every flag that wasn't given on the command line from the
+// config file, profile values win over the top level ones. The configured
+// input is returned separately since it isn't a flag.
+func applyConfig(cf *ConfigFile, profile string) (string, error) {
+	values := map[string]string{}
+	for key, value := range cf.Values {
+		values[key] = value
+	}
+	if profile != "" {
+		profileValues, ok := cf.Profiles[profile]
+		if !ok {
+			return "", fmt.Errorf("profile %q not found in config", profile)
+		}
+		for key, value := range profileValues {
+			values[key] = value
+		}
+	}
+
+	setOnCLI := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		setOnCLI[f.Name] = true
+	})
+
+	var input string
+	for key, value := range values {
+		switch {
+		case key == "gif":
+			input = expandHome(value)
+		case key == "config" || key == "profile":
+			return "", fmt.Errorf("%q can only be set on the command line", key)
+		case flag.Lookup(key) == nil:
+			return "", fmt.Errorf("unknown config option %q", key)
+		case setOnCLI[key]:
+			// Command line flags override the config
+		default:
+			if err := flag.Set(key, value); err != nil {
+				return "", fmt.Errorf("config option %s: %v", key, err)
+			}
+		}
+	}
+	return input, nil
+}
+
+// flagGiven reports whether a flag was set, on the command line or in the
+// config file
+func flagGiven(name string) bool {
+	given := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			given = true
+		}
+	})
+	return given
+}
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/sysinfo"
+)
+
+// configCommand runs brrtfetch config with the arguments after it and
+// returns the exit code
+func configCommand(args []string) int {
+	if len(args) < 2 || len(args) > 3 || args[0] != "import" {
+		fmt.Fprintf(os.Stderr, "Usage: brrtfetch config import <neofetch config.conf | fastfetch config.jsonc> [output]\n")
+		return exitUsage
+	}
+	imported, err := importConfig(expandHome(args[1]))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "brrtfetch: %s\n", errorMessage(err))
+		return exitCode(err)
+	}
+
+	out := io.Writer(os.Stdout)
+	if len(args) == 3 {
+		// Never overwrite a config, it may hold more than an import gives
+		f, err := os.OpenFile(expandHome(args[2]), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "brrtfetch: %s\n", errorMessage(err))
+			return exitFailure
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := imported.write(out); err != nil {
+		fmt.Fprintf(os.Stderr, "brrtfetch: %s\n", errorMessage(err))
+		return exitFailure
+	}
+	return 0
+}
+
+// importedConfig is what a neofetch or fastfetch config translates to
+type importedConfig struct {
+	source  string
+	modules []string
+	custom  []ConfigTable   // Modules with a key of their own
+	color   string          // -info-colors, empty for the default
+	notes   []string        // What couldn't be translated
+	names   map[string]bool // Names of the custom modules
+}
+
+// Modules of neofetch's print_info and of fastfetch by the built-in module
+// they become. Missing ones have no counterpart.
+var (
+	neofetchModules = map[string]string{
+		"title": "title", "distro": "os", "kernel": "kernel", "uptime": "uptime",
+		"shell": "shell", "term": "terminal", "cpu": "cpu", "cpu_usage": "cpu-usage", "gpu": "gpu",
+		"local_ip": "network", "public_ip": "public-ip", "song": "media", "weather": "weather", "memory": "memory", "disk": "disk", "cols": "colors",
+	}
+	fastfetchModules = map[string]string{
+		"title": "title", "os": "os", "kernel": "kernel", "uptime": "uptime", "shell": "shell",
+		"terminal": "terminal", "cpu": "cpu", "memory": "memory", "colors": "colors",
+		"swap": "swap", "cpuusage": "cpu-usage", "gpu": "gpu", "localip": "network", "publicip": "public-ip", "media": "media", "weather": "weather", "disk": "disk", "loadavg": "load", "datetime": "time",
+	}
+)
+
+// Keys the built-in modules show, an imported key that differs gets a module
+// of its own
+var builtinKeys = map[string]string{
+	"os": "OS", "kernel": "Kernel", "uptime": "Uptime", "shell": "Shell", "terminal": "Terminal",
+	"cpu": "CPU", "cpu-usage": "CPU Usage", "gpu": "GPU", "network": "Network", "public-ip": "Public IP", "media": "Media", "weather": "Weather", "temp": "Temp", "memory": "Memory", "swap": "Swap", "load": "Load", "time": "Time",
+}
+
+// importConfig reads a neofetch config.conf or a fastfetch config.jsonc,
+// told apart by their contents
+func importConfig(path string) (*importedConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	imported := &importedConfig{source: path, names: map[string]bool{}}
+	if bytes.HasPrefix(bytes.TrimSpace(stripJSONC(data)), []byte("{")) {
+		err = imported.readFastfetch(data)
+	} else {
+		err = imported.readNeofetch(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(imported.modules) == 0 {
+		return nil, fmt.Errorf("%s: no modules brrtfetch knows", path)
+	}
+	return imported, nil
+}
+
+// addModule adds a built-in module to the list, under key when that isn't
+// the one it has anyway
+func (c *importedConfig) addModule(module, key string) {
+	if key == "" || key == builtinKeys[module] || builtinKeys[module] == "" {
+		c.modules = append(c.modules, module)
+		return
+	}
+	// A name for the module from its key, e.g. "distro" for "Distro"
+	name := strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(key), "-"), "-")
+	if name == "" || sysinfo.ValidateModules(name) == nil {
+		name = "my-" + module
+	}
+	for i, base := 2, name; c.names[name]; i++ {
+		name = base + "-" + strconv.Itoa(i)
+	}
+	c.names[name] = true
+	c.custom = append(c.custom, ConfigTable{Name: name, Values: map[string]string{"key": key, "template": "{" + module + "}"}})
+	c.modules = append(c.modules, name)
+}
+
+// readNeofetch picks the info lines out of print_info and the colors
+// array. Neofetch configs are shell scripts, only these simple forms are
+// understood:
+//
+//	info "Distro" distro
+//	info cols
+//	colors=(4 6 1 8 8 6)
+func (c *importedConfig) readNeofetch(data []byte) error {
+	inPrintInfo := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "print_info()"):
+			inPrintInfo = true
+		case inPrintInfo && line == "}":
+			inPrintInfo = false
+		case inPrintInfo && strings.HasPrefix(line, "info "):
+			args := shellFields(strings.TrimPrefix(line, "info "))
+			key, function := "", ""
+			switch len(args) {
+			case 1:
+				function = args[0]
+			case 2:
+				key, function = args[0], args[1]
+			default:
+				continue
+			}
+			if function == "underline" {
+				continue // The title has one
+			}
+			if module, ok := neofetchModules[function]; ok {
+				c.addModule(module, key)
+			} else {
+				c.notes = append(c.notes, function)
+			}
+		case strings.HasPrefix(line, "colors=("):
+			// Title, @, underline, subtitle, colon and info, the keys
+			// are the subtitles
+			values := strings.Fields(strings.Trim(strings.TrimPrefix(line, "colors="), "()"))
+			if len(values) >= 4 && values[0] != "distro" {
+				if _, err := strconv.Atoi(values[3]); err == nil {
+					c.color = values[3]
+				}
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// shellFields splits the arguments of a shell command, with quotes
+func shellFields(s string) []string {
+	var fields []string
+	var field strings.Builder
+	quote, inField := byte(0), false
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case quote != 0 && ch == quote:
+			quote = 0
+		case quote != 0:
+			field.WriteByte(ch)
+		case ch == '"' || ch == '\'':
+			quote, inField = ch, true
+		case ch == '#' && !inField:
+			return fields
+		case ch == ' ' || ch == '\t':
+			if inField {
+				fields, inField = append(fields, field.String()), false
+				field.Reset()
+			}
+		default:
+			field.WriteByte(ch)
+			inField = true
+		}
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields
+}
+
+// readFastfetch reads the modules and display.color of a fastfetch config
+func (c *importedConfig) readFastfetch(data []byte) error {
+	var config struct {
+		Display struct {
+			Color json.RawMessage `json:"color"`
+		} `json:"display"`
+		Modules []json.RawMessage `json:"modules"`
+	}
+	if err := json.Unmarshal(stripJSONC(data), &config); err != nil {
+		return err
+	}
+	for _, raw := range config.Modules {
+		var entry struct {
+			Type string `json:"type"`
+			Key  string `json:"key"`
+		}
+		if json.Unmarshal(raw, &entry.Type) != nil && json.Unmarshal(raw, &entry) != nil {
+			return errors.New("modules has to be a list of names or objects with a type")
+		}
+		name := strings.ToLower(entry.Type)
+		if name == "separator" || name == "break" {
+			continue
+		}
+		if module, ok := fastfetchModules[name]; ok {
+			c.addModule(module, entry.Key)
+		} else {
+			c.notes = append(c.notes, name)
+		}
+	}
+
+	// Either one color for everything or separate ones, the keys' is used
+	var color string
+	if json.Unmarshal(config.Display.Color, &color) != nil {
+		var colors struct {
+			Keys string `json:"keys"`
+		}
+		json.Unmarshal(config.Display.Color, &colors)
+		color = colors.Keys
+	}
+	if color != "" {
+		if index, ok := fastfetchColor(color); ok {
+			c.color = strconv.Itoa(index)
+		} else {
+			c.notes = append(c.notes, "color "+color)
+		}
+	}
+	return nil
+}
+
+// fastfetchColor turns a fastfetch color, a name like "blue" or
+// "bright_blue" or the SGR code, into a color index
+func fastfetchColor(value string) (int, bool) {
+	names := []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+	name, bright := strings.CutPrefix(strings.ToLower(value), "bright_")
+	for i, n := range names {
+		if name == n {
+			if bright {
+				i += 8
+			}
+			return i, true
+		}
+	}
+	switch code, err := strconv.Atoi(value); {
+	case err == nil && code >= 30 && code <= 37:
+		return code - 30, true
+	case err == nil && code >= 90 && code <= 97:
+		return code - 90 + 8, true
+	}
+	return 0, false
+}
+
+// stripJSONC turns JSON with comments and trailing commas into plain JSON
+func stripJSONC(data []byte) []byte {
+	var out bytes.Buffer
+	inString := false
+	for i := 0; i < len(data); i++ {
+		ch := data[i]
+		switch {
+		case inString:
+			out.WriteByte(ch)
+			if ch == '\\' && i+1 < len(data) {
+				i++
+				out.WriteByte(data[i])
+			} else if ch == '"' {
+				inString = false
+			}
+		case ch == '"':
+			inString = true
+			out.WriteByte(ch)
+		case bytes.HasPrefix(data[i:], []byte("//")):
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			out.WriteByte('\n')
+		case bytes.HasPrefix(data[i:], []byte("/*")):
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				return out.Bytes()
+			}
+			i += end + 3
+		default:
+			out.WriteByte(ch)
+		}
+	}
+	return regexp.MustCompile(`,(\s*[\]}])`).ReplaceAll(out.Bytes(), []byte("$1"))
+}
+
+// write prints the brrtfetch config
+func (c *importedConfig) write(w io.Writer) error {
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "# Imported from %s\n", filepath.Base(c.source))
+	if len(c.notes) > 0 {
+		fmt.Fprintf(b, "# Not available in brrtfetch: %s\n", strings.Join(c.notes, ", "))
+	}
+	fmt.Fprintf(b, "info = %q\n", sysinfo.Native)
+	fmt.Fprintf(b, "modules = %q\n", strings.Join(c.modules, ","))
+	if c.color != "" {
+		fmt.Fprintf(b, "info-colors = %q\n", c.color)
+	}
+	for _, table := range c.custom {
+		fmt.Fprintf(b, "\n[module.%s]\nkey = %q\ntemplate = %q\n", table.Name, table.Values["key"], table.Values["template"])
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/term"
+)
+
+// Commands of the -control socket, one JSON object per line like
+//
+//	{"command": "set-speed", "value": 2}
+const (
+	controlPause  = "pause"
+	controlResume = "resume"
+	controlToggle = "toggle"
+	controlNext   = "next-gif"
+	controlSpeed  = "set-speed"
+	controlReload = "reload-config"
+)
+
+// controlRequest is one line sent to the -control socket
+type controlRequest struct {
+	Command string  `json:"command"`
+	Value   float64 `json:"value,omitempty"`
+}
+
+// controlResponse answers every controlRequest on its own line
+type controlResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// Player commands by the name used on the socket
+var controlCommands = map[string]string{
+	controlPause:  term.CommandPause,
+	controlResume: term.CommandResume,
+	controlToggle: term.CommandToggle,
+	controlNext:   term.CommandNext,
+	controlSpeed:  term.CommandSpeed,
+	controlReload: term.CommandReload,
+}
+
+// listenControl accepts scripts and window manager keybindings on the unix
+// socket at path and hands what they send to commands
+func listenControl(path string, commands chan<- term.Command) (net.Listener, error) {
+	// A socket nobody answers on is left over from an instance that crashed
+	if conn, err := net.DialTimeout("unix", path, daemonTimeout); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("%s is in use by another brrtfetch", path)
+	}
+	os.Remove(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	os.Chmod(path, 0o600)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveControl(conn, commands)
+		}
+	}()
+	return listener, nil
+}
+
+// serveControl answers the requests of one client until it hangs up
+func serveControl(conn net.Conn, commands chan<- term.Command) {
+	defer conn.Close()
+	out := json.NewEncoder(conn)
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var req controlRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			out.Encode(controlResponse{Error: "not a JSON command: " + err.Error()})
+			continue
+		}
+		name, ok := controlCommands[req.Command]
+		switch {
+		case !ok:
+			out.Encode(controlResponse{Error: fmt.Sprintf("unknown command %q", req.Command)})
+			continue
+		case name == term.CommandSpeed && req.Value <= 0:
+			out.Encode(controlResponse{Error: "set-speed needs a value above 0"})
+			continue
+		}
+		// The player only looks between frames, a paused one right away
+		select {
+		case commands <- term.Command{Name: name, Speed: req.Value}:
+			out.Encode(controlResponse{OK: true})
+		case <-time.After(daemonTimeout):
+			out.Encode(controlResponse{Error: "brrtfetch is busy"})
+		}
+	}
+}
+
+// controlSignals turns SIGUSR1 into a toggle and SIGUSR2 into next-gif
+func controlSignals(commands chan<- term.Command) {
+	toggle, next := make(chan os.Signal, 1), make(chan os.Signal, 1)
+	term.NotifyControl(toggle, next)
+	go func() {
+		for {
+			select {
+			case <-toggle:
+				commands <- term.Command{Name: term.CommandToggle}
+			case <-next:
+				commands <- term.Command{Name: term.CommandNext}
+			}
+		}
+	}()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+)
+
+// cropArea is a -crop value, each of x, y, width and height either in pixels
+// or in percent of the frame
+type cropArea struct {
+	values  [4]float64
+	percent [4]bool
+}
+
+// parseCrop reads "x,y,w,h" where every number may end in %, e.g.
+// "10,0,200,150" or "25%,25%,50%,50%"
+func parseCrop(value string) (cropArea, error) {
+	var area cropArea
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return area, fmt.Errorf("%q is not a crop area, use x,y,width,height", value)
+	}
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		area.percent[i] = strings.HasSuffix(part, "%")
+		v, err := strconv.ParseFloat(strings.TrimSuffix(part, "%"), 64)
+		if err != nil || v < 0 {
+			return area, fmt.Errorf("%q is not a crop area, use x,y,width,height in pixels or percent", value)
+		}
+		area.values[i] = v
+	}
+	if area.values[2] == 0 || area.values[3] == 0 {
+		return area, fmt.Errorf("crop area %q is empty", value)
+	}
+	return area, nil
+}
+
+// rect turns the area into pixels of a width x height frame. Percentages of
+// x and width are of the frame's width, those of y and height of its height.
+func (a cropArea) rect(width, height int) image.Rectangle {
+	var px [4]int
+	for i, v := range a.values {
+		if a.percent[i] {
+			size := width
+			if i%2 == 1 {
+				size = height
+			}
+			v = v * float64(size) / 100
+		}
+		px[i] = int(v + 0.5)
+	}
+	return image.Rect(px[0], px[1], px[0]+px[2], px[1]+px[3])
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/gob"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Default memory budget of brrtfetch daemon in MiB
+const defaultDaemonMemory = 1024
+
+// How long a client waits for the daemon before rendering itself
+const daemonTimeout = 2 * time.Second
+
+// Requests of brrtfetch attach to the daemon
+const (
+	daemonGet = "get" // The frames of Key, if the daemon has them
+	daemonPut = "put" // Keep Render under Key
+)
+
+// daemonRequest is what a client sends, one per connection
+type daemonRequest struct {
+	Op     string
+	Key    string // renderCacheKey of the frames
+	Render cachedRender
+}
+
+// daemonResponse answers a daemonRequest
+type daemonResponse struct {
+	Found  bool
+	Render cachedRender
+}
+
+// defaultDaemonSocket is where the daemon listens unless -socket says
+// otherwise: the user's runtime directory, or the cache directory
+func defaultDaemonSocket() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "brrtfetch.sock")
+	}
+	dir, err := cacheDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), fmt.Sprintf("brrtfetch-%d.sock", os.Getuid()))
+	}
+	return filepath.Join(dir, "daemon.sock")
+}
+
+// daemonCommand runs brrtfetch daemon with the arguments after it and
+// returns the exit code. It keeps rendered frames in memory for every
+// brrtfetch attach, until it's stopped.
+func daemonCommand(args []string) int {
+	flags := flag.NewFlagSet("brrtfetch daemon", flag.ContinueOnError)
+	socket := flags.String("socket", defaultDaemonSocket(), "Unix socket to listen on")
+	maxMemory := flags.Int("max-memory", defaultDaemonMemory, "Memory in MiB the kept frames may take, the longest unused ones are dropped first")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Usage: brrtfetch daemon [-socket path] [-max-memory MiB]\n")
+		return exitUsage
+	}
+
+	// A socket nobody answers on is left over from a daemon that crashed
+	if conn, err := net.DialTimeout("unix", *socket, daemonTimeout); err == nil {
+		conn.Close()
+		fmt.Fprintf(os.Stderr, "brrtfetch: a daemon is already listening on %s\n", *socket)
+		return exitFailure
+	}
+	os.Remove(*socket)
+	if err := os.MkdirAll(filepath.Dir(*socket), 0o700); err != nil {
+		fmt.Fprintf(os.Stderr, "brrtfetch: %s\n", errorMessage(err))
+		return exitFailure
+	}
+	listener, err := net.Listen("unix", *socket)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "brrtfetch: %s\n", errorMessage(err))
+		return exitFailure
+	}
+	os.Chmod(*socket, 0o600) // Only for this user's terminals
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		listener.Close() // Removes the socket file too
+	}()
+
+	fmt.Fprintf(os.Stderr, "brrtfetch daemon listening on %s\n", *socket)
+	store := &frameStore{budget: int64(*maxMemory) << 20, entries: map[string]*storedRender{}}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return 0
+			}
+			fmt.Fprintf(os.Stderr, "brrtfetch: %s\n", errorMessage(err))
+			return exitFailure
+		}
+		go store.serve(conn)
+	}
+}
+
+// frameStore holds the frames the daemon keeps, within budget bytes
+type frameStore struct {
+	mu      sync.Mutex
+	budget  int64
+	size    int64
+	entries map[string]*storedRender
+}
+
+// storedRender is one animation in the frameStore
+type storedRender struct {
+	render cachedRender
+	size   int64
+	used   time.Time
+}
+
+// serve answers the request of one client
+func (s *frameStore) serve(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Minute))
+	var req daemonRequest
+	if err := gob.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+	var resp daemonResponse
+	switch req.Op {
+	case daemonGet:
+		resp.Render, resp.Found = s.get(req.Key)
+	case daemonPut:
+		s.put(req.Key, req.Render)
+	}
+	gob.NewEncoder(conn).Encode(resp)
+}
+
+func (s *frameStore) get(key string) (cachedRender, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	if !ok {
+		return cachedRender{}, false
+	}
+	entry.used = time.Now()
+	return entry.render, true
+}
+
+// put keeps render, dropping the longest unused animations when it doesn't
+// fit in the budget. One that's larger than the whole budget isn't kept.
+func (s *frameStore) put(key string, render cachedRender) {
+	if len(render.Frames) == 0 || len(render.Frames) != len(render.Delays) {
+		return
+	}
+	var size int64
+	for _, frame := range render.Frames {
+		for _, line := range frame {
+			size += int64(len(line))
+		}
+	}
+	if size > s.budget {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.entries[key]; ok {
+		s.size -= old.size
+		delete(s.entries, key)
+	}
+	for s.size+size > s.budget {
+		oldest := ""
+		for k, entry := range s.entries {
+			if oldest == "" || entry.used.Before(s.entries[oldest].used) {
+				oldest = k
+			}
+		}
+		s.size -= s.entries[oldest].size
+		delete(s.entries, oldest)
+	}
+	s.entries[key] = &storedRender{render: render, size: size, used: time.Now()}
+	s.size += size
+}
+
+// askDaemon sends req to the daemon on socket and returns its answer
+func askDaemon(socket string, req daemonRequest) (daemonResponse, error) {
+	var resp daemonResponse
+	conn, err := net.DialTimeout("unix", socket, daemonTimeout)
+	if err != nil {
+		return resp, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(daemonTimeout * 5))
+	if err := gob.NewEncoder(conn).Encode(req); err != nil {
+		return resp, err
+	}
+	err = gob.NewDecoder(conn).Decode(&resp)
+	return resp, err
+}
+
+// loadDaemonRender gets the frames of key from the daemon, ok is false when
+// it doesn't have them or isn't running
+func loadDaemonRender(socket, key string) (cachedRender, bool) {
+	resp, err := askDaemon(socket, daemonRequest{Op: daemonGet, Key: key})
+	if err != nil || !resp.Found || len(resp.Render.Frames) == 0 || len(resp.Render.Frames) != len(resp.Render.Delays) {
+		return cachedRender{}, false
+	}
+	return resp.Render, true
+}
+
+// saveDaemonRender hands rendered frames to the daemon for the next attach.
+// Best effort like the render cache, without a daemon nothing happens.
+func saveDaemonRender(socket, key string, render cachedRender) {
+	askDaemon(socket, daemonRequest{Op: daemonPut, Key: key, Render: render})
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/term"
+)
+
+// printTermInfo writes what brrtfetch found out about the terminal, to help
+// figure out why the art looks different than expected.
+func printTermInfo(w io.Writer, colorMode, colorReason string) {
+	fmt.Fprintf(w, "TERM:         %q\n", os.Getenv("TERM"))
+	fmt.Fprintf(w, "COLORTERM:    %q\n", os.Getenv("COLORTERM"))
+	fmt.Fprintf(w, "NO_COLOR:     %q\n", os.Getenv("NO_COLOR"))
+	fmt.Fprintf(w, "Color mode:   %s (%s)\n", colorMode, colorReason)
+	fmt.Fprintf(w, "Output:       %s\n", yesNo(term.IsTerminal(os.Stdout), "terminal", "not a terminal"))
+
+	fmt.Fprintf(w, "Unicode:      %s\n", yesNo(term.UnicodeSupported(), "yes", "no (icons are ASCII)"))
+	if found, known := term.NerdFontInstalled(); known {
+		fmt.Fprintf(w, "Nerd Font:    %s\n", yesNo(found, "installed", "not found (nerd icons become emoji)"))
+	} else {
+		fmt.Fprintf(w, "Nerd Font:    unknown (over SSH the client's fonts are used)\n")
+	}
+
+	if cols, rows, err := term.Size(os.Stdout); err == nil {
+		fmt.Fprintf(w, "Size:         %dx%d cells\n", cols, rows)
+	} else {
+		fmt.Fprintf(w, "Size:         unknown (%v)\n", err)
+	}
+
+	tty, err := term.Open()
+	if err != nil {
+		fmt.Fprintf(w, "Queries:      unavailable (%v)\n", err)
+		return
+	}
+	defer tty.Close()
+
+	sixel := term.SixelSupported(tty)
+	fmt.Fprintf(w, "Sixel:        %s\n", yesNo(sixel, "yes", "no"))
+	if sixel {
+		cellWidth, cellHeight := term.CellPixelSize(tty)
+		fmt.Fprintf(w, "Cell size:    %dx%d pixels\n", cellWidth, cellHeight)
+	}
+	fmt.Fprintf(w, "Synchronized: %s\n", yesNo(term.SyncSupported(tty), "yes", "no"))
+
+	colors, source := term.QueryColors(tty), ""
+	if !colors.HasBackground {
+		colors, source = term.EnvColors(), ", from COLORFGBG"
+	}
+	if colors.HasBackground {
+		fmt.Fprintf(w, "Background:   %s (%s%s)\n", hexColor(colors.Background), yesNo(colors.Dark(), "dark", "light"), source)
+	} else {
+		fmt.Fprintf(w, "Background:   unknown\n")
+	}
+	if colors.HasForeground {
+		fmt.Fprintf(w, "Foreground:   %s\n", hexColor(colors.Foreground))
+	} else {
+		fmt.Fprintf(w, "Foreground:   unknown\n")
+	}
+}
+
+func yesNo(b bool, yes, no string) string {
+	if b {
+		return yes
+	}
+	return no
+}
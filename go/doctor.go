@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/render"
+	"github.com/ferrebarrat/brrtfetch/pkg/term"
+)
+
+// doctorCommand runs brrtfetch doctor, which checks the tools and the
+// terminal brrtfetch works with and tells what to do about what's missing.
+// It returns the exit code.
+func doctorCommand(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "Usage: brrtfetch doctor\n")
+		return exitUsage
+	}
+	runDoctor(os.Stdout)
+	return 0
+}
+
+// runDoctor writes the report of brrtfetch doctor to w
+func runDoctor(w io.Writer) {
+	var hints []string
+	hint := func(format string, args ...interface{}) {
+		hints = append(hints, fmt.Sprintf(format, args...))
+	}
+
+	fmt.Fprintf(w, "Tools\n")
+	tool := func(name, use string) bool {
+		path, err := exec.LookPath(name)
+		fmt.Fprintf(w, "  %-13s %s\n", name+":", yesNo(err == nil, path, "not found ("+use+")"))
+		return err == nil
+	}
+	fastfetch := tool("fastfetch", "the default sysinfo")
+	script := tool("script", "keeps the colors of -info commands")
+	unbuffer := tool("unbuffer", "keeps the colors when script is missing")
+	ffmpeg := tool("ffmpeg", "plays videos")
+	ffprobe := tool("ffprobe", "plays videos")
+
+	if !fastfetch {
+		hint("Install fastfetch for the full sysinfo, until then the built-in modules are shown (like -info native)")
+	}
+	pty := false
+	switch runtime.GOOS {
+	case "linux", "darwin", "freebsd", "openbsd", "netbsd", "dragonfly":
+		pty = true
+	}
+	if !pty && runtime.GOOS != "windows" && !script && !unbuffer {
+		hint("Install script (util-linux) or unbuffer (expect), otherwise -info commands may lose their colors")
+	}
+	if !ffmpeg || !ffprobe {
+		hint("Install ffmpeg, which comes with ffprobe, to play videos like .mp4 and .webm")
+	}
+
+	fmt.Fprintf(w, "\nTerminal\n")
+	colorMode, colorReason := render.DetectColorMode()
+	fmt.Fprintf(w, "  %-13s %s (%s)\n", "Color mode:", colorMode, colorReason)
+	fmt.Fprintf(w, "  %-13s %s\n", "Output:", yesNo(term.IsTerminal(os.Stdout), "terminal", "not a terminal"))
+	unicode := term.UnicodeSupported()
+	fmt.Fprintf(w, "  %-13s %s\n", "Unicode:", yesNo(unicode, "yes", "no"))
+	nerdFont, nerdKnown := term.NerdFontInstalled()
+	switch {
+	case !nerdKnown:
+		fmt.Fprintf(w, "  %-13s unknown (over SSH the client's fonts are used)\n", "Nerd Font:")
+	default:
+		fmt.Fprintf(w, "  %-13s %s\n", "Nerd Font:", yesNo(nerdFont, "installed", "not found"))
+	}
+	fmt.Fprintf(w, "  %-13s %s\n", "Hyperlinks:", yesNo(term.HyperlinksSupported(), "yes", "not a terminal known to have them (-hyperlinks on forces them)"))
+	if !unicode {
+		hint("Use a UTF-8 locale (e.g. LANG=en_US.UTF-8) for the braille, block and symbol renderers and icons")
+	}
+	if nerdKnown && !nerdFont {
+		hint("Install a Nerd Font and use it in the terminal for the glyphs of -icons nerd, without one they become emoji")
+	}
+
+	truecolor, sixel, kitty, sync, passthrough := false, false, false, false, false
+	tty, err := term.Open()
+	if err != nil {
+		fmt.Fprintf(w, "  %-13s unavailable (%v)\n", "Queries:", err)
+	} else {
+		truecolor = term.TruecolorSupported(tty)
+		sixel = term.SixelSupported(tty)
+		kitty = term.KittySupported(tty)
+		sync = term.SyncSupported(tty)
+		passthrough = !sixel && term.InTmux() && term.TmuxSixelSupported(tty)
+		tty.Close()
+		fmt.Fprintf(w, "  %-13s %s\n", "Truecolor:", yesNo(truecolor, "yes", "no answer"))
+		fmt.Fprintf(w, "  %-13s %s\n", "Sixel:", yesNo(sixel, "yes", yesNo(passthrough, "through tmux passthrough", "no")))
+		fmt.Fprintf(w, "  %-13s %s\n", "Kitty images:", yesNo(kitty, "yes", "no"))
+		fmt.Fprintf(w, "  %-13s %s\n", "Synchronized:", yesNo(sync, "yes", "no"))
+	}
+	if term.InTmux() && !sixel {
+		hint("Inside tmux 3.3 or later, sixel and kitty images only reach the terminal with `set -g allow-passthrough on`")
+	}
+	switch {
+	case colorMode == render.ColorNone:
+		hint("Colors are off (%s), unset NO_COLOR or pick one with -color-mode", colorReason)
+	case colorMode != render.ColorTrue && truecolor:
+		hint("The terminal answered with 24-bit color, export COLORTERM=truecolor for the exact colors of the art")
+	}
+
+	fmt.Fprintf(w, "\nFeatures\n")
+	feature := func(name string, on bool, detail string) {
+		fmt.Fprintf(w, "  %-13s %s\n", name+":", yesNo(on, "on", "off")+detail)
+	}
+	feature("Sysinfo", true, yesNo(fastfetch, ", from fastfetch", ", from the built-in modules"))
+	switch {
+	case pty:
+		feature("Info colors", true, ", through a pseudo-terminal")
+	case runtime.GOOS == "windows":
+		feature("Info colors", true, ", as far as the command writes them to a pipe")
+	case script || unbuffer:
+		feature("Info colors", true, ", through "+yesNo(script, "script", "unbuffer"))
+	default:
+		feature("Info colors", false, ", only commands that always color their output")
+	}
+	feature("Colors", colorMode != render.ColorNone, " ("+colorMode+")")
+	feature("Videos", ffmpeg && ffprobe, "")
+	feature("Sixel", sixel || passthrough, yesNo(sixel || passthrough, " (-renderer sixel)", ""))
+	feature("Icons", unicode, yesNo(unicode, yesNo(nerdFont, ", -icons nerd shows Nerd Font glyphs", ", -icons nerd shows emoji"), ", only -icons ascii"))
+
+	if len(hints) == 0 {
+		fmt.Fprintf(w, "\nEverything brrtfetch can use is there.\n")
+		return
+	}
+	fmt.Fprintf(w, "\nHints\n")
+	for _, h := range hints {
+		fmt.Fprintf(w, "  * %s\n", h)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/decode"
+)
+
+// Exit codes, so scripts can tell why brrtfetch gave up
+const (
+	exitFailure     = 1 // anything not listed below
+	exitUsage       = 2 // invalid flags
+	exitNotFound    = 3 // the input file does not exist
+	exitUnsupported = 4 // the input is not a format brrtfetch can read
+	exitDecode      = 5 // the input is in a known format but broken
+)
+
+// exitCode picks the exit code that describes err
+func exitCode(err error) int {
+	var decodeErr decode.Error
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return exitNotFound
+	case errors.Is(err, decode.ErrUnsupportedFormat):
+		return exitUnsupported
+	case errors.As(err, &decodeErr):
+		return exitDecode
+	}
+	return exitFailure
+}
+
+// errorMessage describes err in a single line for the user
+func errorMessage(err error) string {
+	var pathErr *os.PathError
+	switch {
+	case errors.Is(err, os.ErrNotExist) && errors.As(err, &pathErr):
+		return fmt.Sprintf("%s: file not found", pathErr.Path)
+	case errors.As(err, &pathErr):
+		return fmt.Sprintf("%s: %v", pathErr.Path, pathErr.Err)
+	}
+	return err.Error()
+}
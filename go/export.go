@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/term"
+)
+
+// Formats selectable with -format for brrtfetch export
+const (
+	ExportShell = "sh"   // POSIX shell script that plays the frames
+	ExportCast  = "cast" // asciinema recording (asciicast v2)
+	ExportGIF   = "gif"  // the frames drawn as an animated GIF
+	ExportANSI  = "ans"  // a file per frame with its escape sequences
+	ExportText  = "txt"  // a file per frame, text only
+	ExportHTML  = "html" // a page that plays the frames
+)
+
+// An exporter writes a recording in one format, either the whole of it to
+// one file or every frame to a file of its own. Files get the given mode.
+type exporter struct {
+	write func(w io.Writer, rec Recording) error
+	frame func(w io.Writer, lines []string) error
+	mode  os.FileMode
+}
+
+var exporters = map[string]exporter{
+	ExportShell: {write: writeShellScript, mode: 0o755}, // Ready to run
+	ExportCast:  {write: writeCast, mode: 0o644},
+	ExportGIF:   {write: writeGIF, mode: 0o644},
+	ExportANSI:  {frame: writeANSIFrame, mode: 0o644},
+	ExportText:  {frame: writeTextFrame, mode: 0o644},
+	ExportHTML:  {write: writeHTML, mode: 0o644},
+}
+
+// exportFormat picks the format for path: the one asked for, otherwise the
+// one its extension names and a shell script when it names none
+func exportFormat(path, format string) (string, bool) {
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(path), ".")
+		if _, ok := exporters[format]; !ok {
+			format = ExportShell
+		}
+	}
+	_, ok := exporters[format]
+	return format, ok
+}
+
+// Recording is a fully composed animation, art and sysinfo, ready to be
+// written out by an exporter
+type Recording struct {
+	Frames [][]string
+	Delays []time.Duration
+	Loops  int // 0 = forever
+
+	// Default colors of the terminal, for formats that draw the frames
+	// themselves
+	Foreground, Background color.RGBA
+}
+
+// exportRecording writes rec to path in the given format. Formats with a
+// file per frame number them: out.ans becomes out-001.ans, out-002.ans, ...
+func exportRecording(path, format string, rec Recording) error {
+	exp, ok := exporters[format]
+	if !ok {
+		return fmt.Errorf("unknown export format %q", format)
+	}
+	if exp.frame == nil {
+		return writeExportFile(path, exp.mode, func(w io.Writer) error {
+			return exp.write(w, rec)
+		})
+	}
+
+	ext := filepath.Ext(path)
+	digits := len(strconv.Itoa(len(rec.Frames)))
+	if digits < 3 {
+		digits = 3
+	}
+	for i, lines := range rec.Frames {
+		name := fmt.Sprintf("%s-%0*d%s", strings.TrimSuffix(path, ext), digits, i+1, ext)
+		err := writeExportFile(name, exp.mode, func(w io.Writer) error {
+			return exp.frame(w, lines)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeExportFile creates path and fills it with write
+func writeExportFile(path string, mode os.FileMode, write func(w io.Writer) error) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	err = write(w)
+	if flushErr := w.Flush(); err == nil {
+		err = flushErr
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// The heredocs holding the frames end with this line. Art lines always start
+// with an escape sequence or a space, so none of them can end one early.
+const shellFrameEnd = "BRRT_FRAME"
+
+// writeShellScript writes a POSIX script that draws every frame of rec and
+// sleeps between them, so the animation plays where brrtfetch isn't
+// installed. Fractional sleeps need the sleep of GNU, BSD or busybox, which
+// is about every system still around.
+func writeShellScript(w io.Writer, rec Recording) error {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	b.WriteString("# Recorded with brrtfetch, plays in any terminal with ANSI colors\n\n")
+	b.WriteString("trap 'printf \"\\033[0m\\033[?25h\\n\"; exit 130' INT TERM\n")
+	b.WriteString("printf '\\033[?25l\\033[2J'\n\n")
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return err
+	}
+
+	// One function per frame, the loop below only calls them
+	for i, frame := range rec.Frames {
+		b.Reset()
+		fmt.Fprintf(&b, "frame%d() {\n\tcat <<'%s'\n", i, shellFrameEnd)
+		for _, line := range frame {
+			b.WriteString(line)
+			b.WriteString("\033[0m\033[K\n")
+		}
+		fmt.Fprintf(&b, "%s\n}\n", shellFrameEnd)
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+
+	b.Reset()
+	b.WriteString("\nplay() {\n")
+	for i := range rec.Frames {
+		fmt.Fprintf(&b, "\tprintf '\\033[H'; frame%d\n", i)
+		if i < len(rec.Delays) && rec.Delays[i] > 0 {
+			fmt.Fprintf(&b, "\tsleep %.3f\n", rec.Delays[i].Seconds())
+		}
+	}
+	b.WriteString("}\n\n")
+	if rec.Loops > 0 {
+		fmt.Fprintf(&b, "i=0\nwhile [ $i -lt %d ]; do\n\tplay\n\ti=$((i + 1))\ndone\n", rec.Loops)
+	} else {
+		b.WriteString("while :; do\n\tplay\ndone\n")
+	}
+	b.WriteString("printf '\\033[0m\\033[?25h'\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Header of an asciicast v2 file, see
+// https://docs.asciinema.org/manual/asciicast/v2/
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// writeCast writes rec as an asciinema recording: a header line and then one
+// output event per frame, each at the time it's drawn. Animations that loop
+// forever are recorded once, the player can loop them. The terminal of the
+// recording is exactly as big as the largest frame.
+func writeCast(w io.Writer, rec Recording) error {
+	header := castHeader{Version: 2, Timestamp: time.Now().Unix(), Env: map[string]string{"TERM": "xterm-256color"}}
+	for _, frame := range rec.Frames {
+		if len(frame) > header.Height {
+			header.Height = len(frame)
+		}
+		for _, line := range frame {
+			if width := term.VisibleWidth(line); width > header.Width {
+				header.Width = width
+			}
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(header); err != nil {
+		return err
+	}
+	event := func(at time.Duration, data string) error {
+		return enc.Encode([]interface{}{at.Seconds(), "o", data})
+	}
+
+	if err := event(0, "\033[?25l\033[2J"); err != nil {
+		return err
+	}
+	loops := rec.Loops
+	if loops <= 0 {
+		loops = 1
+	}
+	var at time.Duration
+	var b strings.Builder
+	for loop := 0; loop < loops; loop++ {
+		for i, frame := range rec.Frames {
+			// The recording is replayed on a raw terminal, lines need a
+			// carriage return as well
+			b.Reset()
+			b.WriteString("\033[H")
+			for j, line := range frame {
+				if j > 0 {
+					b.WriteString("\r\n")
+				}
+				b.WriteString(line)
+				b.WriteString("\033[0m\033[K")
+			}
+			if err := event(at, b.String()); err != nil {
+				return err
+			}
+			if i < len(rec.Delays) {
+				at += rec.Delays[i]
+			}
+		}
+	}
+	return event(at, "\033[?25h")
+}
+
+// writeANSIFrame writes a frame the way -still prints it, for players of
+// .ans files and MOTD scripts that cat it
+func writeANSIFrame(w io.Writer, lines []string) error {
+	printFrame(w, lines)
+	return nil
+}
+
+// writeTextFrame writes a frame without any colors or other escape
+// sequences, and without the spaces at the end of the lines
+func writeTextFrame(w io.Writer, lines []string) error {
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, strings.TrimRight(term.StripEscapes(line), " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/render"
+	"github.com/ferrebarrat/brrtfetch/pkg/term"
+)
+
+// Colors of the exported image where the output leaves them to the
+// terminal and it didn't say what they are
+var (
+	defaultExportForeground = color.RGBA{229, 229, 229, 255}
+	defaultExportBackground = color.RGBA{0, 0, 0, 255}
+)
+
+// A character of a frame with the colors it's drawn in. The second column of
+// a wide character has char 0.
+type coloredCell struct {
+	char   rune
+	fg, bg color.RGBA
+}
+
+// writeGIF draws every frame of rec with the built-in font and writes them
+// as an animated GIF. Colors come from the SGR sequences of the frames,
+// output with more than 256 of them is drawn with the 256 color palette.
+func writeGIF(w io.Writer, rec Recording) error {
+	// Work out the colors of every cell first, the palette needs them all
+	frames := make([][][]coloredCell, len(rec.Frames))
+	cols, rows := 1, 1
+	used := map[color.RGBA]int{rec.Background: 0}
+	for i, frame := range rec.Frames {
+		if len(frame) > rows {
+			rows = len(frame)
+		}
+		frames[i] = make([][]coloredCell, len(frame))
+		for y, line := range frame {
+			cells := colorCells(line, rec.Foreground, rec.Background)
+			if len(cells) > cols {
+				cols = len(cells)
+			}
+			for _, c := range cells {
+				for _, rgb := range []color.RGBA{c.fg, c.bg} {
+					if _, ok := used[rgb]; !ok && len(used) <= 256 {
+						used[rgb] = len(used)
+					}
+				}
+			}
+			frames[i][y] = cells
+		}
+	}
+
+	pal := make(color.Palette, 0, 256)
+	index := func(c color.RGBA) uint8 { return uint8(used[c]) }
+	if len(used) <= 256 {
+		pal = pal[:len(used)]
+		for rgb, i := range used {
+			pal[i] = rgb
+		}
+	} else {
+		for i := 0; i < 256; i++ {
+			pal = append(pal, render.PaletteColor(i))
+		}
+		index = func(c color.RGBA) uint8 { return uint8(render.Palette256(c.R, c.G, c.B)) }
+	}
+
+	// GIFs count restarts instead of plays and their delays are in 1/100s,
+	// viewers slow down anything quicker than 2/100s
+	anim := &gif.GIF{LoopCount: rec.Loops - 1}
+	switch rec.Loops {
+	case 0:
+		anim.LoopCount = 0
+	case 1:
+		anim.LoopCount = -1 // No restarts at all, 0 would loop forever
+	}
+	bounds := image.Rect(0, 0, cols*fontCellWidth, rows*fontCellHeight)
+	for i, frame := range frames {
+		img := image.NewPaletted(bounds, pal)
+		bg := index(rec.Background)
+		for p := range img.Pix {
+			img.Pix[p] = bg
+		}
+		for y, cells := range frame {
+			for x, c := range cells {
+				drawCell(img, x*fontCellWidth, y*fontCellHeight, glyphFor(c.char), index(c.fg), index(c.bg))
+			}
+		}
+
+		delay := 0
+		if i < len(rec.Delays) {
+			delay = int((rec.Delays[i].Milliseconds() + 5) / 10)
+		}
+		if delay < 2 {
+			delay = 2
+		}
+		anim.Image = append(anim.Image, img)
+		anim.Delay = append(anim.Delay, delay)
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// drawCell paints one character cell with its top left corner at x0,y0
+func drawCell(img *image.Paletted, x0, y0 int, g *glyph, fg, bg uint8) {
+	for y := range g {
+		row := img.Pix[(y0+y)*img.Stride+x0:]
+		for x, on := range g[y] {
+			if on {
+				row[x] = fg
+			} else {
+				row[x] = bg
+			}
+		}
+	}
+}
+
+// colorCells splits a line into its characters and their colors. The
+// colors the line doesn't set are fg and bg.
+func colorCells(line string, fg, bg color.RGBA) []coloredCell {
+	cells := term.ParseCells(line)
+	out := make([]coloredCell, len(cells))
+	for i, c := range cells {
+		var r rune
+		if c.Char != "" {
+			r, _ = utf8.DecodeRuneInString(c.Char)
+		}
+		out[i] = coloredCell{char: r, fg: fg, bg: bg}
+
+		if c.Style == "" {
+			continue
+		}
+		var s term.Style
+		s.Apply(strings.TrimSuffix(strings.TrimPrefix(c.Style, "\x1b["), "m"))
+		if rgb, ok := sgrColor(s.FG); ok {
+			out[i].fg = rgb
+		}
+		if rgb, ok := sgrColor(s.BG); ok {
+			out[i].bg = rgb
+		}
+		for _, attr := range strings.Split(s.Attrs, ";") {
+			if attr == "7" { // Reverse video
+				out[i].fg, out[i].bg = out[i].bg, out[i].fg
+			}
+		}
+	}
+	return out
+}
+
+// sgrColor reads a color as term.Style keeps them, e.g. "38;2;255;0;0",
+// "48:5:196" or "91"
+func sgrColor(spec string) (color.RGBA, bool) {
+	fields := strings.FieldsFunc(spec, func(r rune) bool { return r == ';' || r == ':' })
+	if len(fields) == 0 {
+		return color.RGBA{}, false
+	}
+	code, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return color.RGBA{}, false
+	}
+	switch {
+	case code >= 30 && code <= 37:
+		return render.PaletteColor(code - 30), true
+	case code >= 40 && code <= 47:
+		return render.PaletteColor(code - 40), true
+	case code >= 90 && code <= 97:
+		return render.PaletteColor(code - 90 + 8), true
+	case code >= 100 && code <= 107:
+		return render.PaletteColor(code - 100 + 8), true
+	case len(fields) >= 3 && fields[1] == "5":
+		n, err := strconv.Atoi(fields[2])
+		if err != nil || n < 0 || n > 255 {
+			return color.RGBA{}, false
+		}
+		return render.PaletteColor(n), true
+	case len(fields) >= 5 && fields[1] == "2":
+		// The last three, 38:2:id:r:g:b has a color space id in between
+		var c [3]uint8
+		for i, f := range fields[len(fields)-3:] {
+			v, err := strconv.Atoi(f)
+			if err != nil || v < 0 || v > 255 {
+				return color.RGBA{}, false
+			}
+			c[i] = uint8(v)
+		}
+		return color.RGBA{c[0], c[1], c[2], 255}, true
+	}
+	return color.RGBA{}, false
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// The page of an HTML export. The frames are filled in as a JSON array of
+// HTML snippets, the script swaps them in with their delays in ms.
+const htmlPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>brrtfetch</title>
+<style>
+pre.brrtfetch {
+	display: inline-block;
+	margin: 0;
+	padding: 1em;
+	color: %s;
+	background: %s;
+	font-family: "DejaVu Sans Mono", Menlo, Consolas, monospace;
+	font-size: 14px;
+	line-height: 1.2;
+}
+</style>
+</head>
+<body>
+<pre class="brrtfetch" id="brrtfetch"></pre>
+<script>
+(function () {
+	var frames = %s;
+	var delays = %s;
+	var loops = %d; // 0 = forever
+	var pre = document.getElementById("brrtfetch");
+	var frame = 0, played = 0;
+	function show() {
+		pre.innerHTML = frames[frame];
+		var delay = delays[frame];
+		if (++frame === frames.length) {
+			frame = 0;
+			if (loops > 0 && ++played >= loops) {
+				return; // The last frame stays
+			}
+		}
+		setTimeout(show, delay);
+	}
+	show();
+})();
+</script>
+</body>
+</html>
+`
+
+// writeHTML writes rec as a single page that plays the frames in a <pre>,
+// every run of cells with the same colors becomes a <span>
+func writeHTML(w io.Writer, rec Recording) error {
+	frames := make([]string, len(rec.Frames))
+	for i, frame := range rec.Frames {
+		var b strings.Builder
+		for y, line := range frame {
+			if y > 0 {
+				b.WriteByte('\n')
+			}
+			writeHTMLLine(&b, colorCells(line, rec.Foreground, rec.Background), rec.Foreground, rec.Background)
+		}
+		frames[i] = b.String()
+	}
+	delays := make([]int64, len(rec.Frames))
+	for i := range delays {
+		if i < len(rec.Delays) {
+			delays[i] = rec.Delays[i].Milliseconds()
+		}
+	}
+
+	// JSON escapes < and >, so the frames can't end the script early
+	framesJSON, err := json.Marshal(frames)
+	if err != nil {
+		return err
+	}
+	delaysJSON, err := json.Marshal(delays)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, htmlPage, hexColor(rec.Foreground), hexColor(rec.Background), framesJSON, delaysJSON, rec.Loops)
+	return err
+}
+
+// writeHTMLLine writes the cells of a line, the default colors are left to
+// the <pre>
+func writeHTMLLine(b *strings.Builder, cells []coloredCell, fg, bg color.RGBA) {
+	open := false
+	for i, c := range cells {
+		if i == 0 || c.fg != cells[i-1].fg || c.bg != cells[i-1].bg {
+			if open {
+				b.WriteString("</span>")
+				open = false
+			}
+			var style []string
+			if c.fg != fg {
+				style = append(style, "color:"+hexColor(c.fg))
+			}
+			if c.bg != bg {
+				style = append(style, "background:"+hexColor(c.bg))
+			}
+			if len(style) > 0 {
+				fmt.Fprintf(b, `<span style="%s">`, strings.Join(style, ";"))
+				open = true
+			}
+		}
+		if c.char != 0 { // The second column of a wide character is part of the first
+			b.WriteString(html.EscapeString(string(c.char)))
+		}
+	}
+	if open {
+		b.WriteString("</span>")
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+)
+
+// widthFlag is the -width value, a number of columns or "auto" to fit the
+// terminal
+type widthFlag struct {
+	cols int
+	auto bool
+}
+
+func (w *widthFlag) String() string {
+	if w.auto {
+		return "auto"
+	}
+	return strconv.Itoa(w.cols)
+}
+
+func (w *widthFlag) Set(value string) error {
+	if value == "auto" {
+		w.auto = true
+		return nil
+	}
+	cols, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+	if cols < 1 {
+		return errors.New("has to be at least 1, or auto")
+	}
+	w.cols, w.auto = cols, false
+	return nil
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"math"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/render"
+)
+
+// Size in pixels of a character cell when frames are drawn as images, twice
+// as tall as wide like most terminal fonts
+const (
+	fontCellWidth  = 12
+	fontCellHeight = 24
+)
+
+// The printable ASCII characters from ' ' to '~' in a 5x7 font, drawn twice
+// as large in a cell. Every glyph is 5 columns from left to right, bit 0 of a
+// column is its top pixel.
+var font5x7 = [95][5]byte{
+	{0x00, 0x00, 0x00, 0x00, 0x00}, // ' '
+	{0x00, 0x00, 0x5f, 0x00, 0x00}, // !
+	{0x00, 0x07, 0x00, 0x07, 0x00}, // "
+	{0x14, 0x7f, 0x14, 0x7f, 0x14}, // #
+	{0x24, 0x2a, 0x7f, 0x2a, 0x12}, // $
+	{0x23, 0x13, 0x08, 0x64, 0x62}, // %
+	{0x36, 0x49, 0x55, 0x22, 0x50}, // &
+	{0x00, 0x05, 0x03, 0x00, 0x00}, // '
+	{0x00, 0x1c, 0x22, 0x41, 0x00}, // (
+	{0x00, 0x41, 0x22, 0x1c, 0x00}, // )
+	{0x14, 0x08, 0x3e, 0x08, 0x14}, // *
+	{0x08, 0x08, 0x3e, 0x08, 0x08}, // +
+	{0x00, 0x50, 0x30, 0x00, 0x00}, // ,
+	{0x08, 0x08, 0x08, 0x08, 0x08}, // -
+	{0x00, 0x60, 0x60, 0x00, 0x00}, // .
+	{0x20, 0x10, 0x08, 0x04, 0x02}, // /
+	{0x3e, 0x51, 0x49, 0x45, 0x3e}, // 0
+	{0x00, 0x42, 0x7f, 0x40, 0x00}, // 1
+	{0x42, 0x61, 0x51, 0x49, 0x46}, // 2
+	{0x21, 0x41, 0x45, 0x4b, 0x31}, // 3
+	{0x18, 0x14, 0x12, 0x7f, 0x10}, // 4
+	{0x27, 0x45, 0x45, 0x45, 0x39}, // 5
+	{0x3c, 0x4a, 0x49, 0x49, 0x30}, // 6
+	{0x01, 0x71, 0x09, 0x05, 0x03}, // 7
+	{0x36, 0x49, 0x49, 0x49, 0x36}, // 8
+	{0x06, 0x49, 0x49, 0x29, 0x1e}, // 9
+	{0x00, 0x36, 0x36, 0x00, 0x00}, // :
+	{0x00, 0x56, 0x36, 0x00, 0x00}, // ;
+	{0x08, 0x14, 0x22, 0x41, 0x00}, // <
+	{0x14, 0x14, 0x14, 0x14, 0x14}, // =
+	{0x00, 0x41, 0x22, 0x14, 0x08}, // >
+	{0x02, 0x01, 0x51, 0x09, 0x06}, // ?
+	{0x32, 0x49, 0x79, 0x41, 0x3e}, // @
+	{0x7e, 0x11, 0x11, 0x11, 0x7e}, // A
+	{0x7f, 0x49, 0x49, 0x49, 0x36}, // B
+	{0x3e, 0x41, 0x41, 0x41, 0x22}, // C
+	{0x7f, 0x41, 0x41, 0x22, 0x1c}, // D
+	{0x7f, 0x49, 0x49, 0x49, 0x41}, // E
+	{0x7f, 0x09, 0x09, 0x09, 0x01}, // F
+	{0x3e, 0x41, 0x49, 0x49, 0x7a}, // G
+	{0x7f, 0x08, 0x08, 0x08, 0x7f}, // H
+	{0x00, 0x41, 0x7f, 0x41, 0x00}, // I
+	{0x20, 0x40, 0x41, 0x3f, 0x01}, // J
+	{0x7f, 0x08, 0x14, 0x22, 0x41}, // K
+	{0x7f, 0x40, 0x40, 0x40, 0x40}, // L
+	{0x7f, 0x02, 0x0c, 0x02, 0x7f}, // M
+	{0x7f, 0x04, 0x08, 0x10, 0x7f}, // N
+	{0x3e, 0x41, 0x41, 0x41, 0x3e}, // O
+	{0x7f, 0x09, 0x09, 0x09, 0x06}, // P
+	{0x3e, 0x41, 0x51, 0x21, 0x5e}, // Q
+	{0x7f, 0x09, 0x19, 0x29, 0x46}, // R
+	{0x46, 0x49, 0x49, 0x49, 0x31}, // S
+	{0x01, 0x01, 0x7f, 0x01, 0x01}, // T
+	{0x3f, 0x40, 0x40, 0x40, 0x3f}, // U
+	{0x1f, 0x20, 0x40, 0x20, 0x1f}, // V
+	{0x3f, 0x40, 0x38, 0x40, 0x3f}, // W
+	{0x63, 0x14, 0x08, 0x14, 0x63}, // X
+	{0x07, 0x08, 0x70, 0x08, 0x07}, // Y
+	{0x61, 0x51, 0x49, 0x45, 0x43}, // Z
+	{0x00, 0x7f, 0x41, 0x41, 0x00}, // [
+	{0x02, 0x04, 0x08, 0x10, 0x20}, // \
+	{0x00, 0x41, 0x41, 0x7f, 0x00}, // ]
+	{0x04, 0x02, 0x01, 0x02, 0x04}, // ^
+	{0x40, 0x40, 0x40, 0x40, 0x40}, // _
+	{0x00, 0x01, 0x02, 0x04, 0x00}, // `
+	{0x20, 0x54, 0x54, 0x54, 0x78}, // a
+	{0x7f, 0x48, 0x44, 0x44, 0x38}, // b
+	{0x38, 0x44, 0x44, 0x44, 0x20}, // c
+	{0x38, 0x44, 0x44, 0x48, 0x7f}, // d
+	{0x38, 0x54, 0x54, 0x54, 0x18}, // e
+	{0x08, 0x7e, 0x09, 0x01, 0x02}, // f
+	{0x0c, 0x52, 0x52, 0x52, 0x3e}, // g
+	{0x7f, 0x08, 0x04, 0x04, 0x78}, // h
+	{0x00, 0x44, 0x7d, 0x40, 0x00}, // i
+	{0x20, 0x40, 0x44, 0x3d, 0x00}, // j
+	{0x7f, 0x10, 0x28, 0x44, 0x00}, // k
+	{0x00, 0x41, 0x7f, 0x40, 0x00}, // l
+	{0x7c, 0x04, 0x18, 0x04, 0x78}, // m
+	{0x7c, 0x08, 0x04, 0x04, 0x78}, // n
+	{0x38, 0x44, 0x44, 0x44, 0x38}, // o
+	{0x7c, 0x14, 0x14, 0x14, 0x08}, // p
+	{0x08, 0x14, 0x14, 0x18, 0x7c}, // q
+	{0x7c, 0x08, 0x04, 0x04, 0x08}, // r
+	{0x48, 0x54, 0x54, 0x54, 0x20}, // s
+	{0x04, 0x3f, 0x44, 0x40, 0x20}, // t
+	{0x3c, 0x40, 0x40, 0x20, 0x7c}, // u
+	{0x1c, 0x20, 0x40, 0x20, 0x1c}, // v
+	{0x3c, 0x40, 0x30, 0x40, 0x3c}, // w
+	{0x44, 0x28, 0x10, 0x28, 0x44}, // x
+	{0x0c, 0x50, 0x50, 0x50, 0x3c}, // y
+	{0x44, 0x64, 0x54, 0x4c, 0x44}, // z
+	{0x00, 0x08, 0x36, 0x41, 0x00}, // {
+	{0x00, 0x00, 0x7f, 0x00, 0x00}, // |
+	{0x00, 0x41, 0x36, 0x08, 0x00}, // }
+	{0x08, 0x04, 0x08, 0x10, 0x08}, // ~
+}
+
+// A glyph tells for every pixel of a cell whether it's drawn in the
+// foreground color
+type glyph [fontCellHeight][fontCellWidth]bool
+
+var glyphCache = map[rune]*glyph{}
+
+// glyphFor returns the pixels of r. ASCII comes from font5x7, the block,
+// shade, braille and circle characters the renderers use are drawn to fill
+// the cell like a terminal does, anything else becomes an empty box.
+func glyphFor(r rune) *glyph {
+	if g, ok := glyphCache[r]; ok {
+		return g
+	}
+	g := new(glyph)
+	switch {
+	case r == ' ' || r == 0:
+	case r > ' ' && r <= '~':
+		columns := font5x7[r-' ']
+		for x := 0; x < fontCellWidth; x++ {
+			for y := 0; y < fontCellHeight; y++ {
+				// 2x2 pixels per font pixel, one column of space on either side
+				// and room for the line spacing above and below
+				fx, fy := (x-1)/2, (y-5)/2
+				g[y][x] = x >= 1 && fx < 5 && y >= 5 && fy < 7 && columns[fx]&(1<<fy) != 0
+			}
+		}
+	case r >= 0x2800 && r <= 0x28ff:
+		drawBraille(g, r-0x2800)
+	default:
+		if shape, ok := blockShape(r); ok {
+			g.fill(shape)
+		} else if shape, ok := circleShape(r); ok {
+			g.fill(shape)
+		} else {
+			g.fill(func(x, y float64) bool { // A box, like terminals draw missing glyphs
+				return x >= 0.15 && x <= 0.85 && y >= 0.2 && y <= 0.8 &&
+					(x < 0.25 || x > 0.75 || y < 0.25 || y > 0.75)
+			})
+		}
+	}
+	glyphCache[r] = g
+	return g
+}
+
+// fill sets the pixels whose centers are inside shape, which gets positions
+// from 0 to 1 across the cell
+func (g *glyph) fill(shape func(x, y float64) bool) {
+	for y := range g {
+		for x := range g[y] {
+			g[y][x] = shape((float64(x)+0.5)/fontCellWidth, (float64(y)+0.5)/fontCellHeight)
+		}
+	}
+}
+
+// drawBraille puts the dots of the braille pattern bits in a 2x4 grid
+func drawBraille(g *glyph, bits rune) {
+	for row, dots := range render.BrailleDots {
+		for col, dot := range dots {
+			if bits&dot == 0 {
+				continue
+			}
+			x0, y0 := 2+col*5, 1+row*6
+			for y := y0; y < y0+4; y++ {
+				for x := x0; x < x0+3; x++ {
+					g[y][x] = true
+				}
+			}
+		}
+	}
+}
+
+// Stipple densities of the light, medium and dark shades
+var shadeDensity = map[rune]int{'░': 1, '▒': 2, '▓': 3}
+
+// Quadrants of U+2596 to U+259F, as bits: upper left 1, upper right 2,
+// lower left 4, lower right 8
+var quadrants = [10]int{4, 8, 1, 1 | 4 | 8, 1 | 8, 1 | 2 | 4, 1 | 2 | 8, 2, 2 | 4, 2 | 4 | 8}
+
+// blockShape covers the block elements (U+2580 to U+259F) and the wedges of
+// the symbols renderer
+func blockShape(r rune) (func(x, y float64) bool, bool) {
+	switch {
+	case r == '▀':
+		return func(x, y float64) bool { return y < 0.5 }, true
+	case r >= '▁' && r <= '█': // Lower eighths up to the full block
+		h := float64(r-'▁'+1) / 8
+		return func(x, y float64) bool { return y >= 1-h }, true
+	case r >= '▉' && r <= '▏': // Left eighths
+		w := float64('▏'-r+1) / 8
+		return func(x, y float64) bool { return x < w }, true
+	case r >= '◢' && r <= '◥': // Wedges, the corner they fill in order
+		return []func(x, y float64) bool{
+			func(x, y float64) bool { return x+y >= 1 },
+			func(x, y float64) bool { return y >= x },
+			func(x, y float64) bool { return x+y < 1 },
+			func(x, y float64) bool { return y < x },
+		}[r-'◢'], true
+	case r == '▐':
+		return func(x, y float64) bool { return x >= 0.5 }, true
+	case shadeDensity[r] > 0:
+		density := shadeDensity[r]
+		return func(x, y float64) bool {
+			// Every other pixel for the medium shade, one in four or all
+			// but one in four for the others
+			px, py := int(x*fontCellWidth), int(y*fontCellHeight)
+			n := px%2 + 2*(py%2)
+			switch density {
+			case 1:
+				return n == 0
+			case 2:
+				return n == 0 || n == 3
+			}
+			return n != 3
+		}, true
+	case r == '▔':
+		return func(x, y float64) bool { return y < 0.125 }, true
+	case r == '▕':
+		return func(x, y float64) bool { return x >= 0.875 }, true
+	case r >= '▖' && r <= '▟':
+		q := quadrants[r-'▖']
+		return func(x, y float64) bool {
+			bit := 1
+			if x >= 0.5 {
+				bit <<= 1
+			}
+			if y >= 0.5 {
+				bit <<= 2
+			}
+			return q&bit != 0
+		}, true
+	}
+	return nil, false
+}
+
+// circleShape covers the circles of the circles charset
+func circleShape(r rune) (func(x, y float64) bool, bool) {
+	// Distance from the center of the cell in cell widths
+	dist := func(x, y float64) float64 {
+		return math.Hypot(x-0.5, (y-0.5)*fontCellHeight/fontCellWidth)
+	}
+	ring := func(x, y, radius float64) bool {
+		d := dist(x, y)
+		return d <= radius && d >= radius-0.12
+	}
+	switch r {
+	case '●':
+		return func(x, y float64) bool { return dist(x, y) <= 0.35 }, true
+	case '⬤':
+		return func(x, y float64) bool { return dist(x, y) <= 0.48 }, true
+	case '◌':
+		return func(x, y float64) bool {
+			// A ring broken into 8 dots
+			angle := math.Atan2(y-0.5, x-0.5) + math.Pi
+			return ring(x, y, 0.42) && int(angle/(math.Pi/8))%2 == 0
+		}, true
+	case '⦾':
+		return func(x, y float64) bool { return ring(x, y, 0.45) || ring(x, y, 0.24) }, true
+	case '⦿':
+		return func(x, y float64) bool { return ring(x, y, 0.45) || dist(x, y) <= 0.2 }, true
+	}
+	return nil, false
+}
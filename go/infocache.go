@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Sysinfo is cached for this long unless -info-cache-ttl says otherwise
+const defaultInfoCacheTTL = time.Minute
+
+// infoCacheEntry is the sysinfo of one info command, or one list of
+// built-in modules, in the info cache
+type infoCacheEntry struct {
+	Lines []string  `json:"lines"`
+	Saved time.Time `json:"saved"`
+}
+
+// infoCachePath returns where the info cache lives: a JSON object with an
+// entry per key
+func infoCachePath() (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "info.json"), nil
+}
+
+// infoCacheKey identifies sysinfo gathered with the given settings, anything
+// that changes the lines changes the key
+func infoCacheKey(settings ...interface{}) string {
+	h := sha256.New()
+	for _, s := range settings {
+		fmt.Fprintf(h, "%+v\x00", s)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// readInfoCache reads all entries of the info cache, none when it doesn't
+// exist or is damaged
+func readInfoCache(path string) map[string]infoCacheEntry {
+	entries := map[string]infoCacheEntry{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return entries
+	}
+	if json.Unmarshal(data, &entries) != nil {
+		return map[string]infoCacheEntry{}
+	}
+	return entries
+}
+
+// loadInfoCache returns the lines cached for key when they are younger than ttl
+func loadInfoCache(key string, ttl time.Duration) ([]string, bool) {
+	path, err := infoCachePath()
+	if err != nil {
+		return nil, false
+	}
+	entry, ok := readInfoCache(path)[key]
+	if !ok || time.Since(entry.Saved) >= ttl || time.Since(entry.Saved) < 0 {
+		return nil, false
+	}
+	return entry.Lines, true
+}
+
+// saveInfoCache stores lines under key, dropping the entries that are older
+// than ttl while at it. Like the render cache the file is replaced in one go,
+// so terminals starting at the same time never read half of it.
+func saveInfoCache(key string, lines []string, ttl time.Duration) error {
+	path, err := infoCachePath()
+	if err != nil {
+		return err
+	}
+	entries := readInfoCache(path)
+	for k, entry := range entries {
+		if time.Since(entry.Saved) >= ttl {
+			delete(entries, k)
+		}
+	}
+	entries[key] = infoCacheEntry{Lines: lines, Saved: time.Now()}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "info.json.*.part")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/sysinfo"
+)
+
+// infoFlag is the -info value. It can be given more than once, every
+// command is a source of its own.
+type infoFlag struct {
+	commands []string
+	set      bool // Given at least once, the default is replaced
+}
+
+func (f *infoFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(f.commands, ", ")
+}
+
+func (f *infoFlag) Set(value string) error {
+	if !f.set {
+		f.commands, f.set = nil, true
+	}
+	f.commands = append(f.commands, value)
+	return nil
+}
+
+// infoSources lists where the sysinfo comes from: the -info commands, whose
+// output is in format, then the [source.<name>] tables of the config file.
+// Those take a command, an offset in columns, a color, '#rrggbb' or an index
+// like -tint, and a format.
+func infoSources(info infoFlag, format string, configSources []ConfigTable, colorMode string) ([]sysinfo.Source, error) {
+	var sources []sysinfo.Source
+	for _, command := range info.commands {
+		source := sysinfo.Source{Command: command, Default: command == defaultInfoCommand, Format: format}
+		if source.Default && format == sysinfo.FormatFastfetchJSON {
+			source.Command = sysinfo.FastfetchJSONCommand
+		}
+		sources = append(sources, source)
+	}
+	for _, cs := range configSources {
+		source := sysinfo.Source{}
+		for key, value := range cs.Values {
+			var err error
+			switch key {
+			case "command":
+				source.Command = value
+			case "offset":
+				if source.Offset, err = strconv.Atoi(value); err == nil && source.Offset < 0 {
+					err = fmt.Errorf("%d is negative", source.Offset)
+				}
+			case "color":
+				source.Color, err = tintColor(value, colorMode)
+			case "format":
+				source.Format, err = value, validateInfoFormat(value)
+			default:
+				err = fmt.Errorf("unknown option %q, use command, offset, color or format", key)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("source %s: %s: %v", cs.Name, key, err)
+			}
+		}
+		if source.Command == "" {
+			return nil, fmt.Errorf("source %s has no command", cs.Name)
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// validateInfoFormat checks an -info-format value
+func validateInfoFormat(format string) error {
+	if format != sysinfo.FormatText && format != sysinfo.FormatFastfetchJSON {
+		return fmt.Errorf("unknown format %q, use '%s' or '%s'", format, sysinfo.FormatText, sysinfo.FormatFastfetchJSON)
+	}
+	return nil
+}
+
+// customModules adds the [module.<name>] tables of the config file as
+// sysinfo modules, to use in -modules by their name. Those take the command
+// to exec or a template to fill in, the key shown in front of the value (the
+// name when not set, none when empty) and how long the value is cached, e.g.
+// "30m", for slow commands. The info cache keeps them for -info-cache-ttl
+// otherwise, with the rest of the sysinfo. With noCache nothing is cached.
+// Color tags of templates are written for colorMode.
+func customModules(tables []ConfigTable, opts sysinfo.CommandOptions, colorMode string, noCache bool) error {
+	color := func(value string) (string, error) { return tintColor(value, colorMode) }
+	for _, table := range tables {
+		key, command := table.Name, ""
+		var template *sysinfo.Template
+		var ttl time.Duration
+		for option, value := range table.Values {
+			var err error
+			switch option {
+			case "key":
+				key = value
+			case "exec":
+				command = value
+			case "template":
+				template, err = sysinfo.ParseTemplate(value, color)
+			case "cache":
+				if ttl, err = time.ParseDuration(value); err == nil && ttl < 0 {
+					err = fmt.Errorf("%s is negative", value)
+				}
+			default:
+				err = fmt.Errorf("unknown option %q, use key, exec, template or cache", option)
+			}
+			if err != nil {
+				return fmt.Errorf("module %s: %s: %v", table.Name, option, err)
+			}
+		}
+		var module sysinfo.Module
+		switch {
+		case command != "" && template != nil:
+			return fmt.Errorf("module %s has both exec and template, use one of them", table.Name)
+		case command != "":
+			module = sysinfo.CommandModule(key, command, opts)
+		case template != nil:
+			module = sysinfo.TemplateModule(key, template)
+		default:
+			return fmt.Errorf("module %s needs exec or template", table.Name)
+		}
+		if ttl > 0 && !noCache {
+			collect, cacheKey := module.Collect, infoCacheKey("module", table.Values, sysinfo.Hyperlinks)
+			module.Collect = func() (string, error) {
+				if lines, ok := loadInfoCache(cacheKey, ttl); ok {
+					return strings.Join(lines, "\n"), nil
+				}
+				value, err := collect()
+				if err == nil {
+					saveInfoCache(cacheKey, strings.Split(value, "\n"), ttl) // Run again next time when it fails
+				}
+				return value, err
+			}
+		}
+		if err := sysinfo.AddModule(table.Name, module); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/decode"
+	"github.com/ferrebarrat/brrtfetch/pkg/render"
+	"github.com/ferrebarrat/brrtfetch/pkg/sysinfo"
+	"github.com/ferrebarrat/brrtfetch/pkg/term"
+)
+
+// Values of -info-colors
+const (
+	InfoColorsDefault = "default" // the terminal's blue
+	InfoColorsArt     = "art"     // the dominant colors of the art
+)
+
+// keyStyle is how the keys and values of the built-in sysinfo look, from
+// the -info-key-* options. It goes over the colors of -info-colors.
+type keyStyle struct {
+	keyColor, valueColor string // SGR parameters, empty keeps the theme's
+	delimiter            string
+	width                int
+	upper, bold          bool
+}
+
+// apply returns theme with the keys and values styled by s
+func (s keyStyle) apply(theme sysinfo.Theme) sysinfo.Theme {
+	if s.keyColor != "" {
+		theme.Key = s.keyColor
+	}
+	switch key := strings.TrimPrefix(theme.Key, "1;"); {
+	case !s.bold && key == "1":
+		theme.Key = ""
+	case !s.bold:
+		theme.Key = key
+	case key == "" || key == "1":
+		theme.Key = "1"
+	default:
+		theme.Key = "1;" + key
+	}
+	if s.valueColor != "" {
+		theme.Value = s.valueColor
+	}
+	theme.Delimiter, theme.KeyWidth, theme.UpperKeys = s.delimiter, s.width, s.upper
+	return theme
+}
+
+// artTheme styles the sysinfo with the dominant colors of the first frame of
+// path: the keys and title get the most vivid color that covers a good part
+// of it, the separators the next one. Without colors, or when the input
+// can't be read, the default theme stays. The colors module shows all of
+// them, count of them, or a gradient of gradientWidth through them when
+// that isn't 0.
+func artTheme(path, colorMode string, count, gradientWidth int) sysinfo.Theme {
+	if colorMode == render.ColorNone {
+		return sysinfo.DefaultTheme
+	}
+	anim, err := decode.Open(path, decode.VideoOptions{MaxFrames: 1})
+	if err != nil {
+		return sysinfo.DefaultTheme
+	}
+	var first *image.RGBA
+	anim.Frames(func(frame *image.RGBA, delay time.Duration) bool {
+		first = image.NewRGBA(frame.Rect)
+		copy(first.Pix, frame.Pix)
+		return false
+	})
+	if first == nil {
+		return sysinfo.DefaultTheme
+	}
+	colors := render.DominantColors(first, count)
+	if len(colors) == 0 {
+		return sysinfo.DefaultTheme
+	}
+
+	// Colors come most common first. Dark ones are hard to read on most
+	// terminals and don't count, unless there is nothing else.
+	best, second := -1, -1
+	bestScore, secondScore := 0, 0
+	for i, c := range colors {
+		hi, lo := int(c.R), int(c.R)
+		for _, v := range []int{int(c.G), int(c.B)} {
+			if v > hi {
+				hi = v
+			}
+			if v < lo {
+				lo = v
+			}
+		}
+		if hi < 80 {
+			continue
+		}
+		score := (hi - lo + 32) * (len(colors) - i)
+		switch {
+		case score > bestScore:
+			second, secondScore = best, bestScore
+			best, bestScore = i, score
+		case score > secondScore:
+			second, secondScore = i, score
+		}
+	}
+	if best < 0 {
+		best = 0
+	}
+	if second < 0 {
+		second = best
+	}
+
+	theme := sysinfo.Theme{
+		Title:     "1;" + render.SGRColor(colorMode, false, colors[best]),
+		Key:       "1;" + render.SGRColor(colorMode, false, colors[best]),
+		Separator: render.SGRColor(colorMode, false, colors[second]),
+	}
+	if gradientWidth > 0 {
+		for _, c := range gradient(colors, gradientWidth) {
+			theme.Blocks = append(theme.Blocks, render.SGRColor(colorMode, true, c))
+		}
+		return theme
+	}
+	// Art with few colors has several boxes of the same one
+	seen := map[string]bool{}
+	for _, c := range colors {
+		if block := render.SGRColor(colorMode, true, c); !seen[block] {
+			theme.Blocks = append(theme.Blocks, block)
+			seen[block] = true
+		}
+	}
+	return theme
+}
+
+// gradient blends colors from the darkest to the lightest over width steps
+func gradient(colors []color.RGBA, width int) []color.RGBA {
+	stops := append([]color.RGBA(nil), colors...)
+	luma := func(c color.RGBA) int { return 299*int(c.R) + 587*int(c.G) + 114*int(c.B) }
+	sort.SliceStable(stops, func(i, j int) bool { return luma(stops[i]) < luma(stops[j]) })
+	if len(stops) == 1 || width == 1 {
+		out := make([]color.RGBA, width)
+		for i := range out {
+			out[i] = stops[0]
+		}
+		return out
+	}
+
+	out := make([]color.RGBA, width)
+	for i := range out {
+		// Position between the stops, the last step ends on the last one
+		pos := float64(i) * float64(len(stops)-1) / float64(width-1)
+		from := int(pos)
+		if from >= len(stops)-1 {
+			out[i] = stops[len(stops)-1]
+			continue
+		}
+		t := pos - float64(from)
+		a, b := stops[from], stops[from+1]
+		blend := func(x, y uint8) uint8 { return uint8(float64(x) + (float64(y)-float64(x))*t + 0.5) }
+		out[i] = color.RGBA{R: blend(a.R, b.R), G: blend(a.G, b.G), B: blend(a.B, b.B), A: 255}
+	}
+	return out
+}
+
+// iconSet picks the icons the terminal can likely show for -icons: Nerd
+// Font ones become emoji when no Nerd Font is installed, and terminals
+// without Unicode get ASCII
+func iconSet(requested string) string {
+	if requested == sysinfo.IconsNone || requested == sysinfo.IconsASCII {
+		return requested
+	}
+	if !term.UnicodeSupported() {
+		return sysinfo.IconsASCII
+	}
+	if requested == sysinfo.IconsNerd {
+		if found, known := term.NerdFontInstalled(); known && !found {
+			return sysinfo.IconsEmoji
+		}
+	}
+	return requested
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/ferrebarrat/brrtfetch/gifs"
+	"github.com/ferrebarrat/brrtfetch/pkg/sysinfo"
+)
+
+// bundledLogo writes the bundled logo of the running distro to the cache and
+// returns its path, so it plays like any other input. ok is false when there
+// is no logo for this system. With noCache, or without a usable cache, the
+// logo goes to a temporary file the caller removes when done.
+func bundledLogo(noCache bool) (path string, temporary bool, ok bool, err error) {
+	id, data, ok := gifs.Logo(sysinfo.DistroIDs())
+	if !ok {
+		return "", false, false, nil
+	}
+
+	if !noCache {
+		if dir, err := cacheDir(); err == nil {
+			path = filepath.Join(dir, "logos", id+".gif")
+			if existing, err := os.ReadFile(path); err == nil && len(existing) == len(data) {
+				return path, false, true, nil
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err == nil {
+				if err := os.WriteFile(path, data, 0o644); err == nil {
+					return path, false, true, nil
+				}
+			}
+		}
+	}
+
+	f, err := os.CreateTemp("", "brrtfetch-"+id+"-*.gif")
+	if err != nil {
+		return "", false, false, err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", false, false, err
+	}
+	return f.Name(), true, true, nil
+}
@@ -2,45 +2,27 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"flag"
 	"fmt"
-	"image"
 	"image/color"
-	"image/draw"
-	"image/gif"
+	"math/rand"
+	"net"
 	"os"
-	"os/exec"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
-)
-
-// Config struct to hold CLI overrides or defaults
-type Config struct {
-	Width  int
-	Height int
-	FPS    int
-	Color  bool
-}
 
-// Job represents a frame to be rendered concurrently
-type RenderJob struct {
-	Index   int
-	Image   *image.RGBA
-	PoolKey *image.RGBA // Key to return the buffer to the pool
-}
-
-// Result holds the prerendered ASCII strings and their index
-type RenderResult struct {
-	Index int
-	Lines []string
-}
-
-// Global channel for recycling image buffers (the pool)
-var bufferPool chan *image.RGBA
+	"github.com/ferrebarrat/brrtfetch/pkg/decode"
+	"github.com/ferrebarrat/brrtfetch/pkg/render"
+	"github.com/ferrebarrat/brrtfetch/pkg/sysinfo"
+	"github.com/ferrebarrat/brrtfetch/pkg/term"
+)
 
 // ANSI escape codes for cursor control
 const (
@@ -48,304 +30,1382 @@ const (
 	ANSI_SHOW_CURSOR = "\033[?25h"
 )
 
+// Ramp directions selectable with -invert
+const (
+	InvertAuto = "auto"
+	InvertOn   = "on"
+	InvertOff  = "off"
+)
+
+// Playback orders selectable with -direction
+const (
+	DirectionForward  = "forward"
+	DirectionReverse  = "reverse"
+	DirectionPingPong = "pingpong"
+)
+
+// Sysinfo command used unless -info says otherwise
+const defaultInfoCommand = "fastfetch --logo-type none"
+
 func main() {
+	// reload-config starts over with the arguments as they were given
+	originalArgs := append([]string(nil), os.Args...)
+
+	// brrtfetch config works on config files and doesn't play anything
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(configCommand(os.Args[2:]))
+	}
+	// brrtfetch doctor checks what brrtfetch can use on this machine
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		os.Exit(doctorCommand(os.Args[2:]))
+	}
+	// brrtfetch daemon keeps rendered frames in memory for brrtfetch attach
+	if len(os.Args) > 1 && os.Args[1] == "daemon" {
+		os.Exit(daemonCommand(os.Args[2:]))
+	}
+
+	// brrtfetch export writes the animation to a file instead of playing it,
+	// brrtfetch motd prints a single frame for /etc/motd, brrtfetch attach
+	// plays with the frames of the daemon, brrtfetch compile renders a
+	// .brrt file to play later and brrtfetch serve plays over HTTP
+	var command string
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export", "motd", "attach", "compile", "serve":
+			command = os.Args[1]
+			os.Args = append(os.Args[:1:1], os.Args[2:]...)
+		}
+	}
+	exporting, motd, attaching, compiling, serving := command == "export", command == "motd", command == "attach", command == "compile", command == "serve"
+
 	// --- Flags ---
-	width := flag.Int("width", 40, "Width of ASCII animation (in chars)")
-	height := flag.Int("height", -1, "Height of ASCII animation (in chars)")
-	fps := flag.Int("fps", 17, "Frames per second for playback, more fps = faster animation")
+	width := widthFlag{cols: 40}
+	flag.Var(&width, "width", "Width of ASCII animation (in chars), 'auto' picks the largest size where art and sysinfo fit in the terminal")
+	fit := flag.Bool("fit", false, "Same as -width=auto")
+	height := flag.Int("height", -1, "Height of ASCII animation (in character widths, a terminal row covers 1 / -cell-aspect of them: two with most fonts)")
+	cellAspect := flag.Float64("cell-aspect", 0, "Width / height of a terminal character cell, e.g. 0.5 for a font twice as tall as it is wide. 0 = work it out from the pixel size the terminal reports, 0.5 when it doesn't")
+	fps := flag.Int("fps", 0, "Deprecated, use -speed and -max-fps. Frames per second for playback, overrides the frame delays stored in the GIF or the video sampling rate. 0 = use the input's own timing")
+	speed := flag.Float64("speed", 1, "Playback speed, e.g. 1.5 plays half again as fast and 0.5 at half speed, on top of the input's own timing")
+	direction := flag.String("direction", DirectionForward, "Order the frames play in: 'forward', 'reverse' or 'pingpong' (forwards, then backwards, for loops with a visible seam)")
+	maxFPS := flag.Int("max-fps", 0, "Show at most this many frames per second, quicker frames are skipped while the timing stays the same. 0 = no limit")
 	multiplier := flag.Float64("multiplier", 1.2, "Multiplier for ASCII char determination. Higher = denser, lower means whites could be displayed as transparent in some cases")
-	colorOutput := flag.Bool("color", true, "Disable color for animated art with -color=false (true = 24-bit ANSI, false = monochrome)")
-	infoCommand := flag.String("info", "fastfetch --logo-type none", "Command to execute for system information output, make sure you omit the art. By default it will attempt to use 'fastfetch --logo-type none'")
+	background := flag.String("bg", BackgroundAuto, "Color partly transparent pixels are blended against: '#rrggbb', 'auto' (the terminal's background, when it tells) or 'none' (draw them fully opaque)")
+	cropFlag := flag.String("crop", "", "Only show part of the frames: x,y,width,height in pixels or percent, e.g. 25%,0,50%,100%")
+	segment := flag.String("trim", "", "Only play a segment of the animation: START:END as frame numbers (END not included) or times, e.g. 10:40 or 0.5s:3s. Either may be left out")
+	trimBorders := flag.Bool("trim-borders", false, "Cut off the transparent or single color border around the animation, so the art uses all of its space")
+	alphaThreshold := flag.Int("alpha-threshold", 1, "Pixels less opaque than this (0-255) are transparent, raise it to drop the faint fringe around soft edges")
+	matte := flag.String("matte", MatteNone, "What's drawn behind transparent parts: 'none', a color '#rrggbb', 'checker' or two colors '#rrggbb,#rrggbb' for a checkerboard")
+	paletteFlag := flag.String("palette", "", "Snap the colors of the art to a theme, to match your terminal: gruvbox, nord, dracula, solarized or a file with a #rrggbb color per line")
+	invert := flag.String("invert", InvertAuto, "Give the densest characters to the lightest pixels instead of the darkest: 'on', 'off' or 'auto' (on without colors on a dark terminal background)")
+	autoLevels := flag.Bool("auto-levels", true, "Stretch the darkest to the lightest pixels of the animation over the whole character ramp, -auto-levels=false maps brightness as it is")
+	brightness := flag.Float64("brightness", 0, "Brightness adjustment for the ascii renderer, from -1 (everything dense) to 1 (everything light)")
+	contrast := flag.Float64("contrast", 1, "Contrast adjustment for the ascii renderer, above 1 spreads the characters further apart")
+	gamma := flag.Float64("gamma", 1, "Gamma adjustment for the ascii renderer, above 1 lightens the dark parts")
+	charset := flag.String("charset", render.DefaultCharset, "Characters used by the ascii renderer from lightest to densest, e.g. \" .:-=+*#%@\", or a preset: circles, classic, blocks, dots, shade")
+	colorOutput := flag.Bool("color", true, "Disable color for animated art with -color=false (true = colors as picked by -color-mode, false = monochrome)")
+	tint := flag.String("tint", "", "Single color the art is drawn in with -color=false, '#rrggbb' or a color index (0-255), instead of the terminal's foreground")
+	colorMode := flag.String("color-mode", "", "Colors used for the art: 'truecolor' (24-bit), '256', '16' or 'none'. Picked from COLORTERM and TERM when not set")
+	info := infoFlag{commands: []string{defaultInfoCommand}}
+	flag.Var(&info, "info", "Command to execute for system information output, make sure you omit the art. By default it will attempt to use 'fastfetch --logo-type none' and fall back to the built-in modules when fastfetch isn't installed. 'native' always uses the built-in modules. Give it more than once to show the output of several commands below each other")
+	infoFormat := flag.String("info-format", sysinfo.FormatText, "What the info command prints: 'text' (shown as it is) or 'fastfetch-json' (the output of fastfetch --format json, shown in the style of the built-in sysinfo). With fastfetch-json the default command is 'fastfetch --format json'")
+	infoSeparator := flag.String("info-separator", "", "Line put between the outputs of several -info commands and config sources")
+	modules := flag.String("modules", sysinfo.DefaultModules, "Comma separated list of built-in sysinfo modules in display order, used with -info native. Available: title, os, kernel, hostname, uptime, shell, terminal, cpu, cpu-usage, temp, gpu, network, public-ip, net-speed, media, weather, memory, swap, disk, load, time, colors")
+	publicIP := flag.Bool("public-ip", false, "Let the public-ip module ask -public-ip-url for the address the internet sees, it stays empty otherwise")
+	publicIPURL := flag.String("public-ip-url", "https://api.ipify.org", "Address that answers with the public IP as plain text, for -public-ip")
+	mediaWidth := flag.Int("media-width", sysinfo.Media.Width, "Longest song the media module shows in characters, longer ones scroll when media is in -live. 0 = no limit")
+	weatherLocation := flag.String("weather-location", "", "Place the weather module shows, a name or 'latitude,longitude'. wttr guesses it from the IP when empty")
+	weatherBackend := flag.String("weather-backend", sysinfo.WeatherWttr, "Service the weather module asks: 'wttr' (wttr.in) or 'open-meteo' (needs -weather-location)")
+	weatherUnits := flag.String("weather-units", sysinfo.UnitsMetric, "Units of the weather module: 'metric' or 'imperial'")
+	weatherFormat := flag.String("weather-format", sysinfo.Weather.Format, "Line the weather module shows, with {icon}, {condition}, {temp}, {feels}, {humidity}, {wind} and {location}")
+	weatherTimeout := flag.Duration("weather-timeout", sysinfo.Weather.Timeout, "How long the weather service gets to answer")
+	weatherCache := flag.Duration("weather-cache", 30*time.Minute, "How long a fetched weather is shown before asking again, the last one is also shown while offline. 0 = ask every time")
+	paletteStyle := flag.String("palette-style", sysinfo.PaletteBlocks, "How the colors module draws the colors: 'blocks', 'circles', 'squares', 'lines' or 'gradient' (a bar blending the dominant colors of the art, with -info-colors art)")
+	paletteColors := flag.Int("palette-colors", 8, "Number of colors the colors module shows: 8, or 16 for the bright ones on a second row")
+	paletteWidth := flag.Int("palette-width", 24, "Width of the -palette-style gradient bar in columns")
+	disks := flag.String("disks", strings.Join(sysinfo.Disks.Mounts, ","), "Comma separated mountpoints the disk module shows, e.g. '/,/home'")
+	diskWarn := flag.Int("disk-warn", sysinfo.Disks.Warn, "Disk usage in percent from where the bar of the disk module turns yellow")
+	diskCritical := flag.Int("disk-critical", sysinfo.Disks.Critical, "Disk usage in percent from where the bar of the disk module turns red")
+	infoKeyColor := flag.String("info-key-color", "", "Color of the keys of the built-in sysinfo and fastfetch-json, '#rrggbb' or a color index (0-255), instead of the one of -info-colors")
+	infoValueColor := flag.String("info-value-color", "", "Color of the values of the built-in sysinfo and fastfetch-json, '#rrggbb' or a color index (0-255). The terminal's foreground when empty")
+	infoDelimiter := flag.String("info-delimiter", sysinfo.DefaultTheme.Delimiter, "Put between the keys and values of the built-in sysinfo and fastfetch-json, e.g. ' ->'")
+	infoKeyWidth := flag.Int("info-key-width", 0, "Pad the keys with their delimiter to this many columns so the values line up. 0 = no padding")
+	infoKeyUpper := flag.Bool("info-key-upper", false, "Show the keys of the built-in sysinfo and fastfetch-json in capitals")
+	infoKeyBold := flag.Bool("info-key-bold", true, "Show the keys of the built-in sysinfo and fastfetch-json in bold")
+	hyperlinks := flag.String("hyperlinks", "auto", "Make the {link:...} tags of info templates clickable: 'auto' (when the terminal is known to support OSC 8 links), 'on' or 'off' (only their text)")
+	icons := flag.String("icons", sysinfo.IconsNone, "Icons in front of the keys of the built-in sysinfo: 'nerd' (Nerd Font glyphs, emoji when no Nerd Font is installed), 'emoji', 'ascii' or 'none'")
+	infoColors := flag.String("info-colors", InfoColorsDefault, "Colors of the built-in sysinfo: 'default', 'art' (the dominant colors of the animation) or the color of the title and keys, '#rrggbb' or a color index (0-255)")
+	liveModules := flag.String("live", "", "Comma separated list of built-in modules that keep updating while the animation plays, e.g. 'load,memory,time'")
+	liveInterval := flag.Duration("live-interval", 2*time.Second, "How often the -live modules are updated")
+	layout := flag.String("layout", render.LayoutLeft, "Where the art goes: 'left' of the sysinfo, 'right' of it or centered on 'top' of it")
+	infoOverflow := flag.String("info-overflow", render.OverflowTruncate, "What happens to sysinfo lines wider than the space next to the art: 'truncate' (cut off with an ellipsis), 'wrap' (continued on the next lines) or 'none' (left to the terminal)")
+	infoAlign := flag.String("info-align", render.AlignTop, "Vertical position of the sysinfo next to the art: 'top', 'center' or 'bottom'. -offset moves it further down")
+	infoOffsetX := flag.Int("info-offset-x", 0, "Extra spaces before every sysinfo line")
+	gap := flag.Int("gap", render.DefaultGap, "Number of spaces between the art and the sysinfo")
+	paddingTop := flag.Int("padding-top", 0, "Empty lines above the art and sysinfo")
+	paddingLeft := flag.Int("padding-left", 0, "Spaces left of the art and sysinfo")
+	paddingRight := flag.Int("padding-right", 0, "Columns kept free right of the art and sysinfo when sizing with -fit")
+	paddingBottom := flag.Int("padding-bottom", 0, "Empty lines below the art and sysinfo")
+	infoTimeout := flag.Duration("info-timeout", 10*time.Second, "Give up on the info command after this long and show an error line instead, 0 = wait forever")
+	infoCacheTTL := flag.Duration("info-cache-ttl", defaultInfoCacheTTL, "Reuse the sysinfo of an earlier run for this long, from ~/.cache/brrtfetch/info.json, so terminals opened in quick succession don't each wait for the info command. 0 = always run it")
+	refreshInfo := flag.Bool("refresh-info", false, "Gather the sysinfo again instead of using the cached one")
+	infoPTY := flag.Bool("info-pty", true, "Run the info command in a native pseudo-terminal so it keeps its colors. -info-pty=false uses 'script' or 'unbuffer' instead, like older versions (Linux, macOS and the BSDs, other systems always do)")
+	infoRequired := flag.Bool("info-required", false, "Wait for the info command before starting and exit with status 1 when it fails or times out")
 	offset := flag.Int("offset", 0, "Number of empty lines before sysinfo output")
+	renderer := flag.String("renderer", render.RendererASCII, "How pixels are turned into characters: 'ascii', 'halfblock' (two pixels per character using 24-bit foreground and background colors), 'bg' (every character is a solid block painted with the background color), 'braille' (2x4 dots per character), 'symbols' (the block, wedge or ASCII symbol matching the shapes in each character best, with the best foreground and background color), 'edges' (lines along the edges, the ascii ramp in between) or 'sixel' (real bitmap, falls back to 'ascii' when the terminal can't draw sixel)")
+	scaler := flag.String("scaler", render.ScalerBox, "How frames are shrunk to the art size: 'box' (average of every pixel a character covers), 'bilinear', 'lanczos' (sharpest) or 'nearest' (fastest, one pixel per character like older versions)")
+	videoFPS := flag.Int("video-fps", 15, "Frames sampled per second when the input is a video (needs ffmpeg)")
+	maxFrames := flag.Int("max-frames", 300, "Maximum number of frames taken from a video, 0 = the whole video")
+	debugTerm := flag.Bool("debug-term", false, "Print what brrtfetch detected about the terminal (colors, size, sixel and synchronized output support) and exit")
+	var still stillFlag
+	flag.Var(&still, "still", "Print a single frame next to the sysinfo and exit, without taking over the terminal. -still=N prints frame N instead of the first one")
+	hold := flag.Bool("hold", false, "Keep static images (PNG, JPEG, BMP or single frame GIFs) on screen until Ctrl-C instead of printing them once and exiting")
+	noCache := flag.Bool("no-cache", false, "Don't use ~/.cache/brrtfetch: download URLs again and render every frame again instead of loading the frames rendered by an earlier run")
+	downloadTimeout := flag.Duration("download-timeout", 15*time.Second, "Give up downloading URL inputs after this long")
+	symbols := flag.String("symbols", render.DefaultSymbols, "Symbol sets -renderer=symbols picks from, separated by commas: blocks, wedges, ascii")
+	edgeThreshold := flag.Float64("edge-threshold", render.DefaultEdgeThreshold, "How strong (0-255) a change in brightness has to be for -renderer=edges to draw it as a line")
+	threshold := flag.Float64("threshold", 0, "Minimum brightness (0-255) for a pixel to be drawn as a dot with -renderer=braille, 0 draws every non-transparent pixel")
+	diffDraw := flag.Bool("diff", true, "Only redraw the characters that changed since the previous frame, -diff=false rewrites the whole frame every time")
+	syncMode := flag.String("sync", "auto", "Wrap frames in synchronized output sequences so half drawn frames are never visible: 'auto' (when the terminal supports it), 'on' or 'off'")
+	pipeMode := flag.String("pipe", "frame", "What to print when the output isn't a terminal: 'frame' (only the first frame, like -still) or 'all' (every frame once, separated by form feeds)")
+	playlistFile := flag.String("playlist", "", "File listing inputs to play one after the other, one per line, optionally followed by how many times each plays. Used instead of or after the inputs on the command line")
+	shuffle := flag.Bool("shuffle", false, "Play the inputs in a random order")
+	seed := flag.Int64("seed", 0, "Seed for -shuffle and for picking a file from a directory input, the same seed makes the same choices every run. 0 = different every run")
+	frameSkip := flag.Bool("frame-skip", true, "Skip frames when drawing can't keep up (slow SSH link, very wide art), so the animation stays at its own speed. -frame-skip=false draws every frame and lets the animation slow down instead")
+	adaptive := flag.Bool("adaptive", true, "Lower the quality when the terminal can't keep up (drop the colors, then every other frame) and raise it again once it can")
+	inline := flag.Bool("inline", false, "Play the animation at the cursor instead of on the alternate screen, the last frame stays in the scrollback like the output of any other command")
+	exitFrame := flag.String("exit-frame", term.ExitAuto, "Frame left on screen after playback: 'first', 'last' (of the animation), 'current' (the one on screen when stopped), 'none' or 'auto' (the one it ended on after all loops or -duration, the first when interrupted)")
+	stripColor := flag.Bool("strip-color", false, "Leave all colors and other escape sequences out of the frame printed by brrtfetch motd or -still, for consoles that show them literally")
+	prerenderAll := flag.Bool("prerender", true, "Render every frame before playback starts. -prerender=false renders while playing and only keeps a few frames in memory, for very long animations")
+	loops := flag.Int("loops", -1, "Number of times to play the animation before exiting with its last frame on screen, 0 = forever. By default GIFs loop as often as they say they should")
+	duration := flag.Duration("duration", 0, "Stop the animation after this long (e.g. 10s) and leave the last frame on screen, 0 = no limit")
+	tmuxStatus := flag.Bool("tmux-status", false, "Print a single row of the art, at the frame the animation is at right now, in tmux's status format and exit. For status-left: #(brrtfetch -tmux-status my.gif)")
+	exitOnKey := flag.Bool("exit-on-key", false, "End playback on any key press, e.g. when brrtfetch runs at shell startup. -exit-frame picks what stays on screen")
+	socket := flag.String("socket", defaultDaemonSocket(), "Unix socket of the brrtfetch daemon, for brrtfetch attach")
+	watch := flag.Bool("watch", false, "Start over whenever the input files, the playlist or the config file change, to see edits to the art and settings right away")
+	controlPath := flag.String("control", "", "Unix socket to listen on for JSON commands from scripts and keybindings: pause, resume, toggle, next-gif, set-speed and reload-config. SIGUSR1 toggles pause and SIGUSR2 skips to the next GIF either way")
+	keyControls := flag.Bool("keys", true, "Control playback with the keyboard: space pauses, + and - change the speed, left and right step through the frames and q quits")
+	workers := flag.Int("workers", runtime.NumCPU(), "Frames rendered at the same time, fewer keep the CPU spike of prerendering large GIFs down")
+	compressFrames := flag.Bool("compress-frames", false, "Keep prerendered frames compressed in memory and unpack each one when it's drawn, for long high resolution animations")
+	lowPriority := flag.Bool("low-priority", false, "Render with a lower process priority (nice) and at most half the CPUs as -workers, for laptops")
+	maxMemory := flag.Int("max-memory", 0, "Memory budget in MB for prerendered frames, longer animations are rendered while playing instead. 0 = no limit")
+	cpuProfile := flag.String("cpuprofile", "", "Write a CPU profile to this file, for go tool pprof")
+	memProfile := flag.String("memprofile", "", "Write a heap profile to this file on exit, for go tool pprof")
+	traceFile := flag.String("trace", "", "Write an execution trace to this file, for go tool trace")
+	pprofListen := flag.String("pprof", "", "Serve net/http/pprof on this address while running, e.g. localhost:6060")
+	configPath := flag.String("config", "", "Path of the config file, defaults to ~/.config/brrtfetch/config.toml")
+	profile := flag.String("profile", "", "Named profile from the config file to use, e.g. -profile work for [profile.work]")
+	listen := flag.String("listen", defaultServeAddress, "Address brrtfetch serve listens on, e.g. :8080 or localhost:8080")
+	output := flag.String("o", "", "Output file of brrtfetch compile, the input with the extension .brrt when not given")
+	format := flag.String("format", "", "File format for brrtfetch export: 'sh' (a shell script that plays the animation), 'cast' (asciinema recording), 'gif' (the terminal output as an animated GIF), 'html' (a page that plays the animation), 'ans' or 'txt' (a file per frame, with or without colors). Picked from the extension of the output file when not set, 'sh' when that doesn't name one")
 	flag.Parse()
 
+	// brrtfetch compile in.gif -o out.brrt and brrtfetch serve in.gif -listen
+	// :8080, options may follow the input
+	if compiling || serving {
+		var inputs []string
+		for flag.NArg() > 0 {
+			inputs = append(inputs, flag.Arg(0))
+			flag.CommandLine.Parse(flag.Args()[1:])
+		}
+		flag.CommandLine.Parse(append([]string{"--"}, inputs...))
+	}
+
+	// --- Fill in everything not given on the command line from the config ---
+	path := *configPath
+	if path == "" {
+		path = defaultConfigPath()
+	}
+	var configInput string
+	cf, err := loadConfigFile(path)
+	switch {
+	case err == nil:
+		configInput, err = applyConfig(cf, *profile)
+	case os.IsNotExist(err) && *configPath == "" && *profile == "":
+		err = nil // Not having a config is fine, unless one was asked for
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Config: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	if flagGiven("height") && *height < 1 {
+		fmt.Fprintf(os.Stderr, "-height has to be at least 1\n")
+		os.Exit(exitUsage)
+	}
+
+	// A status line has room for small art of a single row
+	if *tmuxStatus {
+		if !flagGiven("width") {
+			width = widthFlag{cols: defaultTmuxWidth}
+		}
+		if !flagGiven("height") {
+			*height = int(1 / render.DefaultCellAspect)
+		}
+		if !flagGiven("renderer") {
+			*renderer = render.RendererHalfBlock
+		}
+		width.auto, *fit = false, false
+	}
+
 	// If height wasn't set, sync it to width
 	if *height == -1 {
-		*height = *width
+		*height = width.cols
+	}
+	if *fit {
+		width.auto = true
 	}
 
-    *height = *height / 2
+	switch *renderer {
+	case render.RendererASCII, render.RendererHalfBlock, render.RendererBG, render.RendererBraille, render.RendererSymbols, render.RendererEdges, render.RendererSixel:
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown renderer %q, use 'ascii', 'halfblock', 'bg', 'braille', 'symbols', 'edges' or 'sixel'\n", *renderer)
+		os.Exit(exitUsage)
+	}
+	symbolSets, err := render.ResolveSymbols(*symbols)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitUsage)
+	}
+	switch *scaler {
+	case render.ScalerNearest, render.ScalerBox, render.ScalerBilinear, render.ScalerLanczos:
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown scaler %q, use 'box', 'bilinear', 'lanczos' or 'nearest'\n", *scaler)
+		os.Exit(exitUsage)
+	}
 
-	if flag.NArg() < 1 {
-		fmt.Println("Usage: brrtfetch [options] /path/to/file.gif")
-		flag.PrintDefaults()
-		return
+	if *layout != render.LayoutLeft && *layout != render.LayoutRight && *layout != render.LayoutTop {
+		fmt.Fprintf(os.Stderr, "Unknown layout %q, use 'left', 'right' or 'top'\n", *layout)
+		os.Exit(exitUsage)
 	}
 
-	gifPath := flag.Arg(0)
-	f, err := os.Open(gifPath)
+	for name, value := range map[string]int{"gap": *gap, "info-offset-x": *infoOffsetX, "padding-top": *paddingTop, "padding-left": *paddingLeft, "padding-right": *paddingRight, "padding-bottom": *paddingBottom} {
+		if value < 0 {
+			fmt.Fprintf(os.Stderr, "-%s can't be negative\n", name)
+			os.Exit(exitUsage)
+		}
+	}
+	if *invert != InvertAuto && *invert != InvertOn && *invert != InvertOff {
+		fmt.Fprintf(os.Stderr, "Unknown invert mode %q, use 'auto', 'on' or 'off'\n", *invert)
+		os.Exit(exitUsage)
+	}
+	var bgColor color.RGBA
+	if *background != BackgroundAuto && *background != BackgroundNone {
+		if bgColor, err = parseHexColor(*background); err != nil {
+			fmt.Fprintf(os.Stderr, "Unknown background %q, use '#rrggbb', 'auto' or 'none'\n", *background)
+			os.Exit(exitUsage)
+		}
+	}
+	if *direction != DirectionForward && *direction != DirectionReverse && *direction != DirectionPingPong {
+		fmt.Fprintf(os.Stderr, "Unknown direction %q, use 'forward', 'reverse' or 'pingpong'\n", *direction)
+		os.Exit(exitUsage)
+	}
+	if *alphaThreshold < 0 || *alphaThreshold > 255 {
+		fmt.Fprintf(os.Stderr, "-alpha-threshold has to be between 0 and 255\n")
+		os.Exit(exitUsage)
+	}
+	var segmentStart, segmentEnd *decode.Mark
+	if *segment != "" {
+		if segmentStart, segmentEnd, err = parseSegment(*segment); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(exitUsage)
+		}
+	}
+	var crop *cropArea
+	if *cropFlag != "" {
+		area, err := parseCrop(*cropFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(exitUsage)
+		}
+		crop = &area
+	}
+	matteColors, err := parseMatte(*matte)
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(os.Stderr, "Unknown matte %q, use 'none', '#rrggbb', 'checker' or '#rrggbb,#rrggbb'\n", *matte)
+		os.Exit(exitUsage)
+	}
+	var palette []color.RGBA
+	if *paletteFlag != "" {
+		if palette, err = loadPalette(*paletteFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Unknown palette %q, use %s or a palette file: %v\n", *paletteFlag, strings.Join(render.PaletteNames(), ", "), err)
+			os.Exit(exitUsage)
+		}
+	}
+	if *speed <= 0 {
+		fmt.Fprintf(os.Stderr, "-speed has to be positive\n")
+		os.Exit(exitUsage)
+	}
+	if *multiplier <= 0 {
+		fmt.Fprintf(os.Stderr, "-multiplier has to be positive\n")
+		os.Exit(exitUsage)
+	}
+	if *maxFPS < 0 {
+		fmt.Fprintf(os.Stderr, "-max-fps can't be negative\n")
+		os.Exit(exitUsage)
+	}
+	if *workers < 1 {
+		fmt.Fprintf(os.Stderr, "-workers has to be at least 1\n")
+		os.Exit(exitUsage)
+	}
+	if *lowPriority {
+		if half := (runtime.NumCPU() + 1) / 2; *workers > half {
+			*workers = half
+		}
+		if err := lowerPriority(); err != nil {
+			fmt.Fprintf(os.Stderr, "brrtfetch: -low-priority: %s\n", errorMessage(err))
+		}
+	}
+	if *gamma <= 0 {
+		fmt.Fprintf(os.Stderr, "-gamma has to be positive\n")
+		os.Exit(exitUsage)
+	}
+	if *cellAspect < 0 {
+		fmt.Fprintf(os.Stderr, "-cell-aspect can't be negative\n")
+		os.Exit(exitUsage)
 	}
-	defer f.Close()
 
-	g, err := gif.DecodeAll(f)
-	if err != nil {
-		panic(err)
+	if *infoAlign != render.AlignTop && *infoAlign != render.AlignCenter && *infoAlign != render.AlignBottom {
+		fmt.Fprintf(os.Stderr, "Unknown sysinfo alignment %q, use 'top', 'center' or 'bottom'\n", *infoAlign)
+		os.Exit(exitUsage)
 	}
 
-	// EXECUTE EXTERNAL INFO COMMAND
-	sysInfo := getCommandOutputLines(*infoCommand)
+	if *infoOverflow != render.OverflowTruncate && *infoOverflow != render.OverflowWrap && *infoOverflow != render.OverflowNone {
+		fmt.Fprintf(os.Stderr, "Unknown sysinfo overflow %q, use 'truncate', 'wrap' or 'none'\n", *infoOverflow)
+		os.Exit(exitUsage)
+	}
 
-	// --- Build cfg from flags ---
-	cfg := Config{
-		Width:  *width,
-		Height: *height,
-		FPS:    *fps,
-		Color:  *colorOutput,
+	if *syncMode != "auto" && *syncMode != "on" && *syncMode != "off" {
+		fmt.Fprintf(os.Stderr, "Unknown sync mode %q, use 'auto', 'on' or 'off'\n", *syncMode)
+		os.Exit(exitUsage)
+	}
+	if *hyperlinks != "auto" && *hyperlinks != "on" && *hyperlinks != "off" {
+		fmt.Fprintf(os.Stderr, "Unknown hyperlinks mode %q, use 'auto', 'on' or 'off'\n", *hyperlinks)
+		os.Exit(exitUsage)
 	}
 
-	// --- Enter alternate screen buffer ---
-	fmt.Print("\033[?1049h")
-	defer func() {
-		fmt.Print("\033[?1049l") // Exit alternate screen on program exit
-	}()
+	switch *exitFrame {
+	case term.ExitAuto, term.ExitFirst, term.ExitLast, term.ExitCurrent, term.ExitNone:
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown exit frame %q, use 'auto', 'first', 'last', 'current' or 'none'\n", *exitFrame)
+		os.Exit(exitUsage)
+	}
 
-	// --- Setup cursor visibility ---
-	writer := bufio.NewWriter(os.Stdout)
-	defer func() {
-		writer.WriteString(ANSI_SHOW_CURSOR)
-		writer.Flush()
-	}()
-	writer.WriteString(ANSI_HIDE_CURSOR)
-	writer.Flush()
+	if *pipeMode != "frame" && *pipeMode != "all" {
+		fmt.Fprintf(os.Stderr, "Unknown pipe mode %q, use 'frame' or 'all'\n", *pipeMode)
+		os.Exit(exitUsage)
+	}
 
-	// --- Handle Ctrl-C gracefully ---
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	// The terminals a MOTD ends up on are unknown, 256 colors work on about
+	// all of them
+	if motd && *colorMode == "" {
+		*colorMode = render.Color256
+	}
+	// tmux turns 24-bit colors into what its terminal can show by itself
+	if *tmuxStatus && *colorMode == "" {
+		*colorMode = render.ColorTrue
+	}
+	colorReason := "-color-mode"
+	switch *colorMode {
+	case "":
+		*colorMode, colorReason = render.DetectColorMode()
+	case render.ColorTrue, render.Color256, render.Color16, render.ColorNone:
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown color mode %q, use 'truecolor', '256', '16' or 'none'\n", *colorMode)
+		os.Exit(exitUsage)
+	}
+	// A tint and the colors of info sources are written in the colors the
+	// terminal has, even when the art has none
+	tintMode := *colorMode
+	if tintMode == render.ColorNone {
+		tintMode = render.Color256
+	}
+	var tintSGR string
+	if *tint != "" {
+		if tintSGR, err = tintColor(*tint, tintMode); err != nil {
+			fmt.Fprintf(os.Stderr, "Unknown tint %q: %v\n", *tint, err)
+			os.Exit(exitUsage)
+		}
+	}
+	var configSources, configModules []ConfigTable
+	if cf != nil {
+		configSources, configModules = cf.Sources, cf.Modules
+	}
+	if err := validateInfoFormat(*infoFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitUsage)
+	}
+	sources, err := infoSources(info, *infoFormat, configSources, tintMode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Config: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	if !*colorOutput {
+		*colorMode, colorReason = render.ColorNone, "-color=false"
+	}
+	if *stripColor {
+		*colorMode, colorReason = render.ColorNone, "-strip-color"
+	}
 
-	// === CONCURRENT PRERENDERING SETUP ===
-	numWorkers := runtime.NumCPU()
-	jobs := make(chan RenderJob, len(g.Image))
-	results := make(chan RenderResult, len(g.Image))
-	var wg sync.WaitGroup
+	if *debugTerm {
+		printTermInfo(os.Stdout, *colorMode, colorReason)
+		return
+	}
 
-	// 1. Initialize Buffer Pool
-	bufferPool = make(chan *image.RGBA, numWorkers*2)
-	for i := 0; i < cap(bufferPool); i++ {
-		bufferPool <- image.NewRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+	// Consoles that can't show colors are unlikely to have more than ASCII
+	if *stripColor && !flagGiven("charset") {
+		*charset = "classic"
+	}
+	ramp, err := render.ResolveCharset(*charset)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	switch *icons {
+	case sysinfo.IconsNone, sysinfo.IconsNerd, sysinfo.IconsEmoji, sysinfo.IconsASCII:
+		sysinfo.Icons = iconSet(*icons)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown icons %q, use 'nerd', 'emoji', 'ascii' or 'none'\n", *icons)
+		os.Exit(exitUsage)
+	}
+	// Exports, a MOTD and served frames end up on other terminals, which may
+	// print the links literally
+	sysinfo.Hyperlinks = *hyperlinks == "on" || (*hyperlinks == "auto" && !exporting && !motd && !serving && !compiling &&
+		!*stripColor && term.IsTerminal(os.Stdout) && term.HyperlinksSupported())
+	if err := customModules(configModules, sysinfo.CommandOptions{Timeout: *infoTimeout, PTY: *infoPTY}, tintMode, *noCache); err != nil {
+		fmt.Fprintf(os.Stderr, "Config: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	if err := sysinfo.ValidateModules(*modules); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitUsage)
+	}
+	if err := sysinfo.ValidateModules(*liveModules); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitUsage)
+	}
+	infoTheme := sysinfo.DefaultTheme
+	if *infoColors != InfoColorsDefault && *infoColors != InfoColorsArt {
+		keyColor, err := tintColor(*infoColors, tintMode)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unknown info colors %q, use 'default', 'art', '#rrggbb' or a color index\n", *infoColors)
+			os.Exit(exitUsage)
+		}
+		infoTheme.Title, infoTheme.Key = keyColor, keyColor
+	}
+	keyLook := keyStyle{delimiter: *infoDelimiter, width: *infoKeyWidth, upper: *infoKeyUpper, bold: *infoKeyBold}
+	if *infoKeyColor != "" {
+		if keyLook.keyColor, err = tintColor(*infoKeyColor, tintMode); err != nil {
+			fmt.Fprintf(os.Stderr, "-info-key-color: %v\n", err)
+			os.Exit(exitUsage)
+		}
+	}
+	if *infoValueColor != "" {
+		if keyLook.valueColor, err = tintColor(*infoValueColor, tintMode); err != nil {
+			fmt.Fprintf(os.Stderr, "-info-value-color: %v\n", err)
+			os.Exit(exitUsage)
+		}
+	}
+	if *infoKeyWidth < 0 {
+		fmt.Fprintf(os.Stderr, "-info-key-width can't be negative\n")
+		os.Exit(exitUsage)
+	}
+	infoTheme = keyLook.apply(infoTheme)
+	if *diskWarn < 0 || *diskCritical > 100 || *diskWarn > *diskCritical {
+		fmt.Fprintf(os.Stderr, "-disk-warn and -disk-critical have to be between 0 and 100, -disk-warn not above -disk-critical\n")
+		os.Exit(exitUsage)
+	}
+	if *mediaWidth < 0 {
+		fmt.Fprintf(os.Stderr, "-media-width can't be negative\n")
+		os.Exit(exitUsage)
+	}
+	sysinfo.Media.Width = *mediaWidth
+	if !sysinfo.ValidatePaletteStyle(*paletteStyle) {
+		fmt.Fprintf(os.Stderr, "Unknown palette style %q, use 'blocks', 'circles', 'squares', 'lines' or 'gradient'\n", *paletteStyle)
+		os.Exit(exitUsage)
+	}
+	if *paletteColors != 8 && *paletteColors != 16 {
+		fmt.Fprintf(os.Stderr, "-palette-colors has to be 8 or 16\n")
+		os.Exit(exitUsage)
+	}
+	if *paletteWidth < 1 {
+		fmt.Fprintf(os.Stderr, "-palette-width has to be at least 1\n")
+		os.Exit(exitUsage)
+	}
+	sysinfo.Palette.Style = *paletteStyle
+	if *paletteColors == 16 {
+		infoTheme.Blocks = append(infoTheme.Blocks, "100", "101", "102", "103", "104", "105", "106", "107")
+	}
+	if *paletteStyle == sysinfo.PaletteGradient && *infoColors != InfoColorsArt {
+		// The terminal's colors are only known by their number
+		sysinfo.Palette.Style = sysinfo.PaletteBlocks
+	}
+	if *weatherBackend != sysinfo.WeatherWttr && *weatherBackend != sysinfo.WeatherOpenMeteo {
+		fmt.Fprintf(os.Stderr, "Unknown weather backend %q, use 'wttr' or 'open-meteo'\n", *weatherBackend)
+		os.Exit(exitUsage)
+	}
+	if *weatherUnits != sysinfo.UnitsMetric && *weatherUnits != sysinfo.UnitsImperial {
+		fmt.Fprintf(os.Stderr, "Unknown weather units %q, use 'metric' or 'imperial'\n", *weatherUnits)
+		os.Exit(exitUsage)
+	}
+	sysinfo.Weather = sysinfo.WeatherOptions{
+		Backend:  *weatherBackend,
+		Location: *weatherLocation,
+		Units:    *weatherUnits,
+		Format:   *weatherFormat,
+		Timeout:  *weatherTimeout,
+		CacheTTL: *weatherCache,
+	}
+	if dir, err := cacheDir(); err == nil && !*noCache {
+		sysinfo.Weather.CacheFile = filepath.Join(dir, "weather.json")
+	}
+	if *publicIP {
+		sysinfo.Network.PublicIPURL = *publicIPURL
+	}
+	sysinfo.Disks = sysinfo.DiskOptions{Warn: *diskWarn, Critical: *diskCritical}
+	for _, mount := range strings.Split(*disks, ",") {
+		if mount = strings.TrimSpace(mount); mount != "" {
+			sysinfo.Disks.Mounts = append(sysinfo.Disks.Mounts, expandHome(mount))
+		}
+	}
+	if *liveInterval <= 0 {
+		fmt.Fprintf(os.Stderr, "-live-interval has to be positive\n")
+		os.Exit(exitUsage)
 	}
 
-	// 2. Start worker goroutines
-	for w := 0; w < numWorkers; w++ {
-		wg.Add(1)
-		go worker(w, jobs, results, cfg, sysInfo, &wg, *multiplier, *offset)
+	// brrtfetch export [options] out.sh [input ...]
+	args := flag.Args()
+	var exportPath string
+	if exporting {
+		if len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "Usage: brrtfetch export [options] output-file [/path/to/file.gif|image|video|URL ...]")
+			flag.PrintDefaults()
+			os.Exit(exitUsage)
+		}
+		exportPath, args = args[0], args[1:]
+		var ok bool
+		if *format, ok = exportFormat(exportPath, *format); !ok {
+			fmt.Fprintf(os.Stderr, "Unknown export format %q, use 'sh', 'cast', 'gif', 'html', 'ans' or 'txt'\n", *format)
+			os.Exit(exitUsage)
+		}
 	}
 
-	// 3. Composing and dispatching jobs (handling GIF disposal methods)
-	var fullFrame *image.RGBA
-	var lastDisposal = gif.DisposalNone
-	var lastBounds image.Rectangle
-	var snapshot *image.RGBA
+	// brrtfetch compile [options] in.gif [-o out.brrt]
+	if compiling {
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "Usage: brrtfetch compile [options] /path/to/file.gif|image|video|URL [-o output.brrt]")
+			flag.PrintDefaults()
+			os.Exit(exitUsage)
+		}
+		if *output == "" {
+			*output = strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0])) + ".brrt"
+		}
+	}
 
-	for i, frame := range g.Image {
-		if fullFrame == nil {
-			fullFrame = image.NewRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
-			snapshot = image.NewRGBA(fullFrame.Bounds())
-			draw.Draw(fullFrame, fullFrame.Bounds(), image.NewUniform(color.Transparent), image.Point{}, draw.Src)
-		} else {
-			if lastDisposal == gif.DisposalPrevious {
-				draw.Draw(fullFrame, fullFrame.Bounds(), snapshot, image.Point{}, draw.Src)
-			} else if lastDisposal != gif.DisposalNone {
-				draw.Draw(fullFrame, lastBounds, image.NewUniform(color.Transparent), image.Point{}, draw.Src)
-			}
+	// Every input on the command line plays once, a playlist says how often
+	var playlist []playlistItem
+	for _, arg := range args {
+		playlist = append(playlist, playlistItem{path: arg, loops: 1})
+	}
+	if *playlistFile != "" {
+		items, err := readPlaylist(expandHome(*playlistFile))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "brrtfetch: %s\n", errorMessage(err))
+			os.Exit(exitCode(err))
 		}
+		playlist = append(playlist, items...)
+	}
+	if len(playlist) == 0 && configInput != "" {
+		playlist = []playlistItem{{path: configInput, loops: 1}}
+	}
+
+	profiles, err := startProfiling(*cpuProfile, *memProfile, *traceFile, *pprofListen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "brrtfetch: %s\n", errorMessage(err))
+		os.Exit(exitFailure)
+	}
 
-		if int(g.Disposal[i]) == gif.DisposalPrevious {
-			copy(snapshot.Pix, fullFrame.Pix)
+	var tempInputs []string
+	cleanup := func() {
+		// Uncached downloads are only kept while brrtfetch runs
+		for _, path := range tempInputs {
+			os.Remove(path)
 		}
+		profiles.Stop()
+	}
+	defer cleanup()
 
-		draw.Draw(fullFrame, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
-		lastDisposal = int(g.Disposal[i])
-		lastBounds = frame.Bounds()
+	// Without any input the logo of the distro plays, when there is one
+	if len(playlist) == 0 {
+		path, temporary, ok, err := bundledLogo(*noCache)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "brrtfetch: %s\n", errorMessage(err))
+			os.Exit(exitFailure)
+		}
+		if ok {
+			playlist = []playlistItem{{path: path, loops: 1}}
+		}
+		if temporary {
+			tempInputs = append(tempInputs, path)
+		}
+	}
+	if len(playlist) == 0 {
+		fmt.Println("Usage: brrtfetch [options] /path/to/file.gif|image|video|URL ...")
+		flag.PrintDefaults()
+		return
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(*seed))
+	if *shuffle {
+		rng.Shuffle(len(playlist), func(i, j int) { playlist[i], playlist[j] = playlist[j], playlist[i] })
+	}
 
-		frameCopy := <-bufferPool
-		copy(frameCopy.Pix, fullFrame.Pix)
-		jobs <- RenderJob{Index: i, Image: frameCopy, PoolKey: frameCopy}
+	// Errors end the run with a short message and an exit code telling what
+	// went wrong. Once playing, the terminal is put back first.
+	restoreTerminal := cleanup
+	fail := func(err error) {
+		restoreTerminal()
+		fmt.Fprintf(os.Stderr, "brrtfetch: %s\n", errorMessage(err))
+		os.Exit(exitCode(err))
 	}
-	close(jobs)
 
-	// 4. Collect results
-	prerendered := make([][]string, len(g.Image))
-	go func() {
-		for result := range results {
-			prerendered[result.Index] = result.Lines
+	for i, item := range playlist {
+		// A directory stands for a random animation in it, a new one every run
+		if info, err := os.Stat(item.path); err == nil && info.IsDir() {
+			if playlist[i].path, err = pickFromDir(item.path, rng); err != nil {
+				fail(err)
+			}
+			continue
 		}
-	}()
+		if !isRemote(item.path) {
+			continue
+		}
+		local, temporary, err := fetchRemote(item.path, *downloadTimeout, *noCache)
+		if err != nil {
+			fail(err)
+		}
+		if temporary {
+			tempInputs = append(tempInputs, local)
+		}
+		playlist[i].path = local
+	}
 
-	// 5. Wait for workers and close results
-	wg.Wait()
-	close(results)
+	// A compiled .brrt file has its frames rendered already, it only plays
+	// on its own
+	var compiled *compiledAnimation
+	if len(playlist) == 1 && !compiling {
+		if compiled, err = loadCompiled(playlist[0].path); err != nil {
+			fail(err)
+		}
+	}
 
-	// --- Capture first frame for printing after Ctrl-C ---
-	firstFrame := prerendered[0]
+	// --- Gather the sysinfo in the background, playback doesn't wait for it ---
+	sysInfoReady := make(chan []string, 1)
+	var sysInfoErr error
 	go func() {
-		<-sigs
-		fmt.Print("\033[?1049l") // exit alternate screen
-		for _, line := range firstFrame {
-			fmt.Println(line)
+		if *tmuxStatus {
+			sysInfoReady <- nil // Only the art goes in the status line
+			return
 		}
-		fmt.Print(ANSI_SHOW_CURSOR)
-		fmt.Print("\033[0m")
-		os.Exit(0)
+		if *infoColors == InfoColorsArt {
+			gradientWidth := 0
+			if *paletteStyle == sysinfo.PaletteGradient {
+				gradientWidth = *paletteWidth
+			}
+			infoTheme = keyLook.apply(artTheme(playlist[0].path, *colorMode, *paletteColors, gradientWidth))
+		}
+		useCache := !*noCache && *infoCacheTTL > 0
+		key := infoCacheKey(sources, *modules, configModules, infoTheme, *infoSeparator,
+			sysinfo.Icons, sysinfo.Hyperlinks, sysinfo.Palette, sysinfo.Disks, sysinfo.Network, sysinfo.Media, sysinfo.Weather)
+		if useCache && !*refreshInfo {
+			if lines, ok := loadInfoCache(key, *infoCacheTTL); ok {
+				sysInfoReady <- lines
+				return
+			}
+		}
+		var lines []string
+		lines, sysInfoErr = sysinfo.Merge(sources, *modules, infoTheme, *infoSeparator,
+			sysinfo.CommandOptions{Timeout: *infoTimeout, PTY: *infoPTY})
+		if useCache && sysInfoErr == nil {
+			saveInfoCache(key, lines, *infoCacheTTL) // Gathered again next time when it fails
+		}
+		sysInfoReady <- lines
 	}()
+	var sysInfo []string
+	sysInfoKnown := false
+	waitSysInfo := func() {
+		if !sysInfoKnown {
+			sysInfo, sysInfoKnown = <-sysInfoReady, true
+		}
+	}
+	if *infoRequired {
+		waitSysInfo()
+		if sysInfoErr != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", sysInfoErr)
+			cleanup()
+			os.Exit(exitFailure)
+		}
+	}
+
+	// --- Build cfg from flags ---
+	cfg := render.Config{
+		Width:      width.cols,
+		Height:     *height,
+		FPS:        *fps,
+		Speed:      *speed,
+		MaxFPS:     *maxFPS,
+		ColorMode:  *colorMode,
+		Renderer:   *renderer,
+		Scaler:     *scaler,
+		Threshold:  *threshold,
+		Multiplier: *multiplier,
+		Charset:    ramp,
+		Symbols:    symbolSets,
+		Offset:     *offset,
+		Layout:     *layout,
+		CellAspect: *cellAspect,
 
-	delay := time.Duration(1000/cfg.FPS) * time.Millisecond
+		EdgeThreshold: *edgeThreshold,
 
-	// ----- Animation loop -----
-	for {
-		for _, frameStrings := range prerendered {
-			writer.WriteString("\033[H")        // Home cursor
-			for _, line := range frameStrings { // print all lines returned by renderFrame
-				writer.WriteString(line)
-				writer.WriteByte('\n')
+		AutoLevels: *autoLevels,
+		Brightness: *brightness,
+		Contrast:   *contrast,
+		Gamma:      *gamma,
+		InvertRamp: *invert == InvertOn,
+
+		Background:      bgColor,
+		BlendBackground: *background != BackgroundAuto && *background != BackgroundNone,
+		AlphaThreshold:  uint8(*alphaThreshold),
+		Matte:           matteColors,
+		Palette:         palette,
+		Tint:            tintSGR,
+
+		InfoAlign:    *infoAlign,
+		InfoOffsetX:  *infoOffsetX,
+		InfoOverflow: *infoOverflow,
+
+		Gap:           *gap,
+		PaddingTop:    *paddingTop,
+		PaddingLeft:   *paddingLeft,
+		PaddingRight:  *paddingRight,
+		PaddingBottom: *paddingBottom,
+
+		Workers: *workers,
+	}
+
+	// The Windows console prints escape sequences literally unless asked not to
+	term.EnableVirtualTerminal(os.Stdout)
+
+	// --- Ask the terminal what it can do, before we take over the screen ---
+	// A MOTD is generated for other terminals, not the one it runs in
+	var tty *term.Terminal
+	ttyErr := errors.New("no terminal queries for motd")
+	if !motd {
+		tty, ttyErr = term.Open()
+	}
+
+	// Only draw sixel when the terminal says it can. Inside a tmux without
+	// sixel the images are passed through to the terminal it runs in.
+	if cfg.Renderer == render.RendererSixel {
+		cfg.Renderer = render.RendererASCII
+		// GIF exports draw characters, they can't show sixel images.
+		// Compiled and served frames are played in terminals that may not
+		// either.
+		if ttyErr == nil && !(exporting && *format == ExportGIF) && !compiling && !serving && !*tmuxStatus {
+			switch {
+			case term.SixelSupported(tty):
+				cfg.Renderer = render.RendererSixel
+			case term.InTmux() && term.TmuxSixelSupported(tty):
+				cfg.Renderer, cfg.Passthrough = render.RendererSixel, true
 			}
-			writer.Flush()
-			time.Sleep(delay)
+		}
+		if cfg.Renderer == render.RendererSixel {
+			cfg.CellWidth, cfg.CellHeight = term.CellPixelSize(tty)
 		}
 	}
-}
 
-// worker goroutine function
-func worker(id int, jobs <-chan RenderJob, results chan<- RenderResult,
-	cfg Config, sysInfo []string, wg *sync.WaitGroup, multiplier float64, offset int) {
-	defer wg.Done()
-	for job := range jobs {
-		lines := renderFrame(job.Image, cfg.Width, cfg.Height, sysInfo, cfg.Color, multiplier, offset)
-		results <- RenderResult{Index: job.Index, Lines: lines}
-		bufferPool <- job.PoolKey
+	// Fit the art to the terminal's colors, when it tells what they are
+	var termColors term.Colors
+	if ttyErr == nil {
+		termColors = term.QueryColors(tty)
+	}
+	if !termColors.HasBackground && !motd {
+		termColors = term.EnvColors()
+	}
+	if *background == BackgroundAuto && termColors.HasBackground {
+		cfg.Background, cfg.BlendBackground = termColors.Background, true
+	}
+	if *invert == InvertAuto {
+		// Without colors the characters alone show the brightness, on a
+		// dark background the denser ones look brighter
+		cfg.InvertRamp = cfg.ColorMode == render.ColorNone && termColors.HasBackground && termColors.Dark()
+	}
+	if cfg.ColorMode == render.ColorNone && termColors.HasBackground && !flagGiven("charset") {
+		cfg.Charset, _ = render.ResolveCharset(render.MonoCharset(termColors))
 	}
-}
 
-// Convert a frame to ASCII lines
-func renderFrame(img *image.RGBA, width, height int, sysInfo []string, colorOutput bool, multiplier float64, offset int) []string {
-	// totalHeight ensures we can print all sysinfo lines
-	totalHeight := height
-	if len(sysInfo)+offset > height {
-		totalHeight = len(sysInfo) + offset
-	}
-
-	lines := make([]string, totalHeight)
-	pix := img.Pix
-	stride := img.Stride
-	scaleX := float64(img.Bounds().Dx()) / float64(width)
-	scaleY := float64(img.Bounds().Dy()) / float64(height)
-	var lineBuilder strings.Builder
-
-	for y := 0; y < totalHeight; y++ {
-		lineBuilder.Reset()
-
-		// Fill GIF lines
-		if y < height {
-			for x := 0; x < width; x++ {
-				px := int(float64(x) * scaleX)
-				py := int(float64(y) * scaleY)
-				offsetPix := py*stride + px*4
-				r8, g8, b8, a8 := pix[offsetPix], pix[offsetPix+1], pix[offsetPix+2], pix[offsetPix+3]
-
-				if a8 == 0 {
-					lineBuilder.WriteString("\x1b[0m ")
-				} else {
-					char := pixelToASCII(r8, g8, b8, multiplier)
-					if colorOutput {
-						lineBuilder.WriteString(fmt.Sprintf("\x1b[38;2;%d;%d;%dm%s\x1b[0m", r8, g8, b8, char))
-					} else {
-						lineBuilder.WriteString(char)
-					}
-				}
+	syncUpdates := *syncMode == "on" || (*syncMode == "auto" && ttyErr == nil && term.SyncSupported(tty))
+	// Keep the terminal open to read key presses during playback
+	if ttyErr == nil && !*keyControls && !*exitOnKey {
+		tty.Close()
+		tty = nil
+	}
+
+	// Fonts aren't all twice as tall as wide, the terminal may know better
+	if cfg.CellAspect == 0 {
+		cfg.CellAspect = render.DefaultCellAspect
+		if exporting && *format == ExportGIF {
+			cfg.CellAspect = float64(fontCellWidth) / fontCellHeight // The cells of the GIF instead
+		} else if aspect, ok := term.DetectCellAspect(os.Stdout); ok {
+			cfg.CellAspect = aspect
+		}
+	}
+
+	// --- Compiled art has the size and renderer it was compiled with ---
+	if compiled != nil {
+		width.auto = false
+		cfg.Width, cfg.Height, cfg.Renderer = compiled.Width, compiled.Height, compiled.Renderer
+	}
+
+	// --- Size the art to the terminal ---
+	ratio := float64(cfg.Height) / float64(cfg.Width)
+	if width.auto {
+		waitSysInfo() // The art gets the space the sysinfo leaves
+		if cols, rows, err := term.Size(os.Stdout); err == nil {
+			cfg.Width, cfg.Height = render.Fit(cols, rows, sysInfo, ratio, cfg)
+		}
+	}
+	// The sysinfo gets the columns the art leaves, exports have no terminal
+	if !exporting && !motd && !serving {
+		if cols, _, err := term.Size(os.Stdout); err == nil {
+			cfg.InfoWidth = render.InfoColumns(cols, cfg)
+		}
+	}
+
+	// --- Only decode the input when its frames aren't cached ---
+	video := decode.VideoOptions{FPS: *videoFPS, MaxFrames: *maxFrames}
+	var anim *decode.Animation
+	openItem := func(path string) *decode.Animation {
+		itemAnim, err := decode.Open(path, video)
+		if err == nil && *segment != "" {
+			itemAnim, err = decode.Segment(itemAnim, segmentStart, segmentEnd)
+		}
+		if err == nil && crop != nil {
+			itemAnim, err = decode.Crop(itemAnim, crop.rect(itemAnim.Width, itemAnim.Height))
+		}
+		if err == nil && *trimBorders {
+			itemAnim, err = decode.Trim(itemAnim)
+		}
+		if err == nil && *direction == DirectionReverse {
+			itemAnim, err = decode.Reverse(itemAnim)
+		} else if err == nil && *direction == DirectionPingPong {
+			itemAnim, err = decode.PingPong(itemAnim)
+		}
+		if err != nil {
+			fail(err)
+		}
+		return itemAnim
+	}
+	openInput := func() *decode.Animation {
+		if anim != nil {
+			return anim
+		}
+		if len(playlist) == 1 {
+			anim = openItem(playlist[0].path)
+			return anim
+		}
+		items := make([]decode.SequenceItem, len(playlist))
+		for i, item := range playlist {
+			items[i] = decode.SequenceItem{Anim: openItem(item.path), Loops: item.loops}
+		}
+		var err error
+		if anim, err = decode.Sequence(items); err != nil {
+			fail(err)
+		}
+		return anim
+	}
+
+	// Playlists are rendered every time, the cache and the daemon are per
+	// input. No cache when the input can't be read.
+	var cachePath, renderKey string
+	if len(playlist) == 1 && compiled == nil && (attaching || !*noCache) {
+		renderKey, _ = renderCacheKey(playlist[0].path, cfg, video, fmt.Sprintf("trim=%s crop=%s trim-borders=%t direction=%s", *segment, *cropFlag, *trimBorders, *direction))
+	}
+	if !*noCache && renderKey != "" {
+		cachePath, _ = renderCachePath(renderKey)
+	}
+	attaching = attaching && renderKey != ""
+
+	// renderArt renders the art of every frame, compiled files have it
+	renderArt := func(deliver func(art []string, delay time.Duration) bool) error {
+		if compiled == nil {
+			return render.Frames(openInput(), cfg, deliver)
+		}
+		for i, art := range compiled.Frames {
+			if !deliver(art, compiled.Delays[i]) {
+				break
 			}
+		}
+		return nil
+	}
+	loopCount := func() int {
+		if compiled != nil {
+			return compiled.Loops
+		}
+		return openInput().Loops
+	}
+
+	// --- Render the art once, to play it later without rendering ---
+	if compiling {
+		if tty != nil {
+			tty.Close()
+		}
+		c := compiledAnimation{Width: cfg.Width, Height: cfg.Height, Renderer: cfg.Renderer, ColorMode: cfg.ColorMode, Loops: openInput().Loops}
+		if *loops >= 0 {
+			c.Loops = *loops
+		}
+		err := renderArt(func(art []string, delay time.Duration) bool {
+			c.Frames = append(c.Frames, art)
+			c.Delays = append(c.Delays, delay)
+			return true
+		})
+		if err == nil {
+			err = saveCompiled(*output, c)
+		}
+		if err != nil {
+			fail(err)
+		}
+		return
+	}
+
+	// --- Export every composed frame to a file, or serve them, to play without brrtfetch ---
+	if exporting || serving {
+		if tty != nil {
+			tty.Close()
+		}
+		waitSysInfo()
+		rec := Recording{Loops: loopCount(), Foreground: defaultExportForeground, Background: defaultExportBackground}
+		if termColors.HasForeground {
+			rec.Foreground = termColors.Foreground
+		}
+		if termColors.HasBackground {
+			rec.Background = termColors.Background
+		}
+		if *loops >= 0 {
+			rec.Loops = *loops
+		}
+		err := renderArt(func(art []string, delay time.Duration) bool {
+			rec.Frames = append(rec.Frames, render.Compose(art, cfg, sysInfo))
+			rec.Delays = append(rec.Delays, render.FrameDelay(cfg, delay))
+			return true
+		})
+		switch {
+		case err == nil && serving:
+			err = serveRecording(*listen, rec)
+		case err == nil:
+			err = exportRecording(exportPath, *format, rec)
+		}
+		if err != nil {
+			fail(err)
+		}
+		return
+	}
+
+	// --- A row of the art for tmux's status line, the frame it's at right now ---
+	if *tmuxStatus {
+		if tty != nil {
+			tty.Close()
+		}
+		cached, ok := loadRenderCache(cachePath)
+		if compiled != nil {
+			cached, ok = compiled.render(), true
+		}
+		if !ok {
+			if cached.Frames, cached.Delays, err = render.Prerender(openInput(), cfg, 0); err != nil {
+				fail(err)
+			}
+			if cachePath != "" {
+				cached.Loops = openInput().Loops
+				saveRenderCache(cachePath, cached)
+			}
+		}
+		for _, line := range cached.Frames[tmuxFrame(cached.Delays, cfg, time.Now())] {
+			fmt.Println(tmuxStatusLine(line))
+		}
+		return
+	}
+
+	// --- Escape sequences for the screen and cursor only make a mess of files and pagers ---
+	piped := !term.IsTerminal(os.Stdout)
+	if (piped && *pipeMode == "frame" || motd) && !still.set {
+		still.set = true
+	}
+
+	// --- A single frame printed like a regular fetcher, for shell startup files ---
+	if still.set {
+		if tty != nil {
+			tty.Close()
+		}
+		waitSysInfo()
+		var art []string
+		var cached cachedRender
+		ok := false
+		if compiled != nil {
+			cached, ok = compiled.render(), true
+		}
+		if !ok && attaching {
+			cached, ok = loadDaemonRender(*socket, renderKey)
+		}
+		if !ok {
+			cached, ok = loadRenderCache(cachePath)
+		}
+		if ok {
+			art = cached.Frames[still.frame%len(cached.Frames)]
+		} else if art, err = render.Still(openInput(), cfg, still.frame); err != nil {
+			fail(err)
+		}
+		frame := render.Compose(art, cfg, sysInfo)
+		if *stripColor {
+			err = writeTextFrame(os.Stdout, frame)
 		} else {
-			// Pad with spaces if GIF is shorter than totalHeight
-			lineBuilder.WriteString(strings.Repeat(" ", width))
+			printFrame(os.Stdout, frame)
+		}
+		if err != nil {
+			fail(err)
 		}
+		return
+	}
 
-		// Append sysinfo line if exists and within offset
-		sysIndex := y - offset
-		if sysIndex >= 0 && sysIndex < len(sysInfo) {
-			lineBuilder.WriteString("   ")
-			lineBuilder.WriteString(sysInfo[sysIndex])
+	if piped {
+		if tty != nil {
+			tty.Close()
 		}
+		waitSysInfo()
+		out := bufio.NewWriter(os.Stdout)
+		first := true
+		err := renderArt(func(art []string, delay time.Duration) bool {
+			if !first {
+				out.WriteString("\f")
+			}
+			first = false
+			printFrame(out, render.Compose(art, cfg, sysInfo))
+			return true
+		})
+		out.Flush()
+		if err != nil {
+			fail(err)
+		}
+		return
+	}
 
-		lines[y] = lineBuilder.String()
+	// --- Render everything up front, unless it doesn't fit the memory budget ---
+	streaming := !*prerenderAll && compiled == nil
+	var artFrames [][]string
+	var delays []time.Duration
+	var starts []int
+	inputLoops := 0
+	if !streaming {
+		var cached cachedRender
+		ok, fromDaemon := false, false
+		if compiled != nil {
+			cached, ok = compiled.render(), true
+		}
+		if !ok && attaching {
+			cached, ok = loadDaemonRender(*socket, renderKey)
+			fromDaemon = ok
+		}
+		if !ok && cachePath != "" {
+			cached, ok = loadRenderCache(cachePath)
+		}
+		artFrames, delays, inputLoops = cached.Frames, cached.Delays, cached.Loops
+		if !ok {
+			artFrames, delays, err = render.Prerender(openInput(), cfg, int64(*maxMemory)<<20)
+			inputLoops = openInput().Loops
+			switch {
+			case err == render.ErrOverBudget:
+				streaming = true
+			case err != nil:
+				fail(err)
+			case cachePath != "":
+				// Best effort, next run just renders again
+				saveRenderCache(cachePath, cachedRender{Frames: artFrames, Delays: delays, Loops: inputLoops})
+			}
+			if err == nil {
+				starts = frameStarts(openInput().Starts, delays)
+			}
+		}
+		// The daemon keeps them for every following attach, in any terminal
+		if attaching && !fromDaemon && !streaming {
+			saveDaemonRender(*socket, renderKey, cachedRender{Frames: artFrames, Delays: delays, Loops: inputLoops})
+		}
+	} else {
+		inputLoops = openInput().Loops
+	}
+	for i := range delays {
+		delays[i] = render.FrameDelay(cfg, delays[i])
 	}
 
-	return lines
-}
+	// Static images are printed once, just like a regular fetcher would
+	if !streaming && len(artFrames) == 1 && !*hold {
+		if tty != nil {
+			tty.Close()
+		}
+		waitSysInfo()
+		printFrame(os.Stdout, render.Compose(artFrames[0], cfg, sysInfo))
+		return
+	}
 
-// Map pixel brightness to ASCII
-func pixelToASCII(r, g, b uint8, multiplier float64) string {
-	lum := 0.2126*float64(r) + 0.7152*float64(g) + 0.0722*float64(b)
-	switch {
-	case lum > 1000*multiplier: // Needs retuning
-		return " "
-	case lum > 250*multiplier:
-		return "."
-	case lum > 180*multiplier:
-		return "◌"
-	case lum > 140*multiplier:
-		return "*"
-	case lum > 120*multiplier:
-		return "●"
-	case lum > 60*multiplier:
-		return "⦾"
-	case lum > 30*multiplier:
-		return "⦿"
+	// Use the sysinfo when it's already there, otherwise it's filled in
+	// once the command is done
+	select {
+	case sysInfo = <-sysInfoReady:
+		sysInfoKnown = true
 	default:
-		return "⬤"
 	}
-}
 
-// replace your existing runCommand with this one
-func runCommand(commandLine string) string {
-	parts := strings.Fields(commandLine)
-	if len(parts) == 0 {
-		return ""
+	// Packed frames are composed one at a time, the art is only kept packed
+	var artPacked *term.PackedFrames
+	if *compressFrames && !streaming {
+		artPacked, artFrames = term.PackFrames(artFrames), nil
+	}
+	composeFrames := func(p *term.Player, lines []string) {
+		if artPacked != nil {
+			p.Packed = render.ComposePacked(artPacked, cfg, lines)
+		} else {
+			p.Frames = render.ComposeAll(artFrames, cfg, lines)
+		}
+	}
+
+	// --- Enter alternate screen buffer ---
+	if !*inline {
+		fmt.Print("\033[?1049h")
+	}
+
+	// --- Setup cursor visibility ---
+	writer := bufio.NewWriter(os.Stdout)
+	writer.WriteString(ANSI_HIDE_CURSOR)
+	writer.Flush()
+
+	// Sixel images can't be diffed cell by cell, they are always redrawn
+	player := &term.Player{
+		Writer:      writer,
+		Screen:      term.Screen{Diff: *diffDraw && cfg.Renderer != render.RendererSixel, Sync: syncUpdates, Inline: *inline},
+		Delays:      delays,
+		Loops:       inputLoops,
+		Duration:    *duration,
+		FrameSkip:   *frameSkip,
+		Exit:        *exitFrame,
+		AnyKeyQuits: *exitOnKey,
+		Adaptive:    *adaptive && cfg.Renderer != render.RendererSixel,
+	}
+	if *loops >= 0 {
+		player.Loops = *loops
+	}
+	if !streaming {
+		composeFrames(player, sysInfo)
+	}
+
+	// --- Scripts and signals control the player too ---
+	var controlListener net.Listener
+	control := make(chan term.Command)
+	player.Control, player.Starts = control, starts
+	controlSignals(control)
+	if *watch {
+		watched := []string{path}
+		if *playlistFile != "" {
+			watched = append(watched, expandHome(*playlistFile))
+		}
+		for _, item := range playlist {
+			watched = append(watched, item.path)
+		}
+		if err := watchFiles(watched, control); err != nil {
+			fail(err)
+		}
+	}
+	if *controlPath != "" {
+		if controlListener, err = listenControl(expandHome(*controlPath), control); err != nil {
+			fail(err)
+		}
+	}
+
+	// --- Read key presses, the terminal is put back on the way out ---
+	var keys <-chan string
+	if tty != nil {
+		if keys, err = tty.Keys(); err != nil {
+			keys = nil
+		}
 	}
 
-	run := func(name string, args ...string) (string, error) {
-		cmd := exec.Command(name, args...)
-		cmd.Env = append(os.Environ(), "TERM=xterm-256color")
-		out, err := cmd.CombinedOutput()
-		return string(out), err
+	// --- Leave the terminal as we found it, with a frame printed ---
+	var restoreOnce sync.Once
+	restore := func() {
+		restoreOnce.Do(func() {
+			writer.Flush()
+			if tty != nil {
+				tty.Close()
+			}
+			if *inline {
+				// The frame stays where it played, the prompt comes back below it
+				if frame := player.ExitFrame(); frame != nil {
+					cols, rows, _ := term.Size(os.Stdout)
+					player.Screen.Invalidate()
+					player.Screen.Draw(writer, frame, cols, rows)
+				} else {
+					player.Screen.Clear(writer)
+				}
+				player.Screen.Release(writer)
+				writer.Flush()
+			} else {
+				fmt.Print("\033[?1049l") // exit alternate screen
+				for _, line := range player.ExitFrame() {
+					fmt.Println(line)
+				}
+			}
+			fmt.Print(ANSI_SHOW_CURSOR)
+			fmt.Print("\033[0m")
+			if controlListener != nil {
+				controlListener.Close() // Removes the socket file too
+			}
+			cleanup()
+		})
 	}
+	defer restore()
+	restoreTerminal = restore
 
-	flags := []string{"-qefc"}
-		if runtime.GOOS == "darwin" {
-			flags = []string{"-q -c"}
+	// --- Handle Ctrl-C gracefully ---
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM) // SIGTERM is never sent on Windows, but harmless
+	go func() {
+		<-sigs
+		restore()
+		os.Exit(0)
+	}()
+
+	// --- The sysinfo arrives late or live modules keep it changing while playing ---
+	var currentInfo atomic.Value
+	currentInfo.Store(sysInfo)
+	sysInfoNow := func() []string {
+		return currentInfo.Load().([]string)
+	}
+	updates := make(chan []string, 1)
+	player.Info = updates
+	go func(known bool) {
+		if !known {
+			updates <- <-sysInfoReady
+		}
+		// Live modules replace all of the sysinfo, so only when that's
+		// nothing but the built-in modules
+		if *liveModules != "" && len(sources) == 1 && sources[0] == (sysinfo.Source{Command: sources[0].Command, Default: sources[0].Default}) &&
+			sysinfo.UsesNative(sources[0].Command, sources[0].Default) {
+			sysinfo.Watch(*modules, *liveModules, *liveInterval, infoTheme, updates)
+		}
+	}(sysInfoKnown)
+	player.OnInfo = func(p *term.Player, lines []string) {
+		currentInfo.Store(lines)
+		if !streaming {
+			composeFrames(p, lines)
+		}
 	}
 
-	// 1) Try `script` with safe flags
-	if _, err := exec.LookPath("script"); err == nil {
-		// -q quiet, -e exit immediately, -f flush, -c to run command, /dev/null as log
-		out, _ := run("script", append(flags, commandLine+" 2>/dev/null", "/dev/null")...)
-		return out
+	// --- Long animations are rendered while playing instead ---
+	var stopStream chan struct{}
+	startStream := func() {
+		stopStream = make(chan struct{})
+		player.Stream = render.Stream(openInput(), cfg, sysInfoNow, cfg.Workers*2, stopStream)
+	}
+	if streaming {
+		startStream()
 	}
 
-	// 2) Try unbuffer
-	if _, err := exec.LookPath("unbuffer"); err == nil {
-		out, _ := run("unbuffer", parts...)
-		return out
+	// Auto sized art has to be rendered again for the new size, the sysinfo
+	// fitted to the columns next to it
+	player.OnResize = func(p *term.Player, cols, rows int) bool {
+		if cols <= 0 {
+			return false
+		}
+		if width.auto {
+			cfg.Width, cfg.Height = render.Fit(cols, rows, sysInfoNow(), ratio, cfg)
+		}
+		cfg.InfoWidth = render.InfoColumns(cols, cfg)
+		if streaming {
+			close(stopStream)
+			startStream()
+			return true
+		}
+		if !width.auto {
+			composeFrames(p, sysInfoNow())
+			return true
+		}
+		var err error
+		if artFrames, delays, err = render.Prerender(openInput(), cfg, 0); err != nil {
+			fail(err)
+		}
+		for i := range delays {
+			delays[i] = render.FrameDelay(cfg, delays[i])
+		}
+		if artPacked != nil {
+			artPacked, artFrames = term.PackFrames(artFrames), nil
+		}
+		p.Delays = delays
+		composeFrames(p, sysInfoNow())
+		return true
 	}
 
-	// 3) Fallback
-	out, err := run(parts[0], parts[1:]...)
+	// --- Ctrl-Z leaves the alternate screen while stopped ---
+	suspended := make(chan os.Signal, 1)
+	player.Suspend = suspended
+	player.OnSuspend = func(p *term.Player) {
+		if *inline {
+			p.Screen.Release(writer)
+			writer.WriteString(ANSI_SHOW_CURSOR + "\033[0m")
+		} else {
+			writer.WriteString("\033[?1049l" + ANSI_SHOW_CURSOR + "\033[0m")
+		}
+		writer.Flush()
+		term.Suspend(tty)
+		if !*inline {
+			writer.WriteString("\033[?1049h")
+		}
+		writer.WriteString(ANSI_HIDE_CURSOR)
+		writer.Flush()
+	}
+	term.NotifySuspend(suspended)
+
+	// --- Start over with a clean screen when the terminal is resized ---
+	resized := make(chan os.Signal, 1)
+	term.NotifyResize(resized)
+
+	// ----- Animation loop -----
+	err = player.Run(keys, resized)
+	if errors.Is(err, term.ErrReload) {
+		restore()
+		err = reexec(originalArgs)
+	}
 	if err != nil {
-		return fmt.Sprintf("Error running command: %s\n%s", parts[0], out)
+		fail(err)
 	}
-	return out
 }
 
-// getCommandOutputLines executes the command and returns trimmed lines
-func getCommandOutputLines(commandLine string) []string {
-	output := runCommand(commandLine)
-	lines := strings.Split(output, "\n")
-	var cleanLines []string
-	for _, line := range lines {
-		// Trim trailing CR/LF
-		line = strings.TrimRight(line, "\r\n")
-		if line != "" {
-			cleanLines = append(cleanLines, line)
+// frameStarts finds the frames where the inputs of a playlist start, from
+// the times decode.Sequence gives and the frame delays. Merged or dropped
+// frames leave the total time as it was.
+func frameStarts(times, delays []time.Duration) []int {
+	if len(times) < 2 {
+		return nil
+	}
+	var starts []int
+	var elapsed time.Duration
+	next := 0
+	for i, delay := range delays {
+		for next < len(times) && times[next] <= elapsed {
+			if len(starts) == 0 || starts[len(starts)-1] != i {
+				starts = append(starts, i)
+			}
+			next++
 		}
+		elapsed += delay
 	}
-	return cleanLines
+	return starts
 }
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/render"
+)
+
+// tintColor turns a -tint value into SGR parameters: an index of the 256
+// color palette, the first 16 as the terminal's own colors, or a #rrggbb
+// color written in mode
+func tintColor(value, mode string) (string, error) {
+	if n, err := strconv.Atoi(value); err == nil {
+		switch {
+		case n < 0 || n > 255:
+			return "", fmt.Errorf("color index %d is out of range, use 0 to 255", n)
+		case n < 8:
+			return strconv.Itoa(30 + n), nil
+		case n < 16:
+			return strconv.Itoa(90 + n - 8), nil
+		}
+		return "38;5;" + strconv.Itoa(n), nil
+	}
+	c, err := parseHexColor(value)
+	if err != nil {
+		return "", err
+	}
+	return render.SGRColor(mode, false, c), nil
+}
+
+// loadPalette resolves a -palette value: the name of a preset or a palette
+// file
+func loadPalette(value string) ([]color.RGBA, error) {
+	if palette, ok := render.PalettePreset(value); ok {
+		return palette, nil
+	}
+	palette, err := readPaletteFile(expandHome(value))
+	if err != nil {
+		return nil, err
+	}
+	return palette, render.ValidatePalette(palette)
+}
+
+// readPaletteFile reads a color per line, written as #rrggbb or #rgb and
+// optionally followed by its name. Blank lines and lines starting with a #
+// that isn't a color are skipped.
+//
+//	# Catppuccin Mocha
+//	#1e1e2e base
+//	#cdd6f4 text
+func readPaletteFile(path string) ([]color.RGBA, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var palette []color.RGBA
+	scanner := bufio.NewScanner(f)
+	lineNr := 0
+	for scanner.Scan() {
+		lineNr++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		c, err := parseHexColor(fields[0])
+		if err != nil {
+			if strings.HasPrefix(fields[0], "#") {
+				continue // A comment
+			}
+			return nil, fmt.Errorf("%s:%d: %v", path, lineNr, err)
+		}
+		palette = append(palette, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return palette, nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// playlistItem is an input to play and how many times it plays before the
+// next one starts
+type playlistItem struct {
+	path  string
+	loops int
+}
+
+// readPlaylist reads a playlist file: an input per line, optionally followed
+// by the number of times it plays. Blank lines and lines starting with # are
+// skipped, relative paths are relative to the playlist.
+//
+//	~/Pictures/brrtfetch/gifs/defaults/brrt.gif 3
+//	seasonal/pumpkin.gif
+func readPlaylist(path string) ([]playlistItem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var items []playlistItem
+	scanner := bufio.NewScanner(f)
+	lineNr := 0
+	for scanner.Scan() {
+		lineNr++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// The count is the last field, paths may contain spaces
+		item := playlistItem{path: line, loops: 1}
+		if i := strings.LastIndexAny(line, " \t"); i >= 0 {
+			if n, err := strconv.Atoi(line[i+1:]); err == nil {
+				if n < 1 {
+					return nil, fmt.Errorf("%s:%d: loop count has to be at least 1", path, lineNr)
+				}
+				item.path, item.loops = strings.TrimSpace(line[:i]), n
+			}
+		}
+		item.path = expandHome(item.path)
+		if !isRemote(item.path) && !filepath.IsAbs(item.path) {
+			item.path = filepath.Join(filepath.Dir(path), item.path)
+		}
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%s: playlist is empty", path)
+	}
+	return items, nil
+}
+
+// Extensions of the files a directory input picks from
+var animationExtensions = map[string]bool{
+	".gif": true, ".png": true, ".jpg": true, ".jpeg": true, ".bmp": true,
+	".mp4": true, ".webm": true, ".mkv": true, ".mov": true,
+}
+
+// pickFromDir picks a random animation from dir and the directories below
+// it, every file has the same chance
+func pickFromDir(dir string, rng *rand.Rand) (string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && animationExtensions[strings.ToLower(filepath.Ext(path))] {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("%s: no GIFs, images or videos in this directory", dir)
+	}
+	return files[rng.Intn(len(files))], nil
+}
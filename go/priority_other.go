@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd || windows)
+
+package main
+
+// lowerPriority does nothing, there are no process priorities on this
+// platform. The capped workers still help.
+func lowerPriority() error {
+	return nil
+}
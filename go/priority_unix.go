@@ -0,0 +1,14 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package main
+
+import "syscall"
+
+// Niceness of -low-priority, like nice(1) uses without -n
+const lowPriorityNice = 10
+
+// lowerPriority makes brrtfetch, and the commands it starts, give the CPU
+// to everything else first
+func lowerPriority() error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, 0, lowPriorityNice)
+}
@@ -0,0 +1,21 @@
+//go:build windows
+
+package main
+
+import "syscall"
+
+const belowNormalPriorityClass = 0x4000
+
+var procSetPriorityClass = syscall.NewLazyDLL("kernel32.dll").NewProc("SetPriorityClass")
+
+// lowerPriority puts brrtfetch in the below normal priority class
+func lowerPriority() error {
+	process, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return err
+	}
+	if ok, _, err := procSetPriorityClass.Call(uintptr(process), belowNormalPriorityClass); ok == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	_ "net/http/pprof" // The handlers of the -pprof listener
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"sync"
+)
+
+// profiling writes the profiles asked for with -cpuprofile, -memprofile
+// and -trace, to find out where a slow render spends its time
+type profiling struct {
+	cpu, trace *os.File
+	memPath    string
+	once       sync.Once
+}
+
+// startProfiling starts the CPU profile and trace and the -pprof listener,
+// as far as they're asked for. Stop writes them out.
+func startProfiling(cpuPath, memPath, tracePath, listen string) (*profiling, error) {
+	p := &profiling{memPath: memPath}
+	var err error
+	if cpuPath != "" {
+		if p.cpu, err = os.Create(cpuPath); err != nil {
+			return nil, err
+		}
+		if err = pprof.StartCPUProfile(p.cpu); err != nil {
+			p.cpu.Close()
+			return nil, err
+		}
+	}
+	if tracePath != "" {
+		if p.trace, err = os.Create(tracePath); err == nil {
+			err = trace.Start(p.trace)
+		}
+		if err != nil {
+			p.Stop()
+			return nil, err
+		}
+	}
+	if listen != "" {
+		listener, err := net.Listen("tcp", listen)
+		if err != nil {
+			p.Stop()
+			return nil, err
+		}
+		go http.Serve(listener, nil)
+	}
+	return p, nil
+}
+
+// Stop writes the profiles, only the first call does anything
+func (p *profiling) Stop() {
+	p.once.Do(func() {
+		if p.cpu != nil {
+			pprof.StopCPUProfile()
+			p.cpu.Close()
+		}
+		if p.trace != nil {
+			trace.Stop()
+			p.trace.Close()
+		}
+		if p.memPath == "" {
+			return
+		}
+		f, err := os.Create(p.memPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "brrtfetch: %s\n", errorMessage(err))
+			return
+		}
+		defer f.Close()
+		runtime.GC() // Up to date statistics of what's still in use
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			fmt.Fprintf(os.Stderr, "brrtfetch: %s\n", errorMessage(err))
+		}
+	})
+}
@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package main
+
+import "errors"
+
+// reexec can't replace the process on this platform
+func reexec(args []string) error {
+	return errors.New("reload-config isn't supported on this platform, restart brrtfetch instead")
+}
@@ -0,0 +1,18 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// reexec replaces brrtfetch with a fresh start with the same arguments, so
+// the config file and everything derived from it is read again
+func reexec(args []string) error {
+	path, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	return syscall.Exec(path, args, os.Environ())
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// isRemote reports whether the input should be downloaded first
+func isRemote(input string) bool {
+	return strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://")
+}
+
+// cacheDir is where downloads (and later other cached data) are kept,
+// usually ~/.cache/brrtfetch
+func cacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "brrtfetch"), nil
+}
+
+// fetchRemote downloads rawURL and returns the path of the local copy.
+// Downloads are cached by a hash of the URL, so the network is only used the
+// first time. With noCache the file goes to a temporary file of its own
+// instead and temporary is true, the caller removes it when done.
+func fetchRemote(rawURL string, timeout time.Duration, noCache bool) (local string, temporary bool, err error) {
+	// Keep the extension around, it makes the cache folder easier to browse
+	// and tells the decoders what a temporary file is
+	ext := ""
+	if u, err := url.Parse(rawURL); err == nil {
+		ext = path.Ext(u.Path)
+	}
+
+	var dir, name string
+	if !noCache {
+		if dir, err = cacheDir(); err != nil {
+			return "", false, err
+		}
+		if err = os.MkdirAll(dir, 0o755); err != nil {
+			return "", false, err
+		}
+		sum := sha256.Sum256([]byte(rawURL))
+		name = hex.EncodeToString(sum[:]) + ext
+		local = filepath.Join(dir, name)
+		if _, err := os.Stat(local); err == nil {
+			return local, false, nil
+		}
+	}
+
+	client := http.Client{Timeout: timeout}
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", false, fmt.Errorf("downloading %s: %s", rawURL, resp.Status)
+	}
+
+	// Cached downloads are written next to the final name and renamed, so an
+	// interrupted one never ends up in the cache. Temporary ones get a
+	// unique name, other shells may be downloading the same URL.
+	var tmp *os.File
+	if noCache {
+		tmp, err = os.CreateTemp("", "brrtfetch-*"+ext)
+	} else {
+		tmp, err = os.CreateTemp(dir, name+".*.part")
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if _, err = io.Copy(tmp, resp.Body); err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err == nil && noCache {
+		return tmp.Name(), true, nil
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), local)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", false, fmt.Errorf("downloading %s: %w", rawURL, err)
+	}
+	return local, false, nil
+}
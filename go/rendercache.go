@@ -0,0 +1,113 @@
+package main
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/decode"
+	"github.com/ferrebarrat/brrtfetch/pkg/render"
+)
+
+// Bump whenever renderers change their output, so old cache files are ignored
+const renderCacheVersion = 3
+
+// cachedRender is what's stored in a render cache file
+type cachedRender struct {
+	Frames [][]string
+	Delays []time.Duration
+	Loops  int
+}
+
+// renderCachePath returns the cache file of the frames renderCacheKey
+// identifies
+func renderCachePath(key string) (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, key+".brrt"), nil
+}
+
+// renderCacheKey identifies input rendered with cfg, with the frames cut as
+// cuts describes first. It's a hash of the file contents and every option
+// that changes the art, so changing any of them simply misses the cache.
+func renderCacheKey(input string, cfg render.Config, video decode.VideoOptions, cuts string) (string, error) {
+	f, err := os.Open(input)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	// Timing and sysinfo placement are applied after loading
+	cfg.FPS = 0
+	cfg.Offset = 0
+	cfg.Layout = ""
+	cfg.InfoAlign, cfg.InfoOffsetX = "", 0
+	cfg.InfoWidth, cfg.InfoOverflow = 0, ""
+	cfg.Workers = 0
+	cfg.Gap, cfg.PaddingTop, cfg.PaddingLeft, cfg.PaddingRight, cfg.PaddingBottom = 0, 0, 0, 0, 0
+	fmt.Fprintf(h, "\x00%d\x00%+v\x00%+v\x00%s", renderCacheVersion, cfg, video, cuts)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// loadRenderCache reads frames rendered by an earlier run, ok is false when
+// there are none (or the file is damaged).
+func loadRenderCache(path string) (cached cachedRender, ok bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return cached, false
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return cached, false
+	}
+	if err := gob.NewDecoder(zr).Decode(&cached); err != nil {
+		return cached, false
+	}
+	if len(cached.Frames) == 0 || len(cached.Frames) != len(cached.Delays) {
+		return cached, false
+	}
+	return cached, true
+}
+
+// saveRenderCache stores rendered frames for the next run. The file is
+// written under a temporary name first so a crash never leaves half a cache
+// file behind.
+func saveRenderCache(path string, cached cachedRender) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.part")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	zw := gzip.NewWriter(tmp)
+	err = gob.NewEncoder(zw).Encode(cached)
+	if err == nil {
+		err = zw.Close()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
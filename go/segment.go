@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/decode"
+)
+
+// parseSegment reads a -trim value "START:END" into the marks for
+// decode.Segment. Either side is a frame index or a time like 1.5s, and may
+// be left empty to keep that end of the animation, e.g. "12:" or ":3s".
+func parseSegment(value string) (start, end *decode.Mark, err error) {
+	from, to, ok := strings.Cut(value, ":")
+	if !ok {
+		return nil, nil, fmt.Errorf("%q is not a segment, use START:END", value)
+	}
+	if start, err = parseMark(from); err == nil {
+		end, err = parseMark(to)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%q is not a segment, use START:END as frame numbers or times like 1.5s: %v", value, err)
+	}
+	return start, end, nil
+}
+
+// parseMark reads one side of a segment, nil when it's empty
+func parseMark(value string) (*decode.Mark, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if frame, err := strconv.Atoi(value); err == nil {
+		if frame < 0 {
+			return nil, fmt.Errorf("frame %d is negative", frame)
+		}
+		return &decode.Mark{Frame: frame}, nil
+	}
+	at, err := time.ParseDuration(value)
+	if err != nil {
+		return nil, err
+	}
+	if at < 0 {
+		return nil, fmt.Errorf("time %s is negative", value)
+	}
+	return &decode.Mark{Time: at, Timed: true}, nil
+}
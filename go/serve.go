@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Address brrtfetch serve listens on unless -listen says otherwise
+const defaultServeAddress = ":8080"
+
+// Appended to the key of a WebSocket handshake before hashing it (RFC 6455)
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes brrtfetch serve uses
+const (
+	websocketText  = 0x1
+	websocketClose = 0x8
+)
+
+// serveRecording plays rec to everyone connecting to listen, until it fails:
+//
+//	/        a page that plays the frames, like an HTML export
+//	/stream  the frames with their escape sequences, as they would be drawn
+//	         in a terminal, streamed with their delays (curl plays it)
+//	/ws      the same over WebSocket, a text message per frame
+func serveRecording(listen string, rec Recording) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		writeHTML(w, rec)
+	})
+	mux.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		flusher, _ := w.(http.Flusher)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Cache-Control", "no-cache")
+		playRecording(rec, r.Context().Done(), func(text string) error {
+			if _, err := io.WriteString(w, text); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			return nil
+		})
+	})
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		serveWebSocket(w, r, rec)
+	})
+
+	fmt.Fprintf(os.Stderr, "brrtfetch serving on %s (/, /stream and /ws)\n", listen)
+	return http.ListenAndServe(listen, mux)
+}
+
+// playRecording hands send every frame of rec at its time, as text that
+// draws it at the top left of a terminal, until all loops were played, stop
+// is closed or send fails
+func playRecording(rec Recording, stop <-chan struct{}, send func(text string) error) {
+	if len(rec.Frames) == 0 {
+		return
+	}
+	if send(ANSI_HIDE_CURSOR+"\033[2J") != nil {
+		return
+	}
+	for played := 0; rec.Loops == 0 || played < rec.Loops; played++ {
+		for i, frame := range rec.Frames {
+			if send("\033[H"+strings.Join(frame, "\r\n")) != nil {
+				return
+			}
+			select {
+			case <-time.After(rec.Delays[i]):
+			case <-stop:
+				return
+			}
+		}
+	}
+	send("\033[0m" + ANSI_SHOW_CURSOR + "\r\n")
+}
+
+// serveWebSocket takes over the connection of a WebSocket handshake and
+// plays rec on it, a text message per frame
+func serveWebSocket(w http.ResponseWriter, r *http.Request, rec Recording) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" || !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		http.Error(w, "brrtfetch: /ws only speaks WebSocket", http.StatusBadRequest)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "brrtfetch: can't take over the connection", http.StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	accept := sha1.Sum([]byte(key + websocketGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(accept[:]) + "\r\n\r\n")
+	if rw.Flush() != nil {
+		return
+	}
+
+	// Clients only send to close the connection, or when they go away
+	closed := make(chan struct{})
+	go func() {
+		readWebSocket(rw.Reader)
+		close(closed)
+	}()
+	playRecording(rec, closed, func(text string) error {
+		return writeWebSocket(rw.Writer, websocketText, []byte(text))
+	})
+	writeWebSocket(rw.Writer, websocketClose, nil)
+}
+
+// writeWebSocket sends a single unmasked frame, as servers do
+func writeWebSocket(w *bufio.Writer, opcode byte, payload []byte) error {
+	w.WriteByte(0x80 | opcode) // FIN, the message is in one frame
+	switch n := len(payload); {
+	case n < 126:
+		w.WriteByte(byte(n))
+	case n <= 0xffff:
+		w.WriteByte(126)
+		binary.Write(w, binary.BigEndian, uint16(n))
+	default:
+		w.WriteByte(127)
+		binary.Write(w, binary.BigEndian, uint64(n))
+	}
+	w.Write(payload)
+	return w.Flush()
+}
+
+// readWebSocket skips the frames a client sends and returns once it closes
+// the connection
+func readWebSocket(r *bufio.Reader) error {
+	var header [2]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return err
+		}
+		if header[0]&0x0f == websocketClose {
+			return nil
+		}
+		length := uint64(header[1] & 0x7f)
+		switch length {
+		case 126:
+			var n uint16
+			if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+				return err
+			}
+			length = uint64(n)
+		case 127:
+			if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+				return err
+			}
+		}
+		if header[1]&0x80 != 0 {
+			length += 4 // The mask
+		}
+		if length > 1<<20 {
+			return errors.New("websocket frame too large")
+		}
+		if _, err := io.CopyN(io.Discard, r, int64(length)); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// stillFlag is the -still value. Given without a value it picks the first
+// frame, -still=N picks frame N (counting from 0).
+type stillFlag struct {
+	frame int
+	set   bool
+}
+
+func (s *stillFlag) String() string {
+	if !s.set {
+		return "false"
+	}
+	return strconv.Itoa(s.frame)
+}
+
+func (s *stillFlag) Set(value string) error {
+	switch value {
+	case "true":
+		s.frame, s.set = 0, true
+		return nil
+	case "false":
+		s.frame, s.set = 0, false
+		return nil
+	}
+	frame, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+	if frame < 0 {
+		return errors.New("frame number can't be negative")
+	}
+	s.frame, s.set = frame, true
+	return nil
+}
+
+// IsBoolFlag lets -still be given without a value
+func (s *stillFlag) IsBoolFlag() bool {
+	return true
+}
+
+// printFrame writes a composed frame as plain lines, resetting the colors
+// at the end so they don't leak into whatever comes next
+func printFrame(w io.Writer, lines []string) {
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+	fmt.Fprint(w, "\033[0m")
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"image/color"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/render"
+	"github.com/ferrebarrat/brrtfetch/pkg/term"
+)
+
+// Width of -tmux-status art unless -width says otherwise
+const defaultTmuxWidth = 8
+
+// tmuxFrame picks the frame the animation is at now, had it been playing
+// since the epoch, so every refresh of the status line shows the next one
+func tmuxFrame(delays []time.Duration, cfg render.Config, now time.Time) int {
+	var total time.Duration
+	for _, delay := range delays {
+		total += render.FrameDelay(cfg, delay)
+	}
+	if total <= 0 {
+		return 0
+	}
+	at := time.Duration(now.UnixNano()) % total
+	for i, delay := range delays {
+		if at -= render.FrameDelay(cfg, delay); at < 0 {
+			return i
+		}
+	}
+	return 0
+}
+
+// tmuxStatusLine turns a rendered line into tmux's status format: its
+// colors become #[fg=...,bg=...] styles and # is escaped
+func tmuxStatusLine(line string) string {
+	var b strings.Builder
+	style := ""
+	for _, cell := range term.ParseCells(line) {
+		if cell.Style != style {
+			style = cell.Style
+			var sgr term.Style
+			sgr.Apply(strings.TrimSuffix(strings.TrimPrefix(style, "\033["), "m"))
+			b.WriteString("#[default")
+			if sgr.FG != "" {
+				b.WriteString(",fg=" + tmuxColor(sgr.FG))
+			}
+			if sgr.BG != "" {
+				b.WriteString(",bg=" + tmuxColor(sgr.BG))
+			}
+			for _, attr := range strings.Split(sgr.Attrs, ";") {
+				if attr == "1" {
+					b.WriteString(",bold")
+				}
+			}
+			b.WriteString("]")
+		}
+		b.WriteString(strings.ReplaceAll(cell.Char, "#", "##"))
+	}
+	if style != "" {
+		b.WriteString("#[default]")
+	}
+	return b.String()
+}
+
+// tmuxColor turns the SGR parameters of a color, like "38;2;255;0;0",
+// "48;5;196" or "91", into a tmux color
+func tmuxColor(sgr string) string {
+	fields := strings.FieldsFunc(sgr, func(r rune) bool { return r == ';' || r == ':' })
+	if len(fields) == 1 {
+		code, _ := strconv.Atoi(fields[0])
+		switch {
+		case code >= 30 && code <= 37, code >= 40 && code <= 47:
+			return "colour" + strconv.Itoa(code%10)
+		case code >= 90 && code <= 97, code >= 100 && code <= 107:
+			return "colour" + strconv.Itoa(code%10+8)
+		}
+		return "default"
+	}
+	switch {
+	case len(fields) >= 5 && fields[1] == "2":
+		var rgb [3]uint8
+		for i := range rgb {
+			n, _ := strconv.Atoi(fields[len(fields)-3+i])
+			rgb[i] = uint8(n)
+		}
+		return hexColor(color.RGBA{rgb[0], rgb[1], rgb[2], 255})
+	case len(fields) >= 3 && fields[1] == "5":
+		return "colour" + fields[2]
+	}
+	return "default"
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/term"
+)
+
+// How long the files have to stay the same before -watch starts over
+const watchSettle = 500 * time.Millisecond
+
+// watchFiles asks for a reload on commands once one of paths is changed,
+// created or removed. fsnotify watches the directories of the files, so
+// editors that save by replacing a file are noticed too. A change only
+// counts once the files stay the same for watchSettle, so an editor still
+// writing or a GIF still being exported doesn't reload a half written file.
+func watchFiles(paths []string, commands chan<- term.Command) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	watched := map[string]bool{}
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			watcher.Close()
+			return err
+		}
+		watched[abs] = true
+		// A directory that isn't there yet, like the one of a config file
+		// that was never written, has nothing to watch
+		if err := watcher.Add(filepath.Dir(abs)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			watcher.Close()
+			return fmt.Errorf("watching %s: %w", path, err)
+		}
+	}
+
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !watched[event.Name] {
+					continue
+				}
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				continue
+			}
+
+			stamps := fileStamps(paths)
+			for {
+				time.Sleep(watchSettle)
+				now := fileStamps(paths)
+				if now == stamps {
+					break
+				}
+				stamps = now
+			}
+			commands <- term.Command{Name: term.CommandReload}
+			return
+		}
+	}()
+	return nil
+}
+
+// fileStamps sums up the size and modification time of paths, missing
+// files included
+func fileStamps(paths []string) string {
+	var b strings.Builder
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			fmt.Fprintf(&b, "%d %d\n", info.Size(), info.ModTime().UnixNano())
+		} else {
+			b.WriteString("-\n")
+		}
+	}
+	return b.String()
+}
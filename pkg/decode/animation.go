@@ -0,0 +1,170 @@
+// Package decode turns GIFs, stills (PNG, JPEG, BMP) and, through ffmpeg,
+// videos into animations whose frames are composed on demand.
+package decode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Fallback playback rate for frames that don't specify a usable delay
+const defaultFPS = 17
+
+// Animation is a decoded input. Frames are composed one by one when the
+// render pipeline asks for them, so long animations never sit in memory as
+// full RGBA images.
+type Animation struct {
+	Width  int
+	Height int
+
+	// Loops is how many times the animation wants to be played, 0 = forever
+	Loops int
+
+	// Frames calls emit for every composed frame in playback order, until
+	// emit returns false. The image is reused between calls, emit has to copy
+	// whatever it wants to keep. Frames can be called again to start over.
+	Frames func(emit func(frame *image.RGBA, delay time.Duration) bool) error
+
+	// Starts are the times where the items of a Sequence begin, in the
+	// animation's own timing. They're known once all frames were played.
+	Starts []time.Duration
+
+	// Luminance range for auto levels, see LuminanceRange
+	levelsOnce   sync.Once
+	black, white float64
+	levelsErr    error
+}
+
+// Open picks a decoder based on the contents of the file. GIFs are
+// animated, PNG, JPEG and BMP become a single still frame and anything else
+// is handed to ffmpeg.
+func Open(path string, video VideoOptions) (*Animation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header := make([]byte, 6)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	if bytes.HasPrefix(header[:n], []byte("GIF8")) {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		anim, err := decodeGIF(f)
+		if err != nil {
+			return nil, Error{fmt.Errorf("%s: %w", path, err)}
+		}
+		return anim, nil
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(f)
+	if err == nil {
+		return Still(img), nil
+	}
+	if err != image.ErrFormat {
+		return nil, Error{fmt.Errorf("%s: %w", path, err)}
+	}
+
+	return openVideo(path, video)
+}
+
+// Still wraps a static image as an animation of one frame
+func Still(img image.Image) *Animation {
+	bounds := img.Bounds()
+	frame := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(frame, frame.Bounds(), img, bounds.Min, draw.Src)
+
+	return &Animation{
+		Width:  frame.Bounds().Dx(),
+		Height: frame.Bounds().Dy(),
+		Frames: func(emit func(frame *image.RGBA, delay time.Duration) bool) error {
+			emit(frame, time.Second/defaultFPS)
+			return nil
+		},
+	}
+}
+
+// decodeGIF decodes all frames up front, composing them (handling the GIF
+// disposal methods) is left to Frames.
+func decodeGIF(r io.Reader) (*Animation, error) {
+	g, err := gif.DecodeAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	anim := &Animation{Width: g.Config.Width, Height: g.Config.Height, Loops: gifLoops(g)}
+	anim.Frames = func(emit func(frame *image.RGBA, delay time.Duration) bool) error {
+		fullFrame := image.NewRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+		snapshot := image.NewRGBA(fullFrame.Bounds())
+		draw.Draw(fullFrame, fullFrame.Bounds(), image.NewUniform(color.Transparent), image.Point{}, draw.Src)
+
+		var lastDisposal = gif.DisposalNone
+		var lastBounds image.Rectangle
+
+		for i, frame := range g.Image {
+			if i > 0 {
+				if lastDisposal == gif.DisposalPrevious {
+					draw.Draw(fullFrame, fullFrame.Bounds(), snapshot, image.Point{}, draw.Src)
+				} else if lastDisposal != gif.DisposalNone {
+					draw.Draw(fullFrame, lastBounds, image.NewUniform(color.Transparent), image.Point{}, draw.Src)
+				}
+			}
+
+			if int(g.Disposal[i]) == gif.DisposalPrevious {
+				copy(snapshot.Pix, fullFrame.Pix)
+			}
+
+			draw.Draw(fullFrame, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+			lastDisposal = int(g.Disposal[i])
+			lastBounds = frame.Bounds()
+
+			if !emit(fullFrame, gifDelay(g, i)) {
+				break
+			}
+		}
+		return nil
+	}
+	return anim, nil
+}
+
+// gifDelay returns how long frame i stays on screen. GIF delays are stored
+// in 100ths of a second.
+func gifDelay(g *gif.GIF, i int) time.Duration {
+	// Browsers treat 0 and 1 as "as fast as possible", which usually means
+	// the GIF was exported without timing info. Use a sane default instead.
+	delay := 0
+	if i < len(g.Delay) {
+		delay = g.Delay[i]
+	}
+	if delay <= 1 {
+		return time.Second / defaultFPS
+	}
+	return time.Duration(delay) * 10 * time.Millisecond
+}
+
+// gifLoops converts the NETSCAPE loop count, which counts restarts, to the
+// number of times the GIF plays. GIFs without the extension loop forever,
+// like they always did.
+func gifLoops(g *gif.GIF) int {
+	if g.LoopCount <= 0 {
+		return 0
+	}
+	return g.LoopCount + 1
+}
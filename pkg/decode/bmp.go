@@ -0,0 +1,182 @@
+package decode
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"math/bits"
+)
+
+// Minimal BMP support for static logos: uncompressed 24 and 32 bit images,
+// which is what every image editor writes by default. 32 bit images may
+// place their channels anywhere with bit masks (BI_BITFIELDS).
+
+func init() {
+	image.RegisterFormat("bmp", "BM", decodeBMP, decodeBMPConfig)
+}
+
+type bmpHeader struct {
+	pixelOffset uint32
+	width       int
+	height      int
+	topDown     bool
+	bpp         uint16
+	compression uint32
+}
+
+// Channel masks of 32 bit pixels, read as little endian words
+type bmpMasks struct {
+	r, g, b, a uint32
+}
+
+// BI_RGB pixels are BGR, the fourth byte of 32 bit ones is padding
+var bmpDefaultMasks = bmpMasks{r: 0x00ff0000, g: 0x0000ff00, b: 0x000000ff}
+
+// Compressions that aren't: plain pixels, with or without bit masks
+const (
+	bmpRGB            = 0
+	bmpBitfields      = 3
+	bmpAlphaBitfields = 6
+)
+
+func readBMPHeader(data []byte) (bmpHeader, error) {
+	var h bmpHeader
+	if len(data) < 54 || string(data[:2]) != "BM" {
+		return h, errors.New("bmp: not a BMP file")
+	}
+
+	h.pixelOffset = binary.LittleEndian.Uint32(data[10:])
+	width := int32(binary.LittleEndian.Uint32(data[18:]))
+	height := int32(binary.LittleEndian.Uint32(data[22:]))
+	h.bpp = binary.LittleEndian.Uint16(data[28:])
+	h.compression = binary.LittleEndian.Uint32(data[30:])
+
+	if h.compression != bmpRGB && h.compression != bmpBitfields && h.compression != bmpAlphaBitfields {
+		return h, errors.New("bmp: compressed images are not supported")
+	}
+	if h.bpp != 24 && h.bpp != 32 {
+		return h, errors.New("bmp: only 24 and 32 bit images are supported")
+	}
+	if h.bpp == 24 && h.compression != bmpRGB {
+		return h, errors.New("bmp: bit masks are only supported for 32 bit images")
+	}
+	if height < 0 {
+		h.topDown = true
+		height = -height
+	}
+	h.width, h.height = int(width), int(height)
+	if h.width <= 0 || h.height <= 0 {
+		return h, errors.New("bmp: invalid dimensions")
+	}
+	return h, nil
+}
+
+// readBMPMasks finds where the channels of 32 bit pixels are. Headers of
+// version 3 and later hold the masks, the classic 40 byte one is followed
+// by them.
+func readBMPMasks(data []byte, h bmpHeader) (bmpMasks, error) {
+	if h.compression == bmpRGB {
+		return bmpDefaultMasks, nil
+	}
+	const fileHeaderSize = 14
+	infoSize := binary.LittleEndian.Uint32(data[fileHeaderSize:])
+	count := 3
+	if infoSize >= 56 || h.compression == bmpAlphaBitfields {
+		count = 4 // With the alpha mask
+	}
+	if fileHeaderSize+40+count*4 > len(data) {
+		return bmpMasks{}, errors.New("bmp: the bit masks are missing")
+	}
+	mask := func(i int) uint32 {
+		return binary.LittleEndian.Uint32(data[fileHeaderSize+40+i*4:])
+	}
+	m := bmpMasks{r: mask(0), g: mask(1), b: mask(2)}
+	if count == 4 {
+		m.a = mask(3)
+	}
+	return m, nil
+}
+
+// bmpChannel takes the bits of mask out of px, scaled to 0-255
+func bmpChannel(px, mask uint32) uint8 {
+	if mask == 0 {
+		return 0
+	}
+	shift := bits.TrailingZeros32(mask)
+	max := mask >> shift
+	return uint8(uint64(px&mask>>shift) * 255 / uint64(max))
+}
+
+func decodeBMPConfig(r io.Reader) (image.Config, error) {
+	data := make([]byte, 54)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return image.Config{}, err
+	}
+	h, err := readBMPHeader(data)
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{ColorModel: color.NRGBAModel, Width: h.width, Height: h.height}, nil
+}
+
+func decodeBMP(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	h, err := readBMPHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	masks, err := readBMPMasks(data, h)
+	if err != nil {
+		return nil, err
+	}
+
+	// Checked by dividing, the dimensions of a broken file can overflow
+	bytesPerPixel := int(h.bpp) / 8
+	if h.width > len(data)/bytesPerPixel || h.height > len(data)/bytesPerPixel/h.width {
+		return nil, errors.New("bmp: pixel data is truncated")
+	}
+	rowSize := (h.width*bytesPerPixel + 3) &^ 3 // rows are padded to 4 bytes
+	if uint64(h.pixelOffset)+uint64(rowSize)*uint64(h.height) > uint64(len(data)) {
+		return nil, errors.New("bmp: pixel data is truncated")
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, h.width, h.height))
+	transparent := true // Every alpha so far is zero
+	for y := 0; y < h.height; y++ {
+		// Rows are stored bottom-up unless the height was negative
+		srcY := h.height - 1 - y
+		if h.topDown {
+			srcY = y
+		}
+		row := data[int(h.pixelOffset)+srcY*rowSize:]
+		for x := 0; x < h.width; x++ {
+			i := img.PixOffset(x, y)
+			if bytesPerPixel == 3 {
+				px := row[x*3:]
+				img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = px[2], px[1], px[0], 255
+				continue
+			}
+			px := binary.LittleEndian.Uint32(row[x*4:])
+			alpha := uint8(255)
+			if masks.a != 0 {
+				alpha = bmpChannel(px, masks.a)
+				transparent = transparent && alpha == 0
+			}
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = bmpChannel(px, masks.r), bmpChannel(px, masks.g), bmpChannel(px, masks.b), alpha
+		}
+	}
+	// Plenty of writers declare an alpha mask and leave it at zero, such an
+	// image is meant to be opaque rather than invisible
+	if masks.a != 0 && transparent {
+		for i := 3; i < len(img.Pix); i += 4 {
+			img.Pix[i] = 255
+		}
+	}
+	return img, nil
+}
@@ -0,0 +1,85 @@
+package decode
+
+import (
+	"errors"
+	"image"
+	"time"
+)
+
+// Crop cuts rect out of every frame of anim, in pixels of its frames. rect
+// is clipped to the frames, an error is returned when nothing is left.
+func Crop(anim *Animation, rect image.Rectangle) (*Animation, error) {
+	rect = rect.Intersect(image.Rect(0, 0, anim.Width, anim.Height))
+	if rect.Empty() {
+		return nil, errors.New("the crop area is outside of the frames")
+	}
+	width, height := rect.Dx(), rect.Dy()
+	cropped := &Animation{Width: width, Height: height, Loops: anim.Loops}
+	cropped.Frames = func(emit func(frame *image.RGBA, delay time.Duration) bool) error {
+		out := image.NewRGBA(image.Rect(0, 0, width, height))
+		return anim.Frames(func(frame *image.RGBA, delay time.Duration) bool {
+			for y := 0; y < height; y++ {
+				src := frame.Pix[frame.PixOffset(rect.Min.X, rect.Min.Y+y):]
+				copy(out.Pix[y*out.Stride:(y+1)*out.Stride], src[:width*4])
+			}
+			return emit(out, delay)
+		})
+	}
+	return cropped, nil
+}
+
+// Channels this close to the border color still count as border, for the
+// noise lossy inputs have
+const trimTolerance = 8
+
+// Trim crops anim to the part of its frames that ever differs from the
+// border around them: fully transparent pixels, or the color of the top left
+// pixel of the first frame when that one is opaque. Every frame is decoded
+// once to find it. anim is returned as it is when there is no border, or
+// nothing but border.
+func Trim(anim *Animation) (*Animation, error) {
+	var border [4]uint8
+	first := true
+	box := image.Rectangle{}
+	err := anim.Frames(func(frame *image.RGBA, delay time.Duration) bool {
+		if first {
+			copy(border[:], frame.Pix[:4])
+			if border[3] != 255 {
+				border = [4]uint8{} // Transparent, whatever color it has
+			}
+			first = false
+		}
+		isBorder := func(p []uint8) bool {
+			if border[3] == 0 {
+				return p[3] == 0
+			}
+			for c := 0; c < 4; c++ {
+				if d := int(p[c]) - int(border[c]); d > trimTolerance || d < -trimTolerance {
+					return false
+				}
+			}
+			return true
+		}
+		for y := 0; y < anim.Height; y++ {
+			row := frame.Pix[y*frame.Stride:]
+			for x := 0; x < anim.Width; x++ {
+				// Only pixels outside of the box found so far can grow it
+				if x == box.Min.X && y >= box.Min.Y && y < box.Max.Y && box.Dx() > 0 {
+					x = box.Max.X - 1
+					continue
+				}
+				if !isBorder(row[x*4 : x*4+4]) {
+					box = box.Union(image.Rect(x, y, x+1, y+1))
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if box.Empty() || box == image.Rect(0, 0, anim.Width, anim.Height) {
+		return anim, nil
+	}
+	return Crop(anim, box)
+}
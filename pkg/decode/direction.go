@@ -0,0 +1,67 @@
+package decode
+
+import (
+	"image"
+	"time"
+)
+
+// Reverse plays anim backwards. All frames are composed and kept in memory
+// up front, the last frame can't be composed before the ones before it.
+func Reverse(anim *Animation) (*Animation, error) {
+	frames, delays, err := collectFrames(anim)
+	if err != nil {
+		return nil, err
+	}
+	reversed := &Animation{Width: anim.Width, Height: anim.Height, Loops: anim.Loops}
+	reversed.Frames = func(emit func(frame *image.RGBA, delay time.Duration) bool) error {
+		for i := len(frames) - 1; i >= 0; i-- {
+			if !emit(frames[i], delays[i]) {
+				break
+			}
+		}
+		return nil
+	}
+	return reversed, nil
+}
+
+// PingPong plays anim forwards and then backwards, as a single loop. The
+// first and last frame aren't repeated where it turns around, so the motion
+// doesn't stall there. Like Reverse it keeps all frames in memory.
+func PingPong(anim *Animation) (*Animation, error) {
+	frames, delays, err := collectFrames(anim)
+	if err != nil {
+		return nil, err
+	}
+	pingPong := &Animation{Width: anim.Width, Height: anim.Height, Loops: anim.Loops}
+	pingPong.Frames = func(emit func(frame *image.RGBA, delay time.Duration) bool) error {
+		for i := range frames {
+			if !emit(frames[i], delays[i]) {
+				return nil
+			}
+		}
+		for i := len(frames) - 2; i > 0; i-- {
+			if !emit(frames[i], delays[i]) {
+				break
+			}
+		}
+		return nil
+	}
+	return pingPong, nil
+}
+
+// collectFrames composes every frame of anim into a copy of its own
+func collectFrames(anim *Animation) ([]*image.RGBA, []time.Duration, error) {
+	var frames []*image.RGBA
+	var delays []time.Duration
+	err := anim.Frames(func(frame *image.RGBA, delay time.Duration) bool {
+		kept := image.NewRGBA(frame.Rect)
+		copy(kept.Pix, frame.Pix)
+		frames = append(frames, kept)
+		delays = append(delays, delay)
+		return true
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return frames, delays, nil
+}
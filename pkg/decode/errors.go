@@ -0,0 +1,14 @@
+package decode
+
+import "errors"
+
+// ErrUnsupportedFormat is wrapped by errors for inputs no decoder accepts
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+// Error marks an input that was recognized but could not be decoded
+type Error struct {
+	err error
+}
+
+func (e Error) Error() string { return e.err.Error() }
+func (e Error) Unwrap() error { return e.err }
@@ -0,0 +1,71 @@
+package decode
+
+import (
+	"image"
+	"time"
+)
+
+// Auto levels look at this many frames at most, enough for any GIF without
+// decoding a whole video twice
+const levelSampleFrames = 200
+
+// Share of the pixels allowed to fall outside the range auto levels picks,
+// so a few stray pixels don't decide it
+const levelClip = 0.01
+
+// Ranges narrower than this are left alone, stretching an almost flat image
+// only shows off its noise
+const levelMinRange = 16
+
+// LuminanceRange finds the luminance of the darkest and lightest opaque
+// pixels of the animation, ignoring the outer levelClip of them. The result
+// is remembered, the frames are only looked at once.
+func (a *Animation) LuminanceRange() (black, white float64, err error) {
+	a.levelsOnce.Do(func() {
+		var histogram [256]int
+		total := 0
+		frames := 0
+		a.levelsErr = a.Frames(func(frame *image.RGBA, delay time.Duration) bool {
+			// Every other pixel of every other row is plenty for a histogram
+			for y := 0; y < frame.Rect.Dy(); y += 2 {
+				row := frame.Pix[y*frame.Stride:]
+				for x := 0; x < frame.Rect.Dx(); x += 2 {
+					p := row[x*4 : x*4+4]
+					if p[3] == 0 {
+						continue
+					}
+					histogram[int(Luminance(p[0], p[1], p[2]))]++
+					total++
+				}
+			}
+			frames++
+			return frames < levelSampleFrames
+		})
+
+		a.black, a.white = 0, 255
+		if total == 0 {
+			return
+		}
+		clip := int(float64(total) * levelClip)
+		low, high := 0, 255
+		for seen := 0; low < 255; low++ {
+			if seen += histogram[low]; seen > clip {
+				break
+			}
+		}
+		for seen := 0; high > 0; high-- {
+			if seen += histogram[high]; seen > clip {
+				break
+			}
+		}
+		if high-low >= levelMinRange {
+			a.black, a.white = float64(low), float64(high)
+		}
+	})
+	return a.black, a.white, a.levelsErr
+}
+
+// Luminance is the perceived brightness of a color, 0-255
+func Luminance(r, g, b uint8) float64 {
+	return 0.2126*float64(r) + 0.7152*float64(g) + 0.0722*float64(b)
+}
@@ -0,0 +1,51 @@
+package decode
+
+import (
+	"errors"
+	"image"
+	"time"
+)
+
+// Mark is a point in an animation: the index of a frame, counting from 0, or
+// a time from its start
+type Mark struct {
+	Frame int
+	Time  time.Duration
+	Timed bool // Time is set instead of Frame
+}
+
+// Segment plays the part of anim from start up to, but not including, end.
+// A nil start or end leaves that side open. A timed start includes the frame
+// on screen at that moment. The frames before start are still composed, GIF
+// frames build on the ones before them, but not emitted.
+func Segment(anim *Animation, start, end *Mark) (*Animation, error) {
+	if start != nil && end != nil && start.Timed == end.Timed &&
+		(start.Frame >= end.Frame && !start.Timed || start.Time >= end.Time && start.Timed) {
+		return nil, errors.New("the segment ends before it starts")
+	}
+	segment := &Animation{Width: anim.Width, Height: anim.Height, Loops: anim.Loops}
+	segment.Frames = func(emit func(frame *image.RGBA, delay time.Duration) bool) error {
+		i, emitted := 0, 0
+		var at time.Duration
+		err := anim.Frames(func(frame *image.RGBA, delay time.Duration) bool {
+			index, from := i, at
+			i++
+			at += delay
+			switch {
+			case end != nil && !end.Timed && index >= end.Frame,
+				end != nil && end.Timed && from >= end.Time:
+				return false
+			case start != nil && !start.Timed && index < start.Frame,
+				start != nil && start.Timed && at <= start.Time:
+				return true
+			}
+			emitted++
+			return emit(frame, delay)
+		})
+		if err == nil && emitted == 0 {
+			err = errors.New("the segment has no frames, the animation is shorter")
+		}
+		return err
+	}
+	return segment, nil
+}
@@ -0,0 +1,94 @@
+package decode
+
+import (
+	"errors"
+	"image"
+	"time"
+)
+
+// SequenceItem is one animation of a Sequence with the number of times it
+// plays before the next one starts
+type SequenceItem struct {
+	Anim  *Animation
+	Loops int
+}
+
+// Sequence plays animations one after the other as a single animation, each
+// item as often as it says. Frames are fitted into the size of the largest
+// item and centered, the space around smaller ones stays transparent. The
+// sequence itself plays forever.
+func Sequence(items []SequenceItem) (*Animation, error) {
+	if len(items) == 0 {
+		return nil, errors.New("no animations to play")
+	}
+	width, height := 0, 0
+	for _, item := range items {
+		if item.Anim.Width > width {
+			width = item.Anim.Width
+		}
+		if item.Anim.Height > height {
+			height = item.Anim.Height
+		}
+	}
+
+	anim := &Animation{Width: width, Height: height}
+	anim.Frames = func(emit func(frame *image.RGBA, delay time.Duration) bool) error {
+		canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+		stopped := false
+		var starts []time.Duration
+		var elapsed time.Duration
+		for _, item := range items {
+			starts = append(starts, elapsed)
+			loops := item.Loops
+			if loops < 1 {
+				loops = 1
+			}
+			for i := 0; i < loops && !stopped; i++ {
+				err := item.Anim.Frames(func(frame *image.RGBA, delay time.Duration) bool {
+					fitFrame(canvas, frame)
+					elapsed += delay
+					stopped = !emit(canvas, delay)
+					return !stopped
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if !stopped {
+			anim.Starts = starts
+		}
+		return nil
+	}
+	return anim, nil
+}
+
+// fitFrame draws src as large as it fits in dst without changing its
+// aspect ratio, centered and with nearest neighbour scaling. The rest of dst
+// is cleared.
+func fitFrame(dst, src *image.RGBA) {
+	for i := range dst.Pix {
+		dst.Pix[i] = 0
+	}
+	dw, dh := dst.Rect.Dx(), dst.Rect.Dy()
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	if sw == 0 || sh == 0 {
+		return
+	}
+	w, h := dw, sh*dw/sw
+	if h > dh {
+		w, h = sw*dh/sh, dh
+	}
+	x0, y0 := (dw-w)/2, (dh-h)/2
+	for y := 0; y < h; y++ {
+		srcRow := src.Pix[(y*sh/h)*src.Stride:]
+		dstRow := dst.Pix[(y0+y)*dst.Stride+x0*4:]
+		if w == sw {
+			copy(dstRow[:w*4], srcRow[:w*4])
+			continue
+		}
+		for x := 0; x < w; x++ {
+			copy(dstRow[x*4:x*4+4], srcRow[(x*sw/w)*4:])
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package decode
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Videos are scaled down by ffmpeg before they reach us, anything wider
+// than this is wasted bandwidth on the pipe.
+const videoMaxWidth = 640
+
+// VideoOptions control how frames are sampled from video files
+type VideoOptions struct {
+	FPS       int // frames sampled per second of video
+	MaxFrames int // stop after this many frames, 0 = whole video
+}
+
+// openVideo probes a video with ffprobe, the frames are streamed from an
+// ffmpeg process as raw RGBA once the pipeline asks for them.
+func openVideo(path string, opts VideoOptions) (*Animation, error) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return nil, fmt.Errorf("%s: %w, ffmpeg is not installed to try it as a video", path, ErrUnsupportedFormat)
+	}
+	if opts.FPS <= 0 {
+		return nil, errors.New("video sampling rate has to be positive")
+	}
+
+	srcWidth, srcHeight, err := probeVideoSize(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the aspect ratio, ffmpeg wants even dimensions
+	width, height := srcWidth, srcHeight
+	if width > videoMaxWidth {
+		width = videoMaxWidth
+		height = srcHeight * videoMaxWidth / srcWidth
+	}
+	width, height = width&^1, height&^1
+	if width == 0 || height == 0 {
+		return nil, fmt.Errorf("video is too small: %dx%d", srcWidth, srcHeight)
+	}
+
+	anim := &Animation{Width: width, Height: height}
+	anim.Frames = func(emit func(frame *image.RGBA, delay time.Duration) bool) error {
+		args := []string{"-v", "error", "-i", path,
+			"-vf", fmt.Sprintf("fps=%d,scale=%d:%d", opts.FPS, width, height)}
+		if opts.MaxFrames > 0 {
+			args = append(args, "-frames:v", strconv.Itoa(opts.MaxFrames))
+		}
+		args = append(args, "-f", "rawvideo", "-pix_fmt", "rgba", "-")
+
+		cmd := exec.Command("ffmpeg", args...)
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return err
+		}
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+
+		frame := image.NewRGBA(image.Rect(0, 0, width, height))
+		delay := time.Second / time.Duration(opts.FPS)
+		r := bufio.NewReaderSize(stdout, len(frame.Pix))
+		for {
+			if _, err := io.ReadFull(r, frame.Pix); err != nil {
+				if err == io.EOF {
+					break
+				}
+				cmd.Process.Kill()
+				cmd.Wait()
+				return Error{fmt.Errorf("reading video frames: %w", err)}
+			}
+			if !emit(frame, delay) {
+				cmd.Process.Kill()
+				cmd.Wait()
+				return nil
+			}
+		}
+		if err := cmd.Wait(); err != nil {
+			return Error{fmt.Errorf("decoding %s: %w", path, err)}
+		}
+		return nil
+	}
+	return anim, nil
+}
+
+// probeVideoSize asks ffprobe for the dimensions of the first video stream
+func probeVideoSize(path string) (int, int, error) {
+	out, err := exec.Command("ffprobe", "-v", "error", "-select_streams", "v:0",
+		"-show_entries", "stream=width,height", "-of", "csv=p=0:s=x", path).Output()
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w, ffprobe could not read it: %v", path, ErrUnsupportedFormat, err)
+	}
+
+	var width, height int
+	if _, err := fmt.Sscanf(strings.TrimSpace(string(out)), "%dx%d", &width, &height); err != nil {
+		return 0, 0, fmt.Errorf("%s: %w, no video stream found", path, ErrUnsupportedFormat)
+	}
+	return width, height, nil
+}
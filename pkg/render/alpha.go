@@ -0,0 +1,83 @@
+package render
+
+import (
+	"image"
+	"image/color"
+)
+
+// flattenAlpha makes every partly transparent pixel of img opaque, blended
+// against the background when there is one. The renderers only know drawn
+// and not drawn pixels. img is left alone, a copy is returned when anything
+// had to change.
+func flattenAlpha(img *image.RGBA, background color.RGBA, blend bool) *image.RGBA {
+	out := img
+	for i := 0; i < len(img.Pix); i += 4 {
+		a := uint32(img.Pix[i+3])
+		if a == 0 || a == 255 {
+			continue
+		}
+		if out == img {
+			out = &image.RGBA{Pix: append([]byte(nil), img.Pix...), Stride: img.Stride, Rect: img.Rect}
+		}
+
+		// The colors are premultiplied by alpha
+		p := out.Pix[i : i+4]
+		if blend {
+			rest := 255 - a
+			p[0] = uint8(uint32(p[0]) + uint32(background.R)*rest/255)
+			p[1] = uint8(uint32(p[1]) + uint32(background.G)*rest/255)
+			p[2] = uint8(uint32(p[2]) + uint32(background.B)*rest/255)
+		} else {
+			p[0] = uint8(uint32(p[0]) * 255 / a)
+			p[1] = uint8(uint32(p[1]) * 255 / a)
+			p[2] = uint8(uint32(p[2]) * 255 / a)
+		}
+		p[3] = 255
+	}
+	return out
+}
+
+// Colors of the checkerboard -matte checker puts behind the art
+var CheckerMatte = []color.RGBA{{0x66, 0x66, 0x66, 0xff}, {0x99, 0x99, 0x99, 0xff}}
+
+// matteFrame prepares the transparency of a frame before it's scaled: pixels
+// less opaque than threshold become fully transparent, then with a matte
+// everything transparent is drawn on top of it. A matte of one color is
+// solid, one of two colors a checkerboard of 16 squares across. img is left
+// alone, a copy is returned when anything had to change.
+func matteFrame(img *image.RGBA, threshold uint8, matte []color.RGBA) *image.RGBA {
+	if threshold <= 1 && len(matte) == 0 {
+		return img
+	}
+	out := &image.RGBA{Pix: append([]byte(nil), img.Pix...), Stride: img.Stride, Rect: img.Rect}
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	square := width
+	if height > square {
+		square = height
+	}
+	square = (square + 15) / 16
+
+	for y := 0; y < height; y++ {
+		row := out.Pix[y*out.Stride:]
+		for x := 0; x < width; x++ {
+			p := row[x*4 : x*4+4]
+			if p[3] < threshold {
+				p[0], p[1], p[2], p[3] = 0, 0, 0, 0
+			}
+			if len(matte) == 0 || p[3] == 255 {
+				continue
+			}
+			m := matte[0]
+			if len(matte) > 1 && (x/square+y/square)%2 == 1 {
+				m = matte[1]
+			}
+			// The colors are premultiplied by alpha
+			rest := 255 - uint32(p[3])
+			p[0] = uint8(uint32(p[0]) + uint32(m.R)*rest/255)
+			p[1] = uint8(uint32(p[1]) + uint32(m.G)*rest/255)
+			p[2] = uint8(uint32(p[2]) + uint32(m.B)*rest/255)
+			p[3] = 255
+		}
+	}
+	return out
+}
@@ -0,0 +1,48 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/term"
+)
+
+// Charset used by the ascii renderer when none is given
+const DefaultCharset = "circles"
+
+// Named character ramps for -charset, from lightest to densest
+var charsetPresets = map[string]string{
+	"circles": " .◌*●⦾⦿⬤",
+	"classic": " .:-=+*#%@",
+	"blocks":  " ▁▂▃▄▅▆▇█",
+	"dots":    " ⠁⠃⠇⡇⣇⣧⣷⣿",
+	"shade":   " ░▒▓█",
+}
+
+// MonoCharset picks the default ramp for art without colors: ink-like
+// characters on a light background and solid shades on a dark one
+func MonoCharset(colors term.Colors) string {
+	if colors.Dark() {
+		return "shade"
+	}
+	return "classic"
+}
+
+// ResolveCharset turns a -charset value into the characters of the ramp.
+// Preset names are looked up, anything else is used as the ramp itself.
+func ResolveCharset(value string) ([]string, error) {
+	if preset, ok := charsetPresets[value]; ok {
+		value = preset
+	}
+	ramp := strings.Split(value, "")
+	if len(ramp) < 2 {
+		names := make([]string, 0, len(charsetPresets))
+		for name := range charsetPresets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("charset %q needs at least 2 characters, or one of the presets: %s", value, strings.Join(names, ", "))
+	}
+	return ramp, nil
+}
@@ -0,0 +1,266 @@
+package render
+
+import (
+	"bytes"
+	"image/color"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Color modes for -color-mode
+const (
+	ColorTrue = "truecolor"
+	Color256  = "256"
+	Color16   = "16"
+	ColorNone = "none"
+)
+
+// Levels of the 6x6x6 color cube in the 256 color palette
+var cube256Levels = [6]int{0, 95, 135, 175, 215, 255}
+
+// The 16 basic colors as xterm draws them, in SGR order: 30-37 then 90-97
+var basic16 = [16][3]int{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// DetectColorMode guesses what the terminal can show from NO_COLOR (see
+// https://no-color.org), COLORTERM and TERM. Terminals we know nothing about
+// get 24-bit color, like before. reason explains the choice for -debug-term.
+func DetectColorMode() (mode, reason string) {
+	if os.Getenv("NO_COLOR") != "" {
+		return ColorNone, "NO_COLOR is set"
+	}
+
+	switch colorterm := os.Getenv("COLORTERM"); colorterm {
+	case "truecolor", "24bit":
+		return ColorTrue, "COLORTERM=" + colorterm
+	}
+
+	term := os.Getenv("TERM")
+	switch {
+	case term == "dumb":
+		return ColorNone, "TERM=dumb"
+	case strings.Contains(term, "256color"), term == "xterm":
+		return Color256, "TERM=" + term + " without COLORTERM=truecolor"
+	case term == "linux", term == "ansi", term == "cons25",
+		strings.HasPrefix(term, "vt"), strings.HasSuffix(term, "-16color"), strings.HasSuffix(term, "-color"):
+		return Color16, "TERM=" + term
+	}
+	return ColorTrue, "unknown terminal, assuming 24-bit color"
+}
+
+// colorOutput is how the renderers write colors: in the color mode of the
+// terminal, snapped to the closest color of palette first when it has any
+type colorOutput struct {
+	mode    string
+	palette []color.RGBA
+}
+
+// none tells whether no colors are written at all
+func (c colorOutput) none() bool {
+	return c.mode == ColorNone
+}
+
+// appendFg appends the SGR parameters that set the foreground to r,g,b,
+// e.g. "38;2;255;0;0", "38;5;196" or "91"
+func (c colorOutput) appendFg(buf []byte, r, g, b uint8) []byte {
+	r, g, b = c.snap(r, g, b)
+	return appendSGRColor(buf, c.mode, false, r, g, b)
+}
+
+// appendBg is appendFg for the background
+func (c colorOutput) appendBg(buf []byte, r, g, b uint8) []byte {
+	r, g, b = c.snap(r, g, b)
+	return appendSGRColor(buf, c.mode, true, r, g, b)
+}
+
+// snap returns the color of the palette closest to r,g,b, or r,g,b itself
+// without a palette
+func (c colorOutput) snap(r, g, b uint8) (uint8, uint8, uint8) {
+	if len(c.palette) == 0 {
+		return r, g, b
+	}
+	p := c.palette[nearestColor(c.palette, r, g, b)]
+	return p.R, p.G, p.B
+}
+
+// SGRColor returns the SGR parameters that set the foreground to c, or the
+// background with background set, in the given color mode, e.g.
+// "38;2;255;0;0". They are empty for ColorNone.
+func SGRColor(mode string, background bool, c color.RGBA) string {
+	if mode == ColorNone {
+		return ""
+	}
+	return string(appendSGRColor(nil, mode, background, c.R, c.G, c.B))
+}
+
+func appendSGRColor(buf []byte, mode string, background bool, r, g, b uint8) []byte {
+	switch mode {
+	case Color256:
+		if background {
+			buf = append(buf, "48;5;"...)
+		} else {
+			buf = append(buf, "38;5;"...)
+		}
+		return strconv.AppendUint(buf, uint64(Palette256(r, g, b)), 10)
+	case Color16:
+		i := palette16(r, g, b)
+		code := 30 + i
+		if i >= 8 {
+			code = 90 + i - 8
+		}
+		if background {
+			code += 10
+		}
+		return strconv.AppendUint(buf, uint64(code), 10)
+	default:
+		if background {
+			buf = append(buf, "48;2;"...)
+		} else {
+			buf = append(buf, "38;2;"...)
+		}
+		buf = strconv.AppendUint(buf, uint64(r), 10)
+		buf = append(buf, ';')
+		buf = strconv.AppendUint(buf, uint64(g), 10)
+		buf = append(buf, ';')
+		return strconv.AppendUint(buf, uint64(b), 10)
+	}
+}
+
+// artLine builds one line of rendered art. The caller puts the SGR
+// parameters of every cell in style before adding it. An escape sequence is
+// only written where the style changes, so every style of a line has to set
+// the same attributes (e.g. always foreground and background) for one to
+// fully replace the other.
+type artLine struct {
+	buf    []byte
+	style  []byte // SGR parameters for the next cell, empty for none
+	active []byte // style of the last cell written
+	fresh  bool   // nothing written yet, the style left by the line before is unknown
+}
+
+// reset starts a new line, reusing the buffers
+func (l *artLine) reset() {
+	l.buf = l.buf[:0]
+	l.style = l.style[:0]
+	l.active = l.active[:0]
+	l.fresh = true
+}
+
+// cell adds a character drawn in the current style
+func (l *artLine) cell(char string) {
+	l.applyStyle()
+	l.buf = append(l.buf, char...)
+}
+
+// cellRune is cell for a single rune
+func (l *artLine) cellRune(r rune) {
+	l.applyStyle()
+	l.buf = utf8.AppendRune(l.buf, r)
+}
+
+// applyStyle switches to style, unless the line already has it. Only going
+// back to no style at all or starting a line needs a reset.
+func (l *artLine) applyStyle() {
+	if !l.fresh && bytes.Equal(l.style, l.active) {
+		return
+	}
+	switch {
+	case len(l.style) == 0:
+		l.buf = append(l.buf, "\x1b[0m"...)
+	case l.fresh:
+		l.buf = append(l.buf, "\x1b[0;"...)
+	default:
+		l.buf = append(l.buf, "\x1b["...)
+	}
+	if len(l.style) > 0 {
+		l.buf = append(l.buf, l.style...)
+		l.buf = append(l.buf, 'm')
+	}
+	l.active = append(l.active[:0], l.style...)
+	l.fresh = false
+}
+
+// String returns the line, ending with the colors reset
+func (l *artLine) String() string {
+	if len(l.active) > 0 {
+		l.buf = append(l.buf, "\x1b[0m"...)
+		l.active = l.active[:0]
+	}
+	return string(l.buf)
+}
+
+// Palette256 picks the closest color of the 6x6x6 cube (16-231) or the gray
+// ramp (232-255)
+func Palette256(r, g, b uint8) int {
+	ri, gi, bi := cube256Index(r), cube256Index(g), cube256Index(b)
+	cube := 16 + 36*ri + 6*gi + bi
+	cubeDist := colorDistance(int(r), int(g), int(b), cube256Levels[ri], cube256Levels[gi], cube256Levels[bi])
+
+	// Gray ramp runs from 8 to 238 in steps of 10
+	avg := (int(r) + int(g) + int(b)) / 3
+	grayIndex := (avg - 3) / 10
+	if grayIndex < 0 {
+		grayIndex = 0
+	} else if grayIndex > 23 {
+		grayIndex = 23
+	}
+	level := 8 + 10*grayIndex
+	if colorDistance(int(r), int(g), int(b), level, level, level) < cubeDist {
+		return 232 + grayIndex
+	}
+	return cube
+}
+
+// PaletteColor returns color n of the 256 color palette, the inverse of
+// Palette256 for the colors it picks
+func PaletteColor(n int) color.RGBA {
+	switch {
+	case n < 16:
+		c := basic16[n]
+		return color.RGBA{uint8(c[0]), uint8(c[1]), uint8(c[2]), 255}
+	case n < 232:
+		n -= 16
+		return color.RGBA{uint8(cube256Levels[n/36]), uint8(cube256Levels[n/6%6]), uint8(cube256Levels[n%6]), 255}
+	}
+	level := uint8(8 + 10*(n-232))
+	return color.RGBA{level, level, level, 255}
+}
+
+// cube256Index returns the closest cube level for one channel
+func cube256Index(v uint8) int {
+	best := 0
+	for i, level := range cube256Levels {
+		if abs(int(v)-level) < abs(int(v)-cube256Levels[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+// palette16 picks the closest of the 16 basic colors
+func palette16(r, g, b uint8) int {
+	best, bestDist := 0, -1
+	for i, c := range basic16 {
+		if d := colorDistance(int(r), int(g), int(b), c[0], c[1], c[2]); bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+func colorDistance(r1, g1, b1, r2, g2, b2 int) int {
+	return (r1-r2)*(r1-r2) + (g1-g2)*(g1-g2) + (b1-b2)*(b1-b2)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,225 @@
+package render
+
+import (
+	"strings"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/term"
+)
+
+// Place the sysinfo next to the art lines of a frame
+func Compose(art []string, cfg Config, sysInfo []string) []string {
+	sysInfo = FitInfo(sysInfo, cfg)
+	if cfg.Layout == LayoutTop {
+		return padFrame(composeFrameTop(art, cfg, sysInfo), cfg)
+	}
+
+	// Where the sysinfo starts, -offset moves it further down
+	offset := cfg.Offset
+	switch cfg.InfoAlign {
+	case AlignCenter:
+		offset += (len(art) - len(sysInfo)) / 2
+	case AlignBottom:
+		offset += len(art) - len(sysInfo)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	// totalHeight ensures we can print all sysinfo lines
+	totalHeight := len(art)
+	if len(sysInfo)+offset > totalHeight {
+		totalHeight = len(sysInfo) + offset
+	}
+	infoIndent := strings.Repeat(" ", cfg.InfoOffsetX)
+
+	// With the art on the right every sysinfo line is padded to the widest
+	infoWidth := 0
+	if cfg.Layout == LayoutRight {
+		infoWidth = term.MaxVisibleWidth(sysInfo)
+	}
+
+	lines := make([]string, totalHeight)
+	var lineBuilder strings.Builder
+
+	for y := 0; y < totalHeight; y++ {
+		lineBuilder.Reset()
+
+		artLine := strings.Repeat(" ", cfg.Width) // Pad with spaces if GIF is shorter than totalHeight
+		if y < len(art) {
+			artLine = art[y]
+		}
+
+		// Append sysinfo line if exists and within offset
+		infoLine := ""
+		sysIndex := y - offset
+		if sysIndex >= 0 && sysIndex < len(sysInfo) {
+			infoLine = sysInfo[sysIndex]
+		}
+
+		if cfg.Layout == LayoutRight {
+			lineBuilder.WriteString(infoIndent)
+			lineBuilder.WriteString(infoLine)
+			lineBuilder.WriteString("\x1b[0m")
+			lineBuilder.WriteString(strings.Repeat(" ", infoWidth-term.VisibleWidth(infoLine)+cfg.Gap))
+			lineBuilder.WriteString(artLine)
+		} else {
+			lineBuilder.WriteString(artLine)
+			if infoLine != "" {
+				lineBuilder.WriteString(strings.Repeat(" ", cfg.Gap))
+				lineBuilder.WriteString(infoIndent)
+				lineBuilder.WriteString(infoLine)
+			}
+		}
+
+		lines[y] = lineBuilder.String()
+	}
+
+	return padFrame(lines, cfg)
+}
+
+// composeFrameTop centers the art above the sysinfo, with cfg.Offset empty
+// lines in between. The sysinfo lines are the same for every frame, so only
+// the art changes on screen.
+func composeFrameTop(art []string, cfg Config, sysInfo []string) []string {
+	indent := ""
+	if infoWidth := term.MaxVisibleWidth(sysInfo); infoWidth > cfg.Width {
+		indent = strings.Repeat(" ", (infoWidth-cfg.Width)/2)
+	}
+
+	lines := make([]string, 0, len(art)+cfg.Offset+len(sysInfo))
+	for _, line := range art {
+		lines = append(lines, indent+line)
+	}
+	for i := 0; i < cfg.Offset; i++ {
+		lines = append(lines, "")
+	}
+	infoIndent := strings.Repeat(" ", cfg.InfoOffsetX)
+	for _, line := range sysInfo {
+		lines = append(lines, infoIndent+line)
+	}
+	return lines
+}
+
+// padFrame adds the -padding-* empty space around a composed frame. Right
+// padding only matters when sizing the art, trailing spaces aren't visible.
+func padFrame(lines []string, cfg Config) []string {
+	if cfg.PaddingTop == 0 && cfg.PaddingLeft == 0 && cfg.PaddingBottom == 0 {
+		return lines
+	}
+
+	padded := make([]string, 0, cfg.PaddingTop+len(lines)+cfg.PaddingBottom)
+	for i := 0; i < cfg.PaddingTop; i++ {
+		padded = append(padded, "")
+	}
+	indent := strings.Repeat(" ", cfg.PaddingLeft)
+	for _, line := range lines {
+		padded = append(padded, indent+line)
+	}
+	for i := 0; i < cfg.PaddingBottom; i++ {
+		padded = append(padded, "")
+	}
+	return padded
+}
+
+// ComposeAll places the sysinfo next to every frame
+func ComposeAll(artFrames [][]string, cfg Config, sysInfo []string) [][]string {
+	sysInfo = FitInfo(sysInfo, cfg)
+	cfg.InfoWidth = 0 // Fitted once for every frame
+	frames := make([][]string, len(artFrames))
+	for i, art := range artFrames {
+		frames[i] = Compose(art, cfg, sysInfo)
+	}
+	return frames
+}
+
+// ComposePacked is ComposeAll for packed frames. Only one frame at a time
+// is unpacked, so the composed frames never take all of the memory they
+// would unpacked.
+func ComposePacked(artFrames *term.PackedFrames, cfg Config, sysInfo []string) *term.PackedFrames {
+	sysInfo = FitInfo(sysInfo, cfg)
+	cfg.InfoWidth = 0
+	frames := &term.PackedFrames{}
+	for i := 0; i < artFrames.Len(); i++ {
+		frames.Append(Compose(artFrames.Frame(i), cfg, sysInfo))
+	}
+	return frames
+}
+
+// FitInfo makes the sysinfo lines fit in cfg.InfoWidth columns, by cutting
+// them off or wrapping them as cfg.InfoOverflow says. Wrapped "Key: value"
+// lines continue below the value.
+func FitInfo(sysInfo []string, cfg Config) []string {
+	if cfg.InfoWidth <= 0 || cfg.InfoOverflow == OverflowNone || term.MaxVisibleWidth(sysInfo) <= cfg.InfoWidth {
+		return sysInfo
+	}
+	fitted := make([]string, 0, len(sysInfo))
+	for _, line := range sysInfo {
+		switch {
+		case term.VisibleWidth(line) <= cfg.InfoWidth:
+			fitted = append(fitted, line)
+		case cfg.InfoOverflow == OverflowWrap:
+			indent := ""
+			if i := strings.Index(term.StripEscapes(line), ": "); i >= 0 && i < cfg.InfoWidth/2 {
+				indent = strings.Repeat(" ", term.VisibleWidth(term.StripEscapes(line)[:i+2]))
+			}
+			fitted = append(fitted, term.WrapWidth(line, cfg.InfoWidth, indent)...)
+		default:
+			fitted = append(fitted, term.TruncateWidth(line, cfg.InfoWidth-1)+"…")
+		}
+	}
+	return fitted
+}
+
+// InfoColumns is how many columns the sysinfo has in a terminal cols wide,
+// next to or below the art, for cfg.InfoWidth
+func InfoColumns(cols int, cfg Config) int {
+	cols -= cfg.PaddingLeft + cfg.PaddingRight + cfg.InfoOffsetX
+	if cfg.Layout != LayoutTop {
+		cols -= cfg.Width + cfg.Gap
+	}
+	if cols < 1 {
+		cols = 1
+	}
+	return cols
+}
+
+// Fit picks the largest art size that fits next to (or with -layout=top
+// above) the sysinfo in a terminal of cols x rows cells. ratio is height /
+// width of the art, the returned height is in cell widths like -height.
+func Fit(cols, rows int, sysInfo []string, ratio float64, cfg Config) (int, int) {
+	infoWidth := term.MaxVisibleWidth(sysInfo)
+	cols -= cfg.PaddingLeft + cfg.PaddingRight
+	rows -= cfg.PaddingTop + cfg.PaddingBottom
+
+	width := cols
+	if cfg.Layout == LayoutTop {
+		rows -= len(sysInfo) + cfg.Offset
+	} else if infoWidth > 0 {
+		width = cols - infoWidth - cfg.Gap - cfg.InfoOffsetX
+	}
+	height := int(float64(width) * ratio)
+
+	// Too tall, let the terminal height decide instead
+	if Rows(height, cfg.CellAspect) > rows {
+		height = int(float64(rows) / cfg.CellAspect)
+		width = int(float64(height) / ratio)
+	}
+
+	if width < 1 {
+		width = 1
+	}
+	if minHeight := int(1 / cfg.CellAspect); height < minHeight {
+		height = minHeight
+	}
+	return width, height
+}
+
+// Rows is how many terminal rows art of the given -height takes. Height
+// is measured in cell widths, so square pixels stay square on screen.
+func Rows(height int, cellAspect float64) int {
+	rows := int(float64(height) * cellAspect)
+	if rows < 1 {
+		rows = 1
+	}
+	return rows
+}
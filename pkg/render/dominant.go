@@ -0,0 +1,81 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+// At most this many pixels of a frame are looked at for its dominant colors,
+// evenly spread over it
+const dominantSamples = 1 << 14
+
+// DominantColors finds up to n colors that together stand for the opaque
+// pixels of img, with median cut: the box of colors with the widest range in
+// any channel is split in half at its median until there are n of them.
+// They are sorted by how many pixels they cover, most first.
+func DominantColors(img *image.RGBA, n int) []color.RGBA {
+	bounds := img.Bounds()
+	step := 1
+	for bounds.Dx()*bounds.Dy()/(step*step) > dominantSamples {
+		step++
+	}
+	var pixels [][3]uint8
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			p := img.Pix[img.PixOffset(x, y):]
+			if p[3] == 0 {
+				continue
+			}
+			pixels = append(pixels, [3]uint8{p[0], p[1], p[2]})
+		}
+	}
+	if len(pixels) == 0 || n < 1 {
+		return nil
+	}
+
+	boxes := [][][3]uint8{pixels}
+	for len(boxes) < n {
+		// The box with the widest channel, boxes of a single color can't
+		// be split any further
+		widest, channel, widestRange := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			for c := 0; c < 3; c++ {
+				lo, hi := box[0][c], box[0][c]
+				for _, p := range box {
+					if p[c] < lo {
+						lo = p[c]
+					} else if p[c] > hi {
+						hi = p[c]
+					}
+				}
+				if r := int(hi) - int(lo); r > widestRange {
+					widest, channel, widestRange = i, c, r
+				}
+			}
+		}
+		if widest < 0 {
+			break
+		}
+		box := boxes[widest]
+		sort.Slice(box, func(i, j int) bool { return box[i][channel] < box[j][channel] })
+		boxes[widest] = box[:len(box)/2]
+		boxes = append(boxes, box[len(box)/2:])
+	}
+
+	sort.SliceStable(boxes, func(i, j int) bool { return len(boxes[i]) > len(boxes[j]) })
+	colors := make([]color.RGBA, len(boxes))
+	for i, box := range boxes {
+		var sum [3]int
+		for _, p := range box {
+			sum[0] += int(p[0])
+			sum[1] += int(p[1])
+			sum[2] += int(p[2])
+		}
+		colors[i] = color.RGBA{uint8(sum[0] / len(box)), uint8(sum[1] / len(box)), uint8(sum[2] / len(box)), 255}
+	}
+	return colors
+}
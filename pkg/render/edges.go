@@ -0,0 +1,145 @@
+package render
+
+import (
+	"image"
+	"math"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/decode"
+)
+
+// Gradient strength from where the edges renderer draws lines when
+// -edge-threshold isn't given
+const DefaultEdgeThreshold = 64
+
+// Convert a frame to lines of ASCII that trace its edges. A Sobel filter
+// finds how strongly and in which direction the brightness changes at every
+// sampled pixel, where it changes at least edgeThreshold (0-255) the pixel
+// becomes the line along the edge: - _ | / or \. Only the strongest pixel
+// across an edge is drawn, the others are filled from the ramp like the
+// ascii renderer does. The outline of the opaque part counts as an edge
+// too, however dark it is.
+func renderEdges(img *image.RGBA, width, rows int, colors colorOutput, ramp []string, tone toneCurve, edgeThreshold float64) []string {
+	pix := img.Pix
+	stride := img.Stride
+	scaleX := float64(img.Bounds().Dx()) / float64(width)
+	scaleY := float64(img.Bounds().Dy()) / float64(rows)
+
+	// Luminance and opacity of every sampled pixel, transparent ones count
+	// as black
+	lum := make([]float64, width*rows)
+	opacity := make([]float64, width*rows)
+	offsets := make([]int, width*rows)
+	for y := 0; y < rows; y++ {
+		for x := 0; x < width; x++ {
+			px := int(float64(x) * scaleX)
+			py := int(float64(y) * scaleY)
+			o := py*stride + px*4
+			offsets[y*width+x] = o
+			if pix[o+3] != 0 {
+				lum[y*width+x] = decode.Luminance(pix[o], pix[o+1], pix[o+2])
+				opacity[y*width+x] = 255
+			}
+		}
+	}
+	at := func(values []float64, x, y int) float64 {
+		// Clamp to the edge, the border doesn't count as an edge itself
+		if x < 0 {
+			x = 0
+		} else if x >= width {
+			x = width - 1
+		}
+		if y < 0 {
+			y = 0
+		} else if y >= rows {
+			y = rows - 1
+		}
+		return values[y*width+x]
+	}
+	sobel := func(values []float64, x, y int) (gx, gy float64) {
+		gx = at(values, x+1, y-1) + 2*at(values, x+1, y) + at(values, x+1, y+1) -
+			at(values, x-1, y-1) - 2*at(values, x-1, y) - at(values, x-1, y+1)
+		gy = at(values, x-1, y+1) + 2*at(values, x, y+1) + at(values, x+1, y+1) -
+			at(values, x-1, y-1) - 2*at(values, x, y-1) - at(values, x+1, y-1)
+		return gx, gy
+	}
+
+	// Sobel gradients, scaled so a step from black to white is 255. The
+	// opacity is used where it changes more than the luminance.
+	gradX := make([]float64, width*rows)
+	gradY := make([]float64, width*rows)
+	magnitude := make([]float64, width*rows)
+	for y := 0; y < rows; y++ {
+		for x := 0; x < width; x++ {
+			gx, gy := sobel(lum, x, y)
+			if ax, ay := sobel(opacity, x, y); math.Hypot(ax, ay) > math.Hypot(gx, gy) {
+				gx, gy = ax, ay
+			}
+			i := y*width + x
+			gradX[i], gradY[i] = gx/4, gy/4
+			magnitude[i] = math.Hypot(gx, gy) / 4
+		}
+	}
+	magAt := func(x, y int) float64 {
+		if x < 0 || x >= width || y < 0 || y >= rows {
+			return 0
+		}
+		return magnitude[y*width+x]
+	}
+
+	lines := make([]string, rows)
+	var line artLine
+	for y := 0; y < rows; y++ {
+		line.reset()
+		for x := 0; x < width; x++ {
+			i := y*width + x
+			o := offsets[i]
+			r8, g8, b8, a8 := pix[o], pix[o+1], pix[o+2], pix[o+3]
+
+			line.style = line.style[:0]
+			if a8 == 0 {
+				line.cell(" ")
+				continue
+			}
+			if !colors.none() {
+				line.style = colors.appendFg(line.style, r8, g8, b8)
+			}
+
+			// The direction of the gradient, folded into 4 bins of 45°.
+			// The edge runs across it.
+			char := ""
+			if mag := magnitude[i]; mag >= edgeThreshold && mag > 0 {
+				angle := math.Atan2(gradY[i], gradX[i])
+				if angle < 0 {
+					angle += math.Pi
+				}
+				var dx, dy int // Neighbour across the edge
+				switch bin := int(angle/(math.Pi/4)+0.5) % 4; bin {
+				case 0:
+					char, dx, dy = "|", 1, 0
+				case 1:
+					char, dx, dy = "/", 1, 1
+				case 2:
+					// An edge closer to the row below than the row above
+					// sits at the bottom of the character
+					char, dx, dy = "-", 0, 1
+					if math.Abs(at(lum, x, y+1)-lum[i]) > math.Abs(lum[i]-at(lum, x, y-1)) {
+						char = "_"
+					}
+				case 3:
+					char, dx, dy = "\\", -1, 1
+				}
+				// Thin the edge to the strongest pixel across it
+				if mag < magAt(x+dx, y+dy) || mag < magAt(x-dx, y-dy) {
+					char = ""
+				}
+			}
+			if char == "" {
+				char = pixelToASCII(r8, g8, b8, ramp, tone)
+			}
+			line.cell(char)
+		}
+		lines[y] = line.String()
+	}
+
+	return lines
+}
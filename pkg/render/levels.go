@@ -0,0 +1,68 @@
+package render
+
+import (
+	"math"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/decode"
+)
+
+// toneCurve turns the luminance of a pixel into how light it is drawn, for
+// picking characters from the ramp
+type toneCurve struct {
+	black, white float64 // luminance drawn as the darkest and lightest
+	brightness   float64
+	contrast     float64
+	gamma        float64
+	invert       bool
+	multiplier   float64
+}
+
+// newToneCurve builds the curve for cfg, black and white are only known
+// while rendering when auto levels are on
+func newToneCurve(cfg Config) toneCurve {
+	t := toneCurve{
+		black:      0,
+		white:      255,
+		brightness: cfg.Brightness,
+		contrast:   cfg.Contrast,
+		gamma:      cfg.Gamma,
+		invert:     cfg.InvertRamp,
+		multiplier: cfg.Multiplier,
+	}
+	if cfg.white > cfg.black {
+		t.black, t.white = cfg.black, cfg.white
+	}
+	return t
+}
+
+// level maps luminance (0-255) to 0-1, before -multiplier scales it
+func (t toneCurve) level(lum float64) float64 {
+	level := (lum - t.black) / (t.white - t.black + 1)
+	level = (level-0.5)*t.contrast + 0.5 + t.brightness
+	switch {
+	case level <= 0:
+		level = 0
+	case level >= 1:
+		level = 1
+	case t.gamma != 1 && t.gamma > 0:
+		level = math.Pow(level, 1/t.gamma)
+	}
+	if t.invert {
+		level = 1 - level
+	}
+	return level / t.multiplier
+}
+
+// withLevels fills in the luminance range of the animation when auto levels
+// are on. Only the ascii and edges renderers map brightness to characters.
+func withLevels(anim *decode.Animation, cfg Config) (Config, error) {
+	if !cfg.AutoLevels || cfg.Renderer != RendererASCII && cfg.Renderer != RendererEdges {
+		return cfg, nil
+	}
+	black, white, err := anim.LuminanceRange()
+	if err != nil {
+		return cfg, err
+	}
+	cfg.black, cfg.white = black, white
+	return cfg, nil
+}
@@ -0,0 +1,79 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"sort"
+)
+
+// Most colors a palette can have, sixel output needs a register for every
+// one of them next to the transparent one
+const MaxPaletteColors = 255
+
+// Named palettes for -palette, the colors of popular terminal themes
+var palettePresets = map[string][]color.RGBA{
+	"gruvbox": hexPalette(
+		0x282828, 0xcc241d, 0x98971a, 0xd79921, 0x458588, 0xb16286, 0x689d6a, 0xa89984,
+		0x928374, 0xfb4934, 0xb8bb26, 0xfabd2f, 0x83a598, 0xd3869b, 0x8ec07c, 0xebdbb2,
+	),
+	"nord": hexPalette(
+		0x2e3440, 0x3b4252, 0x434c5e, 0x4c566a, 0xd8dee9, 0xe5e9f0, 0xeceff4, 0x8fbcbb,
+		0x88c0d0, 0x81a1c1, 0x5e81ac, 0xbf616a, 0xd08770, 0xebcb8b, 0xa3be8c, 0xb48ead,
+	),
+	"dracula": hexPalette(
+		0x21222c, 0x282a36, 0x44475a, 0x6272a4, 0xf8f8f2, 0x8be9fd, 0x50fa7b, 0xffb86c,
+		0xff79c6, 0xbd93f9, 0xff5555, 0xf1fa8c,
+	),
+	"solarized": hexPalette(
+		0x002b36, 0x073642, 0x586e75, 0x657b83, 0x839496, 0x93a1a1, 0xeee8d5, 0xfdf6e3,
+		0xb58900, 0xcb4b16, 0xdc322f, 0xd33682, 0x6c71c4, 0x268bd2, 0x2aa198, 0x859900,
+	),
+}
+
+// hexPalette turns colors written as 0xrrggbb into a palette
+func hexPalette(values ...uint32) []color.RGBA {
+	palette := make([]color.RGBA, len(values))
+	for i, v := range values {
+		palette[i] = color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}
+	}
+	return palette
+}
+
+// PalettePreset returns the colors of a named palette
+func PalettePreset(name string) ([]color.RGBA, bool) {
+	palette, ok := palettePresets[name]
+	return palette, ok
+}
+
+// PaletteNames lists the named palettes, sorted
+func PaletteNames() []string {
+	names := make([]string, 0, len(palettePresets))
+	for name := range palettePresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidatePalette checks that a palette can be used for Config.Palette
+func ValidatePalette(palette []color.RGBA) error {
+	switch {
+	case len(palette) == 0:
+		return errors.New("palette has no colors")
+	case len(palette) > MaxPaletteColors:
+		return fmt.Errorf("palette has %d colors, at most %d are supported", len(palette), MaxPaletteColors)
+	}
+	return nil
+}
+
+// nearestColor returns the index of the color of palette closest to r,g,b
+func nearestColor(palette []color.RGBA, r, g, b uint8) int {
+	best, bestDist := 0, -1
+	for i, c := range palette {
+		if d := colorDistance(int(r), int(g), int(b), int(c.R), int(c.G), int(c.B)); bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
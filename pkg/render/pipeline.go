@@ -0,0 +1,265 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/decode"
+	"github.com/ferrebarrat/brrtfetch/pkg/term"
+)
+
+// Job represents a frame to be rendered concurrently
+type RenderJob struct {
+	Index   int
+	Image   *image.RGBA
+	PoolKey *image.RGBA // Key to return the buffer to the pool
+	Delay   time.Duration
+}
+
+// Result holds the rendered art lines and their index
+type RenderResult struct {
+	Index int
+	Lines []string
+	Delay time.Duration
+}
+
+// Returned by Prerender when the frames don't fit in the memory budget
+var ErrOverBudget = errors.New("prerendered frames exceed the memory budget")
+
+// Frames composes the frames of the animation and renders them to
+// art lines concurrently, handing them to deliver in playback order. Runs of
+// identical frames are delivered as one frame with their delays added up.
+// Only a few frames are in flight at any time: when deliver blocks, the
+// workers and the decoder wait for it. Rendering stops early when deliver
+// returns false.
+func Frames(anim *decode.Animation, cfg Config, deliver func(art []string, delay time.Duration) bool) error {
+	cfg, err := withLevels(anim, cfg)
+	if err != nil {
+		return err
+	}
+
+	// === CONCURRENT RENDERING SETUP ===
+	numWorkers := cfg.Workers
+	if numWorkers <= 0 {
+		numWorkers = runtime.NumCPU()
+	}
+	jobs := make(chan RenderJob, numWorkers*2)
+	results := make(chan RenderResult, numWorkers*2)
+	var wg sync.WaitGroup
+
+	// 1. Initialize Buffer Pool, recycles image buffers between frames
+	bufferPool := make(chan *image.RGBA, numWorkers*2)
+	for i := 0; i < cap(bufferPool); i++ {
+		bufferPool <- image.NewRGBA(image.Rect(0, 0, anim.Width, anim.Height))
+	}
+
+	// 2. Start worker goroutines
+	for w := 0; w < numWorkers; w++ {
+		wg.Add(1)
+		go worker(w, jobs, results, bufferPool, cfg, &wg)
+	}
+
+	// 3. Put results back in order, workers finish in any order
+	var stopped atomic.Bool
+	collected := make(chan struct{})
+	go func() {
+		pending := map[int]RenderResult{}
+		next := 0
+		for result := range results {
+			pending[result.Index] = result
+			for {
+				r, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+				if !stopped.Load() && !deliver(r.Lines, r.Delay) {
+					stopped.Store(true)
+				}
+			}
+		}
+		close(collected)
+	}()
+
+	// 4. Composing and dispatching jobs. A frame is held back until the next
+	// one differs, identical frames are rendered once and shown for all
+	// their delays together. A frame too short for -max-fps is replaced by
+	// the next one, which takes over its time.
+	index := 0
+	var held *RenderJob
+	minDelay := minFrameDelay(cfg)
+	err = anim.Frames(func(frame *image.RGBA, delay time.Duration) bool {
+		if stopped.Load() {
+			return false
+		}
+		if held != nil && bytes.Equal(held.Image.Pix, frame.Pix) {
+			held.Delay += delay
+			return true
+		}
+		if held != nil && held.Delay < minDelay {
+			copy(held.Image.Pix, frame.Pix)
+			held.Delay += delay
+			return true
+		}
+		frameCopy := <-bufferPool
+		copy(frameCopy.Pix, frame.Pix)
+		if held != nil {
+			jobs <- *held
+			index++
+		}
+		held = &RenderJob{Index: index, Image: frameCopy, PoolKey: frameCopy, Delay: delay}
+		return true
+	})
+	if held != nil {
+		jobs <- *held
+		index++
+	}
+	close(jobs)
+
+	// 5. Wait for workers and close results
+	wg.Wait()
+	close(results)
+	<-collected
+
+	if err != nil {
+		return err
+	}
+	if index == 0 {
+		return errors.New("no frames to play")
+	}
+	return nil
+}
+
+// Prerender renders every frame of the animation up front. It returns the
+// art lines of each frame and how long each frame is shown. With maxMemory > 0
+// it gives up with ErrOverBudget once the frames take more bytes than that.
+func Prerender(anim *decode.Animation, cfg Config, maxMemory int64) ([][]string, []time.Duration, error) {
+	var prerendered [][]string
+	var delays []time.Duration
+	var size int64
+	err := Frames(anim, cfg, func(lines []string, delay time.Duration) bool {
+		prerendered = append(prerendered, lines)
+		delays = append(delays, delay)
+		for _, line := range lines {
+			size += int64(len(line))
+		}
+		return maxMemory <= 0 || size <= maxMemory
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if maxMemory > 0 && size > maxMemory {
+		return nil, nil, ErrOverBudget
+	}
+	return prerendered, delays, nil
+}
+
+// Stream renders the animation over and over in the background for inputs
+// too long to prerender, placing the sysinfo next to every frame. sysInfo is
+// asked for the current lines every frame. Only window frames are kept ahead
+// of playback. Closing stop ends the stream.
+func Stream(anim *decode.Animation, cfg Config, sysInfo func() []string, window int, stop <-chan struct{}) <-chan term.Frame {
+	frames := make(chan term.Frame, window)
+	go func() {
+		for {
+			first := true
+			err := Frames(anim, cfg, func(art []string, delay time.Duration) bool {
+				select {
+				case frames <- term.Frame{Lines: Compose(art, cfg, sysInfo()), Delay: FrameDelay(cfg, delay), First: first}:
+					first = false
+					return true
+				case <-stop:
+					return false
+				}
+			})
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if err != nil {
+				frames <- term.Frame{Err: err}
+				return
+			}
+		}
+	}()
+	return frames
+}
+
+// FrameDelay applies -fps and -speed, a fixed fps replaces the timing of the
+// input
+func FrameDelay(cfg Config, delay time.Duration) time.Duration {
+	if cfg.FPS > 0 {
+		delay = time.Second / time.Duration(cfg.FPS)
+	}
+	if cfg.Speed > 0 {
+		delay = time.Duration(float64(delay) / cfg.Speed)
+	}
+	return delay
+}
+
+// minFrameDelay is how long a frame of the input has to be shown at least
+// for -max-fps, in the input's own timing. 0 = no limit.
+func minFrameDelay(cfg Config) time.Duration {
+	if cfg.MaxFPS <= 0 {
+		return 0
+	}
+	speed := cfg.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+	return time.Duration(float64(time.Second) * speed / float64(cfg.MaxFPS))
+}
+
+// worker goroutine function
+func worker(id int, jobs <-chan RenderJob, results chan<- RenderResult, bufferPool chan<- *image.RGBA,
+	cfg Config, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for job := range jobs {
+		lines := Art(job.Image, cfg)
+		results <- RenderResult{Index: job.Index, Lines: lines, Delay: job.Delay}
+		bufferPool <- job.PoolKey
+	}
+}
+
+// Still renders only frame n of the animation, wrapping around when
+// the animation is shorter than that. Frames are counted like
+// Frames delivers them, repeats of the previous frame don't count.
+func Still(anim *decode.Animation, cfg Config, n int) ([]string, error) {
+	cfg, err := withLevels(anim, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var art []string
+	var previous []byte
+	count := 0
+	err = anim.Frames(func(frame *image.RGBA, delay time.Duration) bool {
+		if count > 0 && bytes.Equal(previous, frame.Pix) {
+			return true
+		}
+		if count == n {
+			art = Art(frame, cfg)
+			return false
+		}
+		previous = append(previous[:0], frame.Pix...)
+		count++
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if art != nil {
+		return art, nil
+	}
+	if count == 0 {
+		return nil, errors.New("no frames to play")
+	}
+	return Still(anim, cfg, n%count)
+}
@@ -0,0 +1,468 @@
+// Package render turns the frames of an animation into lines of colored
+// characters and puts the sysinfo next to them.
+//
+// A minimal program:
+//
+//	anim, err := decode.Open("nyan.gif", decode.VideoOptions{})
+//	if err != nil {
+//		return err
+//	}
+//	cfg := render.DefaultConfig()
+//	art, err := render.Still(anim, cfg, 0)
+//	if err != nil {
+//		return err
+//	}
+//	for _, line := range render.Compose(art, cfg, []string{"hello"}) {
+//		fmt.Println(line)
+//	}
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/decode"
+	"github.com/ferrebarrat/brrtfetch/pkg/term"
+)
+
+// Width / height of a terminal cell when the terminal doesn't report its
+// pixel size, most fonts are about twice as tall as they are wide
+const DefaultCellAspect = 0.5
+
+// Spaces between the art and the sysinfo when -gap isn't given
+const DefaultGap = 3
+
+// Config struct to hold CLI overrides or defaults
+type Config struct {
+	Width      int
+	Height     int
+	FPS        int // Deprecated: a fixed rate, use Speed and MaxFPS instead
+	ColorMode  string
+	Renderer   string
+	Scaler     string
+	Threshold  float64
+	Multiplier float64
+	Charset    []string // Ramp from lightest to densest, for the ascii renderer
+	Symbols    []string // Symbol sets of the symbols renderer
+	Offset     int
+	Layout     string
+
+	// Speed multiplies the playback rate, 0 counts as 1. Frames shown
+	// shorter than 1/MaxFPS are merged into the next one, 0 = no limit.
+	Speed  float64
+	MaxFPS int
+
+	// Gradient strength (0-255) from where the edges renderer draws lines
+	EdgeThreshold float64
+
+	// Width / height of a terminal cell, how many rows Height takes
+	CellAspect float64
+
+	// Brightness to character mapping of the ascii renderer. black and white
+	// are the luminance range auto levels found, filled in while rendering.
+	AutoLevels   bool
+	Brightness   float64
+	Contrast     float64
+	Gamma        float64
+	InvertRamp   bool // lightest pixels get the densest characters
+	black, white float64
+
+	// Where the sysinfo goes next to the art
+	InfoAlign   string
+	InfoOffsetX int
+
+	// Lines of the sysinfo wider than InfoWidth columns are handled as
+	// InfoOverflow says, 0 = no limit. See InfoColumns.
+	InfoWidth    int
+	InfoOverflow string
+
+	// Spaces between art and sysinfo, and empty space around both of them
+	Gap           int
+	PaddingTop    int
+	PaddingLeft   int
+	PaddingRight  int
+	PaddingBottom int
+
+	// Partly transparent pixels are blended against Background when
+	// BlendBackground is set, otherwise drawn fully opaque
+	Background      color.RGBA
+	BlendBackground bool
+
+	// Pixels less opaque than AlphaThreshold are transparent, 0 and 1 leave
+	// only the fully transparent ones. Matte is drawn behind the art: one
+	// color fills the transparent parts, two make a checkerboard, nil
+	// leaves them transparent.
+	AlphaThreshold uint8
+	Matte          []color.RGBA
+
+	// Colors of the art are snapped to the closest of these, nil keeps them
+	// as they are
+	Palette []color.RGBA
+
+	// SGR parameters of the single color art without colors is drawn in,
+	// e.g. "38;5;111". Empty leaves it in the terminal's foreground.
+	Tint string
+
+	// Pixel size of a terminal cell, only used for sixel output
+	CellWidth  int
+	CellHeight int
+
+	// Passthrough wraps sixel images for tmux, which then hands them to the
+	// terminal it runs in
+	Passthrough bool
+
+	// Frames rendered at the same time, 0 = one per CPU
+	Workers int
+}
+
+// DefaultConfig returns the settings brrtfetch uses when no flags are given,
+// with the color mode picked from the environment
+func DefaultConfig() Config {
+	colorMode, _ := DetectColorMode()
+	charset, _ := ResolveCharset(DefaultCharset)
+	return Config{
+		Width:      40,
+		Height:     40,
+		ColorMode:  colorMode,
+		Renderer:   RendererASCII,
+		Scaler:     ScalerBox,
+		Multiplier: 1.2,
+		Charset:    charset,
+		Symbols:    []string{DefaultSymbols},
+		Layout:     LayoutLeft,
+		CellAspect: DefaultCellAspect,
+		AutoLevels: true,
+		Contrast:   1,
+		Gamma:      1,
+		InfoAlign:  AlignTop,
+		Gap:        DefaultGap,
+
+		InfoOverflow: OverflowTruncate,
+
+		EdgeThreshold: DefaultEdgeThreshold,
+	}
+}
+
+// Layouts selectable with -layout, where the art goes
+const (
+	LayoutLeft  = "left"
+	LayoutRight = "right"
+	LayoutTop   = "top"
+)
+
+// Vertical alignments of the sysinfo against the art, for -info-align
+const (
+	AlignTop    = "top"
+	AlignCenter = "center"
+	AlignBottom = "bottom"
+)
+
+// What happens to sysinfo lines wider than their column, for -info-overflow
+const (
+	OverflowTruncate = "truncate" // Cut off with an ellipsis
+	OverflowWrap     = "wrap"     // Continued on the next lines
+	OverflowNone     = "none"     // Left to the terminal
+)
+
+// Renderers selectable with -renderer
+const (
+	RendererASCII     = "ascii"
+	RendererHalfBlock = "halfblock"
+	RendererBraille   = "braille"
+	RendererSixel     = "sixel"
+	RendererBG        = "bg"
+	RendererSymbols   = "symbols"
+	RendererEdges     = "edges"
+)
+
+// Dot bits of a braille cell indexed by [row][column], see U+2800
+var BrailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// Convert a frame to art lines, every line is cfg.Width columns wide. The
+// frame is first scaled to exactly the pixels the renderer samples and made
+// opaque wherever it isn't fully transparent. Without colors the lines are
+// drawn in cfg.Tint.
+func Art(img *image.RGBA, cfg Config) []string {
+	lines := renderArt(img, cfg)
+	if cfg.ColorMode == ColorNone && cfg.Tint != "" && cfg.Renderer != RendererSixel {
+		// Lines without colors start with a reset and have no other escape
+		// sequences, the tint goes right after it
+		for i, line := range lines {
+			lines[i] = "\x1b[0;" + cfg.Tint + "m" + strings.TrimPrefix(line, "\x1b[0m") + "\x1b[0m"
+		}
+	}
+	return lines
+}
+
+// renderArt is Art before the tint, with the renderer cfg picks
+func renderArt(img *image.RGBA, cfg Config) []string {
+	img = matteFrame(img, cfg.AlphaThreshold, cfg.Matte)
+	scale := func(width, height int) *image.RGBA {
+		return flattenAlpha(scaleImage(img, width, height, cfg.Scaler), cfg.Background, cfg.BlendBackground)
+	}
+	rows := Rows(cfg.Height, cfg.CellAspect)
+	colors := colorOutput{mode: cfg.ColorMode, palette: cfg.Palette}
+	switch cfg.Renderer {
+	case RendererHalfBlock:
+		// Two pixels per row, the last row only has its top half when odd
+		height := int(float64(cfg.Height) * cfg.CellAspect * 2)
+		if height < 1 {
+			height = 1
+		}
+		img = scale(cfg.Width, height)
+		return renderHalfBlock(img, cfg.Width, height, colors)
+	case RendererBG:
+		img = scale(cfg.Width, rows)
+		return renderBackground(img, cfg.Width, rows, colors)
+	case RendererBraille:
+		img = scale(cfg.Width*2, rows*4)
+		return renderBraille(img, cfg.Width, rows, colors, cfg.Threshold)
+	case RendererSymbols:
+		img = scale(cfg.Width*symbolGrid, rows*symbolGrid)
+		return renderSymbols(img, cfg.Width, rows, colors, cfg.Threshold, cfg.Symbols)
+	case RendererEdges:
+		img = scale(cfg.Width, rows)
+		return renderEdges(img, cfg.Width, rows, colors, cfg.Charset, newToneCurve(cfg), cfg.EdgeThreshold)
+	case RendererSixel:
+		img = scale(cfg.Width*cfg.CellWidth, rows*cfg.CellHeight)
+
+		// Keep the art area blank, the image is drawn on top of it
+		art := make([]string, rows)
+		for i := range art {
+			art[i] = strings.Repeat(" ", cfg.Width)
+		}
+
+		// Sixel images have to be drawn after the text, otherwise the blank
+		// art area would paint over them. At the end of the last art line,
+		// save the cursor, draw from the first art line and jump back. Moving
+		// relative to the art keeps this working whichever side it is on.
+		up := ""
+		if rows > 1 {
+			up = fmt.Sprintf("\033[%dA", rows-1)
+		}
+		sixel := encodeSixel(img, cfg.Width*cfg.CellWidth, rows*cfg.CellHeight, colors)
+		if cfg.Passthrough {
+			sixel = term.TmuxPassthrough(sixel)
+		}
+		art[rows-1] += "\0337" + up + fmt.Sprintf("\033[%dD", cfg.Width) + sixel + "\0338"
+		return art
+	default:
+		img = scale(cfg.Width, rows)
+		return renderASCII(img, cfg.Width, rows, colors, cfg.Charset, newToneCurve(cfg))
+	}
+}
+
+// Convert a frame to ASCII lines, one character per sampled pixel
+func renderASCII(img *image.RGBA, width, rows int, colors colorOutput, ramp []string, tone toneCurve) []string {
+	lines := make([]string, rows)
+	pix := img.Pix
+	stride := img.Stride
+	scaleX := float64(img.Bounds().Dx()) / float64(width)
+	scaleY := float64(img.Bounds().Dy()) / float64(rows)
+	var line artLine
+
+	for y := 0; y < rows; y++ {
+		line.reset()
+		for x := 0; x < width; x++ {
+			px := int(float64(x) * scaleX)
+			py := int(float64(y) * scaleY)
+			offsetPix := py*stride + px*4
+			r8, g8, b8, a8 := pix[offsetPix], pix[offsetPix+1], pix[offsetPix+2], pix[offsetPix+3]
+
+			line.style = line.style[:0]
+			if a8 == 0 {
+				line.cell(" ")
+				continue
+			}
+			if !colors.none() {
+				line.style = colors.appendFg(line.style, r8, g8, b8)
+			}
+			line.cell(pixelToASCII(r8, g8, b8, ramp, tone))
+		}
+		lines[y] = line.String()
+	}
+
+	return lines
+}
+
+// Convert a frame to half block lines. Every character covers two pixels
+// stacked on top of each other: the upper one is drawn with the foreground
+// color of '▀' and the lower one with the background color.
+func renderHalfBlock(img *image.RGBA, width, height int, colors colorOutput) []string {
+	rows := (height + 1) / 2
+	lines := make([]string, rows)
+	pix := img.Pix
+	stride := img.Stride
+	scaleX := float64(img.Bounds().Dx()) / float64(width)
+	scaleY := float64(img.Bounds().Dy()) / float64(height)
+	var line artLine
+
+	for y := 0; y < rows; y++ {
+		line.reset()
+		pyTop := int(float64(2*y) * scaleY)
+		pyBottom := int(float64(2*y+1) * scaleY)
+		if 2*y+1 >= height {
+			pyBottom = -1 // odd height, the last row only has a top half
+		}
+
+		for x := 0; x < width; x++ {
+			px := int(float64(x) * scaleX)
+			top := pyTop*stride + px*4
+			topOpaque := pix[top+3] != 0
+			bottom := -1
+			bottomOpaque := false
+			if pyBottom >= 0 {
+				bottom = pyBottom*stride + px*4
+				bottomOpaque = pix[bottom+3] != 0
+			}
+
+			line.style = line.style[:0]
+			switch {
+			case !topOpaque && !bottomOpaque:
+				line.cell(" ")
+			case colors.none():
+				line.cell(halfBlockMono(topOpaque, bottomOpaque))
+			case topOpaque && bottomOpaque:
+				line.style = colors.appendFg(line.style, pix[top], pix[top+1], pix[top+2])
+				line.style = append(line.style, ';')
+				line.style = colors.appendBg(line.style, pix[bottom], pix[bottom+1], pix[bottom+2])
+				line.cell("▀")
+			case topOpaque:
+				// The default background, a colored one may still be set
+				line.style = colors.appendFg(line.style, pix[top], pix[top+1], pix[top+2])
+				line.style = append(line.style, ";49"...)
+				line.cell("▀")
+			default:
+				line.style = colors.appendFg(line.style, pix[bottom], pix[bottom+1], pix[bottom+2])
+				line.style = append(line.style, ";49"...)
+				line.cell("▄")
+			}
+		}
+		lines[y] = line.String()
+	}
+
+	return lines
+}
+
+// Convert a frame to lines of solid cells, every character is a space
+// painted with the background color of its pixel. Without color the cells
+// become full blocks.
+func renderBackground(img *image.RGBA, width, rows int, colors colorOutput) []string {
+	lines := make([]string, rows)
+	pix := img.Pix
+	stride := img.Stride
+	scaleX := float64(img.Bounds().Dx()) / float64(width)
+	scaleY := float64(img.Bounds().Dy()) / float64(rows)
+	var line artLine
+
+	for y := 0; y < rows; y++ {
+		line.reset()
+		for x := 0; x < width; x++ {
+			px := int(float64(x) * scaleX)
+			py := int(float64(y) * scaleY)
+			offsetPix := py*stride + px*4
+			r8, g8, b8, a8 := pix[offsetPix], pix[offsetPix+1], pix[offsetPix+2], pix[offsetPix+3]
+
+			line.style = line.style[:0]
+			switch {
+			case a8 == 0:
+				line.cell(" ")
+			case colors.none():
+				line.cell("█")
+			default:
+				line.style = colors.appendBg(line.style, r8, g8, b8)
+				line.cell(" ")
+			}
+		}
+		lines[y] = line.String()
+	}
+
+	return lines
+}
+
+// Convert a frame to braille lines. Every character packs a 2x4 block of
+// pixels, a dot is drawn for each non-transparent pixel that is at least as
+// bright as threshold. In color mode the whole cell gets the average color
+// of its drawn dots.
+func renderBraille(img *image.RGBA, width, rows int, colors colorOutput, threshold float64) []string {
+	lines := make([]string, rows)
+	pix := img.Pix
+	stride := img.Stride
+	scaleX := float64(img.Bounds().Dx()) / float64(width*2)
+	scaleY := float64(img.Bounds().Dy()) / float64(rows*4)
+	var line artLine
+
+	for y := 0; y < rows; y++ {
+		line.reset()
+		for x := 0; x < width; x++ {
+			var cell rune
+			var sumR, sumG, sumB, count int
+
+			for dy := 0; dy < 4; dy++ {
+				py := int(float64(y*4+dy) * scaleY)
+				for dx := 0; dx < 2; dx++ {
+					px := int(float64(x*2+dx) * scaleX)
+					offsetPix := py*stride + px*4
+					r8, g8, b8, a8 := pix[offsetPix], pix[offsetPix+1], pix[offsetPix+2], pix[offsetPix+3]
+					if a8 == 0 || decode.Luminance(r8, g8, b8) < threshold {
+						continue
+					}
+					cell |= BrailleDots[dy][dx]
+					sumR += int(r8)
+					sumG += int(g8)
+					sumB += int(b8)
+					count++
+				}
+			}
+
+			line.style = line.style[:0]
+			if count == 0 {
+				line.cell(" ")
+				continue
+			}
+			if !colors.none() {
+				line.style = colors.appendFg(line.style, uint8(sumR/count), uint8(sumG/count), uint8(sumB/count))
+			}
+			line.cellRune(0x2800 + cell)
+		}
+		lines[y] = line.String()
+	}
+
+	return lines
+}
+
+// Pick the block character for monochrome half block output
+func halfBlockMono(top, bottom bool) string {
+	switch {
+	case top && bottom:
+		return "█"
+	case top:
+		return "▀"
+	case bottom:
+		return "▄"
+	default:
+		return " "
+	}
+}
+
+// Map pixel brightness to ASCII
+func pixelToASCII(r, g, b uint8, ramp []string, tone toneCurve) string {
+	// Every character covers an equal slice of the brightness range, darker
+	// pixels get the denser characters at the end of the ramp
+	level := tone.level(decode.Luminance(r, g, b))
+	i := len(ramp) - 1 - int(level*float64(len(ramp)))
+	switch {
+	case i < 0:
+		i = 0
+	case i > len(ramp)-1:
+		i = len(ramp) - 1
+	}
+	return ramp[i]
+}
@@ -0,0 +1,179 @@
+package render
+
+import (
+	"image"
+	"math"
+)
+
+// Scalers selectable with -scaler, how a frame is shrunk to the art size
+const (
+	ScalerNearest  = "nearest"
+	ScalerBox      = "box"
+	ScalerBilinear = "bilinear"
+	ScalerLanczos  = "lanczos"
+)
+
+// A resampling filter: weight gives the contribution of a source pixel at
+// distance x, support is the distance beyond which it is always 0. Both are
+// in source pixels when upscaling and get stretched when downscaling.
+type scaleKernel struct {
+	support float64
+	weight  func(x float64) float64
+}
+
+var scaleKernels = map[string]scaleKernel{
+	ScalerBox: {0.5, func(x float64) float64 {
+		if x <= 0.5 {
+			return 1
+		}
+		return 0
+	}},
+	ScalerBilinear: {1, func(x float64) float64 {
+		return 1 - x
+	}},
+	ScalerLanczos: {3, func(x float64) float64 {
+		if x == 0 {
+			return 1
+		}
+		px := math.Pi * x
+		return 3 * math.Sin(px) * math.Sin(px/3) / (px * px)
+	}},
+}
+
+// A source pixel and how much it adds to an output pixel
+type scaleTap struct {
+	index  int
+	weight float64
+}
+
+// scaleTaps lists for every output position along one axis the source
+// pixels it's made of, with weights adding up to 1
+func scaleTaps(src, dst int, kernel scaleKernel) [][]scaleTap {
+	scale := float64(src) / float64(dst)
+	stretch := 1.0
+	if scale > 1 {
+		stretch = scale // Downscaling, every output pixel covers several source pixels
+	}
+	support := kernel.support * stretch
+
+	taps := make([][]scaleTap, dst)
+	for i := range taps {
+		center := (float64(i)+0.5)*scale - 0.5
+		first := int(math.Ceil(center - support))
+		last := int(math.Floor(center + support))
+		var sum float64
+		for j := first; j <= last; j++ {
+			w := kernel.weight(math.Abs(float64(j)-center) / stretch)
+			if w == 0 {
+				continue
+			}
+			// Clamp to the edge, the pixels outside repeat the border
+			k := j
+			if k < 0 {
+				k = 0
+			} else if k >= src {
+				k = src - 1
+			}
+			taps[i] = append(taps[i], scaleTap{k, w})
+			sum += w
+		}
+		if sum == 0 {
+			// Narrower than a source pixel, fall back to the nearest one
+			k := int(center + 0.5)
+			if k < 0 {
+				k = 0
+			} else if k >= src {
+				k = src - 1
+			}
+			taps[i] = []scaleTap{{k, 1}}
+			continue
+		}
+		for j := range taps[i] {
+			taps[i][j].weight /= sum
+		}
+	}
+	return taps
+}
+
+// scaleImage resizes img to width x height pixels with the given scaler.
+// Pixels that end up mostly transparent become fully transparent, keeping
+// outlines as sharp as the input has them, the others keep their alpha for
+// flattenAlpha. ScalerNearest returns img as it is, the renderers sample it
+// themselves.
+func scaleImage(img *image.RGBA, width, height int, scaler string) *image.RGBA {
+	kernel, ok := scaleKernels[scaler]
+	bounds := img.Bounds()
+	if !ok || width <= 0 || height <= 0 || bounds.Empty() {
+		return img
+	}
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	tapsX := scaleTaps(srcW, width, kernel)
+	tapsY := scaleTaps(srcH, height, kernel)
+
+	// Horizontal pass into floats, colors stay premultiplied by alpha so
+	// transparent pixels don't bleed their color into the opaque ones
+	tmp := make([]float64, srcH*width*4)
+	for y := 0; y < srcH; y++ {
+		row := img.Pix[y*img.Stride:]
+		for x, taps := range tapsX {
+			var r, g, b, a float64
+			for _, t := range taps {
+				p := row[t.index*4 : t.index*4+4]
+				r += float64(p[0]) * t.weight
+				g += float64(p[1]) * t.weight
+				b += float64(p[2]) * t.weight
+				a += float64(p[3]) * t.weight
+			}
+			o := (y*width + x) * 4
+			tmp[o], tmp[o+1], tmp[o+2], tmp[o+3] = r, g, b, a
+		}
+	}
+
+	// Vertical pass
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y, taps := range tapsY {
+		for x := 0; x < width; x++ {
+			var r, g, b, a float64
+			for _, t := range taps {
+				o := (t.index*width + x) * 4
+				r += tmp[o] * t.weight
+				g += tmp[o+1] * t.weight
+				b += tmp[o+2] * t.weight
+				a += tmp[o+3] * t.weight
+			}
+			if a < 128 {
+				continue // Left transparent
+			}
+			// Premultiplied colors can't be brighter than their alpha
+			alpha := clampByte(a)
+			p := out.Pix[y*out.Stride+x*4:]
+			p[0] = clampChannel(r, alpha)
+			p[1] = clampChannel(g, alpha)
+			p[2] = clampChannel(b, alpha)
+			p[3] = alpha
+		}
+	}
+	return out
+}
+
+// clampByte rounds v to the nearest value a color channel can hold, lanczos
+// overshoots a little around sharp edges
+func clampByte(v float64) uint8 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 255:
+		return 255
+	}
+	return uint8(v + 0.5)
+}
+
+// clampChannel is clampByte for a premultiplied channel of a pixel with the
+// given alpha
+func clampChannel(v float64, alpha uint8) uint8 {
+	c := clampByte(v)
+	if c > alpha {
+		return alpha
+	}
+	return c
+}
@@ -0,0 +1,127 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"strings"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/decode"
+)
+
+// Palette register used for transparent pixels, real colors use 0-215
+const sixelTransparent = 255
+
+// encodeSixel scales a frame to width x height pixels and encodes it as a
+// sixel image. Colors are quantized to a 6x6x6 cube, or to the palette of
+// colors when it has one, so they always fit in the 256 registers most
+// terminals provide. Transparent pixels are left untouched so the terminal
+// background shows through.
+func encodeSixel(img *image.RGBA, width, height int, colors colorOutput) string {
+	pix := img.Pix
+	stride := img.Stride
+	scaleX := float64(img.Bounds().Dx()) / float64(width)
+	scaleY := float64(img.Bounds().Dy()) / float64(height)
+
+	indices := make([]byte, width*height)
+	var used [sixelTransparent]bool
+	for y := 0; y < height; y++ {
+		py := int(float64(y) * scaleY)
+		for x := 0; x < width; x++ {
+			px := int(float64(x) * scaleX)
+			offsetPix := py*stride + px*4
+			r8, g8, b8, a8 := pix[offsetPix], pix[offsetPix+1], pix[offsetPix+2], pix[offsetPix+3]
+			if a8 == 0 {
+				indices[y*width+x] = sixelTransparent
+				continue
+			}
+			if colors.none() {
+				lum := uint8(decode.Luminance(r8, g8, b8))
+				r8, g8, b8 = lum, lum, lum
+			}
+			var idx int
+			if len(colors.palette) > 0 {
+				idx = nearestColor(colors.palette, r8, g8, b8)
+			} else {
+				idx = cubeLevel(r8)*36 + cubeLevel(g8)*6 + cubeLevel(b8)
+			}
+			indices[y*width+x] = byte(idx)
+			used[idx] = true
+		}
+	}
+
+	var sb strings.Builder
+	// P2=1 keeps pixels we don't paint transparent
+	sb.WriteString("\033P0;1;0q")
+	fmt.Fprintf(&sb, "\"1;1;%d;%d", width, height)
+	for idx, ok := range used {
+		// Register colors are in percent
+		switch {
+		case !ok:
+		case len(colors.palette) > 0:
+			c := colors.palette[idx]
+			fmt.Fprintf(&sb, "#%d;2;%d;%d;%d", idx, int(c.R)*100/255, int(c.G)*100/255, int(c.B)*100/255)
+		default:
+			fmt.Fprintf(&sb, "#%d;2;%d;%d;%d", idx, idx/36*20, idx/6%6*20, idx%6*20)
+		}
+	}
+
+	row := make([]byte, width)
+	for band := 0; band < height; band += 6 {
+		var inBand [sixelTransparent]bool
+		for y := band; y < band+6 && y < height; y++ {
+			for _, idx := range indices[y*width : (y+1)*width] {
+				if idx != sixelTransparent {
+					inBand[idx] = true
+				}
+			}
+		}
+
+		first := true
+		for color, ok := range inBand {
+			if !ok {
+				continue
+			}
+			for x := 0; x < width; x++ {
+				var bits byte
+				for dy := 0; dy < 6 && band+dy < height; dy++ {
+					if indices[(band+dy)*width+x] == byte(color) {
+						bits |= 1 << dy
+					}
+				}
+				row[x] = '?' + bits
+			}
+			if !first {
+				sb.WriteByte('$') // back to the start of the band for the next color
+			}
+			first = false
+			fmt.Fprintf(&sb, "#%d", color)
+			writeSixelRow(&sb, row)
+		}
+		sb.WriteByte('-')
+	}
+
+	sb.WriteString("\033\\")
+	return sb.String()
+}
+
+// writeSixelRow writes one color of a band, collapsing repeated characters
+// with the sixel repeat introducer.
+func writeSixelRow(sb *strings.Builder, row []byte) {
+	for i := 0; i < len(row); {
+		j := i
+		for j < len(row) && row[j] == row[i] {
+			j++
+		}
+		if run := j - i; run > 3 {
+			fmt.Fprintf(sb, "!%d%c", run, row[i])
+		} else {
+			sb.Write(row[i:j])
+		}
+		i = j
+	}
+}
+
+// Map a channel to one of the 6 levels of the color cube
+func cubeLevel(v uint8) int {
+	return (int(v)*5 + 127) / 255
+}
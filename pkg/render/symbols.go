@@ -0,0 +1,259 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/bits"
+	"strings"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/decode"
+)
+
+// Symbol sets the symbols renderer picks from, for -symbols
+const (
+	SymbolsBlocks = "blocks" // halves, eighths and quadrants of the block elements
+	SymbolsWedges = "wedges" // triangles filling half of the cell diagonally
+	SymbolsASCII  = "ascii"  // lines and dots of plain ASCII
+)
+
+// Symbol set used by the symbols renderer when none is given
+const DefaultSymbols = SymbolsBlocks
+
+// Pixels per side of the block every cell is matched on
+const symbolGrid = 8
+
+// A character with the pixels of its cell it draws in the foreground color,
+// bit y*symbolGrid+x for the pixel in column x of row y
+type symbol struct {
+	char string
+	mask uint64
+	n    int // Number of foreground pixels
+}
+
+// newSymbol rasterizes shape, which gets positions from 0 to 1 across the
+// cell, to the pixels of a symbol
+func newSymbol(char string, shape func(x, y float64) bool) symbol {
+	var mask uint64
+	for y := 0; y < symbolGrid; y++ {
+		for x := 0; x < symbolGrid; x++ {
+			if shape((float64(x)+0.5)/symbolGrid, (float64(y)+0.5)/symbolGrid) {
+				mask |= 1 << (y*symbolGrid + x)
+			}
+		}
+	}
+	return symbol{char: char, mask: mask, n: bits.OnesCount64(mask)}
+}
+
+// The full block, part of every set
+var fullBlock = newSymbol("█", func(x, y float64) bool { return true })
+
+var symbolSets = map[string][]symbol{
+	SymbolsBlocks: blockSymbols(),
+	SymbolsWedges: {
+		newSymbol("◢", func(x, y float64) bool { return x+y >= 1 }),
+		newSymbol("◣", func(x, y float64) bool { return y >= x }),
+		newSymbol("◤", func(x, y float64) bool { return x+y < 1 }),
+		newSymbol("◥", func(x, y float64) bool { return y < x }),
+	},
+	SymbolsASCII: {
+		newSymbol("-", func(x, y float64) bool { return y >= 0.4 && y < 0.6 && x >= 0.15 && x < 0.85 }),
+		newSymbol("_", func(x, y float64) bool { return y >= 0.85 }),
+		newSymbol("|", func(x, y float64) bool { return x >= 0.375 && x < 0.625 }),
+		newSymbol("/", func(x, y float64) bool { return math.Abs(x+y-1) < 0.15 }),
+		newSymbol("\\", func(x, y float64) bool { return math.Abs(x-y) < 0.15 }),
+		newSymbol(".", func(x, y float64) bool { return y >= 0.75 && x >= 0.375 && x < 0.625 }),
+		newSymbol("'", func(x, y float64) bool { return y < 0.3 && x >= 0.375 && x < 0.625 }),
+		newSymbol("=", func(x, y float64) bool {
+			return (y >= 0.3 && y < 0.45 || y >= 0.55 && y < 0.7) && x >= 0.15 && x < 0.85
+		}),
+		newSymbol("+", func(x, y float64) bool {
+			return y >= 0.4 && y < 0.6 && x >= 0.15 && x < 0.85 || x >= 0.375 && x < 0.625 && y >= 0.2 && y < 0.8
+		}),
+	},
+}
+
+// Quadrants of U+2596 to U+259F, as bits: upper left 1, upper right 2,
+// lower left 4, lower right 8
+var quadrantBits = [10]int{4, 8, 1, 1 | 4 | 8, 1 | 8, 1 | 2 | 4, 1 | 2 | 8, 2, 2 | 4, 2 | 4 | 8}
+
+// blockSymbols lists the block elements that split a cell into a
+// foreground and a background part
+func blockSymbols() []symbol {
+	syms := []symbol{
+		newSymbol("▀", func(x, y float64) bool { return y < 0.5 }),
+		newSymbol("▐", func(x, y float64) bool { return x >= 0.5 }),
+		newSymbol("▔", func(x, y float64) bool { return y < 0.125 }),
+		newSymbol("▕", func(x, y float64) bool { return x >= 0.875 }),
+	}
+	for r := '▁'; r < '█'; r++ { // Lower eighths
+		h := float64(r-'▁'+1) / 8
+		syms = append(syms, newSymbol(string(r), func(x, y float64) bool { return y >= 1-h }))
+	}
+	for r := '▉'; r <= '▏'; r++ { // Left eighths
+		w := float64('▏'-r+1) / 8
+		syms = append(syms, newSymbol(string(r), func(x, y float64) bool { return x < w }))
+	}
+	for i, q := range quadrantBits {
+		q := q
+		syms = append(syms, newSymbol(string('▖'+rune(i)), func(x, y float64) bool {
+			bit := 1
+			if x >= 0.5 {
+				bit <<= 1
+			}
+			if y >= 0.5 {
+				bit <<= 2
+			}
+			return q&bit != 0
+		}))
+	}
+	return syms
+}
+
+// ResolveSymbols turns a -symbols value, set names separated by commas, into
+// the list of sets
+func ResolveSymbols(value string) ([]string, error) {
+	var sets []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := symbolSets[name]; !ok {
+			return nil, fmt.Errorf("unknown symbol set %q, use %s, %s or %s", name, SymbolsBlocks, SymbolsWedges, SymbolsASCII)
+		}
+		sets = append(sets, name)
+	}
+	return sets, nil
+}
+
+// Convert a frame to lines of the symbols that match the shapes in it best.
+// Every cell is an 8x8 block of pixels, each symbol splits it into a
+// foreground and a background part and the one whose parts are the most
+// even in color wins, drawn with the average colors of its parts. Cells that
+// are partly transparent get the symbol closest to their outline with the
+// default background, so do all cells without color, where only the pixels
+// at least as bright as threshold count.
+func renderSymbols(img *image.RGBA, width, rows int, colors colorOutput, threshold float64, sets []string) []string {
+	syms := []symbol{fullBlock}
+	if len(sets) == 0 {
+		sets = []string{DefaultSymbols}
+	}
+	for _, set := range sets {
+		syms = append(syms, symbolSets[set]...)
+	}
+
+	lines := make([]string, rows)
+	pix := img.Pix
+	stride := img.Stride
+	scaleX := float64(img.Bounds().Dx()) / float64(width*symbolGrid)
+	scaleY := float64(img.Bounds().Dy()) / float64(rows*symbolGrid)
+	var line artLine
+	var cell [symbolGrid * symbolGrid][3]int
+
+	for y := 0; y < rows; y++ {
+		line.reset()
+		for x := 0; x < width; x++ {
+			var opaque, lit uint64
+			var total [3]int
+			for dy := 0; dy < symbolGrid; dy++ {
+				py := int(float64(y*symbolGrid+dy) * scaleY)
+				for dx := 0; dx < symbolGrid; dx++ {
+					px := int(float64(x*symbolGrid+dx) * scaleX)
+					offsetPix := py*stride + px*4
+					r8, g8, b8, a8 := pix[offsetPix], pix[offsetPix+1], pix[offsetPix+2], pix[offsetPix+3]
+					i := dy*symbolGrid + dx
+					if a8 == 0 {
+						cell[i] = [3]int{}
+						continue
+					}
+					opaque |= 1 << i
+					if decode.Luminance(r8, g8, b8) >= threshold {
+						lit |= 1 << i
+					}
+					cell[i] = [3]int{int(r8), int(g8), int(b8)}
+					total[0] += int(r8)
+					total[1] += int(g8)
+					total[2] += int(b8)
+				}
+			}
+
+			line.style = line.style[:0]
+			switch {
+			case opaque == 0:
+				line.cell(" ")
+			case colors.none():
+				line.cell(nearestSymbol(syms, lit))
+			case opaque != math.MaxUint64:
+				n := bits.OnesCount64(opaque)
+				line.style = colors.appendFg(line.style, uint8(total[0]/n), uint8(total[1]/n), uint8(total[2]/n))
+				line.style = append(line.style, ";49"...)
+				line.cell(nearestSymbol(syms, opaque))
+			default:
+				sym, fg, bg := bestSymbol(syms, &cell, total)
+				line.style = colors.appendFg(line.style, fg[0], fg[1], fg[2])
+				if sym.n < len(cell) {
+					line.style = append(line.style, ';')
+					line.style = colors.appendBg(line.style, bg[0], bg[1], bg[2])
+				}
+				line.cell(sym.char)
+			}
+		}
+		lines[y] = line.String()
+	}
+
+	return lines
+}
+
+// nearestSymbol returns the symbol that differs from mask in the fewest
+// pixels, a space when that's closer than any of them
+func nearestSymbol(syms []symbol, mask uint64) string {
+	best, bestDist := " ", bits.OnesCount64(mask)
+	for _, s := range syms {
+		if dist := bits.OnesCount64(s.mask ^ mask); dist < bestDist {
+			best, bestDist = s.char, dist
+		}
+	}
+	return best
+}
+
+// bestSymbol picks the symbol that leaves the least color error when its
+// foreground and background pixels each get their average color, and
+// returns it with those colors. total is the sum of all pixels of cell.
+func bestSymbol(syms []symbol, cell *[symbolGrid * symbolGrid][3]int, total [3]int) (symbol, [3]uint8, [3]uint8) {
+	// The error is the sum of squares minus sum²/n of both parts, the sum
+	// of squares is the same for every symbol so only the rest is compared
+	best, bestScore := syms[0], -1.0
+	var bestFg [3]int
+	for _, s := range syms {
+		var fg [3]int
+		for m := s.mask; m != 0; m &= m - 1 {
+			p := cell[bits.TrailingZeros64(m)]
+			fg[0] += p[0]
+			fg[1] += p[1]
+			fg[2] += p[2]
+		}
+		nFg, nBg := float64(s.n), float64(len(cell)-s.n)
+		score := 0.0
+		for c := 0; c < 3; c++ {
+			if s.n > 0 {
+				score += float64(fg[c]*fg[c]) / nFg
+			}
+			if s.n < len(cell) {
+				bg := float64(total[c] - fg[c])
+				score += bg * bg / nBg
+			}
+		}
+		// Only a real improvement counts, splitting an even cell can come out
+		// a rounding error ahead of the full block
+		if score > bestScore+0.5 {
+			best, bestScore, bestFg = s, score, fg
+		}
+	}
+
+	var fg, bg [3]uint8
+	for c := 0; c < 3; c++ {
+		fg[c] = uint8(bestFg[c] / best.n)
+		if best.n < len(cell) {
+			bg[c] = uint8((total[c] - bestFg[c]) / (len(cell) - best.n))
+		}
+	}
+	return best, fg, bg
+}
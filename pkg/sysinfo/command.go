@@ -0,0 +1,115 @@
+package sysinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Returned by runInPTY on platforms without a native pseudo-terminal
+var errPTYUnsupported = errors.New("pseudo-terminals are not supported on this platform")
+
+// CommandOptions controls how the info command is run
+type CommandOptions struct {
+	Timeout time.Duration // Kill the command after this long, 0 = no limit
+	PTY     bool          // Use a native pseudo-terminal instead of script/unbuffer
+	Format  string        // What the command prints, FormatText when empty
+}
+
+// runCommand runs the info command under a pseudo-terminal, so it still
+// thinks it writes to a terminal and keeps its colors. That is either a
+// native one or the one of `script` or `unbuffer` when available.
+func runCommand(commandLine string, opts CommandOptions) (string, error) {
+	parts := strings.Fields(commandLine)
+	if len(parts) == 0 {
+		return "", nil
+	}
+
+	ctx := context.Background()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	result := func(out string, err error) (string, error) {
+		if ctx.Err() == context.DeadlineExceeded {
+			return out, fmt.Errorf("%s timed out after %v", parts[0], opts.Timeout)
+		}
+		if err != nil {
+			return out, fmt.Errorf("%s failed: %v", parts[0], err)
+		}
+		return out, nil
+	}
+	run := func(name string, args ...string) (string, error) {
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Env = append(os.Environ(), "TERM=xterm-256color")
+		cmd.WaitDelay = time.Second // Don't wait for children that keep the output open after a kill
+		out, err := cmd.CombinedOutput()
+		return result(string(out), err)
+	}
+
+	// 0) Our own pseudo-terminal, through the shell like script does
+	if opts.PTY {
+		out, err := runInPTY(ctx, "/bin/sh", "-c", commandLine)
+		if err != errPTYUnsupported {
+			return result(out, err)
+		}
+	}
+
+	flags := []string{"-qefc"}
+	if runtime.GOOS == "darwin" {
+		flags = []string{"-q -c"}
+	}
+
+	// Windows has neither, fetchers there write their colors straight to the pipe
+	if runtime.GOOS == "windows" {
+		return run(parts[0], parts[1:]...)
+	}
+
+	// 1) Try `script` with safe flags
+	if _, err := exec.LookPath("script"); err == nil {
+		// -q quiet, -e exit immediately, -f flush, -c to run command, /dev/null as log
+		return run("script", append(flags, commandLine+" 2>/dev/null", "/dev/null")...)
+	}
+
+	// 2) Try unbuffer
+	if _, err := exec.LookPath("unbuffer"); err == nil {
+		return run("unbuffer", parts...)
+	}
+
+	// 3) Fallback
+	return run(parts[0], parts[1:]...)
+}
+
+// CommandModule is a module whose value is the output of commandLine, run
+// like the info command. Output of several lines keeps them all.
+func CommandModule(key, commandLine string, opts CommandOptions) Module {
+	return Module{Key: key, Collect: func() (string, error) {
+		lines, err := getCommandOutputLines(commandLine, opts)
+		return strings.Join(lines, "\n"), err
+	}}
+}
+
+// getCommandOutputLines executes the command and returns trimmed lines
+func getCommandOutputLines(commandLine string, opts CommandOptions) ([]string, error) {
+	output, err := runCommand(commandLine, opts)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(output, "\n")
+	var cleanLines []string
+	for _, line := range lines {
+		// Trim trailing CR/LF
+		line = strings.TrimRight(line, "\r\n")
+		if line != "" {
+			cleanLines = append(cleanLines, line)
+		}
+	}
+	return cleanLines, nil
+}
@@ -0,0 +1,148 @@
+package sysinfo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long the CPU is watched when there is no earlier sample to compare to
+const cpuSampleTime = 250 * time.Millisecond
+
+// cpuTimes is the busy and total time the CPU spent, from /proc/stat
+type cpuTimes struct {
+	busy, total uint64
+}
+
+// The sample of the last usage measurement, live updates measure the time
+// since then
+var (
+	lastCPUMu     sync.Mutex
+	lastCPUSample *cpuTimes
+)
+
+// readCPUTimes reads the times of all CPUs together
+func readCPUTimes() (cpuTimes, error) {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return cpuTimes{}, err
+	}
+	// "cpu  user nice system idle iowait irq softirq steal ..."
+	line, _, _ := strings.Cut(string(data), "\n")
+	fields := strings.Fields(line)
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return cpuTimes{}, errors.New("unexpected /proc/stat format")
+	}
+	var t cpuTimes
+	for i, field := range fields[1:] {
+		if i >= 8 { // guest times are part of user already
+			break
+		}
+		n, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return cpuTimes{}, err
+		}
+		t.total += n
+		if i != 3 && i != 4 { // idle and iowait
+			t.busy += n
+		}
+	}
+	return t, nil
+}
+
+// cpuUsage returns how busy the CPU was in percent since the last time it
+// was asked, or over a short moment the first time
+func cpuUsage() (float64, error) {
+	if runtime.GOOS != "linux" {
+		return 0, errors.New("cpu usage is unknown on this system")
+	}
+	lastCPUMu.Lock()
+	defer lastCPUMu.Unlock()
+	if lastCPUSample == nil {
+		first, err := readCPUTimes()
+		if err != nil {
+			return 0, err
+		}
+		lastCPUSample = &first
+		time.Sleep(cpuSampleTime)
+	}
+	now, err := readCPUTimes()
+	if err != nil {
+		return 0, err
+	}
+	prev := *lastCPUSample
+	*lastCPUSample = now
+	if now.total <= prev.total {
+		return 0, nil
+	}
+	return float64(now.busy-prev.busy) * 100 / float64(now.total-prev.total), nil
+}
+
+func collectCPUUsage() (string, error) {
+	usage, err := cpuUsage()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%.0f%%", usage), nil
+}
+
+// Names of the hwmon drivers that measure the CPU, by preference
+var cpuSensors = []string{"coretemp", "k10temp", "zenpower", "cpu_thermal", "soc_thermal", "acpitz"}
+
+// cpuTemperature returns the temperature of the CPU in °C, from hwmon or
+// otherwise the thermal zones
+func cpuTemperature() (float64, error) {
+	if runtime.GOOS != "linux" {
+		return 0, errors.New("cpu temperature is unknown on this system")
+	}
+	readMilli := func(path string) (float64, bool) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return 0, false
+		}
+		n, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+		return n / 1000, err == nil
+	}
+
+	hwmons, _ := filepath.Glob("/sys/class/hwmon/hwmon*")
+	for _, sensor := range cpuSensors {
+		for _, dir := range hwmons {
+			if name, err := os.ReadFile(filepath.Join(dir, "name")); err != nil || strings.TrimSpace(string(name)) != sensor {
+				continue
+			}
+			if temp, ok := readMilli(filepath.Join(dir, "temp1_input")); ok {
+				return temp, nil
+			}
+		}
+	}
+
+	// The package zone of Intel CPUs, or the first one there is
+	zones, _ := filepath.Glob("/sys/class/thermal/thermal_zone*")
+	for _, zone := range zones {
+		if kind, err := os.ReadFile(filepath.Join(zone, "type")); err == nil && strings.TrimSpace(string(kind)) == "x86_pkg_temp" {
+			if temp, ok := readMilli(filepath.Join(zone, "temp")); ok {
+				return temp, nil
+			}
+		}
+	}
+	for _, zone := range zones {
+		if temp, ok := readMilli(filepath.Join(zone, "temp")); ok {
+			return temp, nil
+		}
+	}
+	return 0, errors.New("no temperature sensor found")
+}
+
+func collectTemperature() (string, error) {
+	temp, err := cpuTemperature()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%.1f°C", temp), nil
+}
@@ -0,0 +1,59 @@
+package sysinfo
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// DiskOptions picks what the disk module shows
+type DiskOptions struct {
+	Mounts []string // Mountpoints, a line each
+
+	// Usage in percent from where the bar is yellow and red, green below
+	Warn     int
+	Critical int
+}
+
+// Disks configures the disk module, set it before collecting
+var Disks = DiskOptions{Mounts: []string{defaultMount()}, Warn: 70, Critical: 90}
+
+// Cells of the usage bar
+const diskBarWidth = 10
+
+// defaultMount is the root of the file system, or the system drive
+func defaultMount() string {
+	if runtime.GOOS == "windows" {
+		return `C:\`
+	}
+	return "/"
+}
+
+// collectDisks returns a "Disk (mountpoint): used / total (percent%) bar"
+// line per mountpoint of Disks. Mountpoints that can't be read are left out.
+func collectDisks(theme Theme) []string {
+	var lines []string
+	for _, mount := range Disks.Mounts {
+		used, total, err := diskUsage(mount)
+		if err != nil || total == 0 {
+			continue
+		}
+		key := fmt.Sprintf("Disk (%s)", mount)
+		lines = append(lines, styledKey(theme, "disk", key, mount)+" "+style(theme.Value, formatUsage(used, total))+" "+diskBar(used, total))
+	}
+	return lines
+}
+
+// diskBar draws the usage as a bar of blocks, colored by Disks' thresholds
+func diskBar(used, total uint64) string {
+	percent := int(used * 100 / total)
+	color := "32"
+	switch {
+	case percent >= Disks.Critical:
+		color = "31"
+	case percent >= Disks.Warn:
+		color = "33"
+	}
+	filled := int((used*diskBarWidth + total/2) / total)
+	return style(color, strings.Repeat("█", filled)) + style("2", strings.Repeat("░", diskBarWidth-filled))
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd && !dragonfly && !windows
+
+package sysinfo
+
+import "errors"
+
+// diskUsage isn't available on this platform, the disk module stays empty
+func diskUsage(path string) (used, total uint64, err error) {
+	return 0, 0, errors.New("disk usage is not supported on this platform")
+}
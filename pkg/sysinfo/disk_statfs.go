@@ -0,0 +1,16 @@
+//go:build linux || darwin || freebsd || dragonfly
+
+package sysinfo
+
+import "syscall"
+
+// diskUsage returns how many bytes of the file system at path are in use
+// and how large it is, as df reports them
+func diskUsage(path string) (used, total uint64, err error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+	total = uint64(st.Blocks) * uint64(st.Bsize)
+	return total - uint64(st.Bfree)*uint64(st.Bsize), total, nil
+}
@@ -0,0 +1,24 @@
+package sysinfo
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var getDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// diskUsage returns how many bytes of the drive at path are in use and how
+// large it is
+func diskUsage(path string) (used, total uint64, err error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	var available, totalBytes, free uint64
+	r, _, callErr := getDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(p)),
+		uintptr(unsafe.Pointer(&available)), uintptr(unsafe.Pointer(&totalBytes)), uintptr(unsafe.Pointer(&free)))
+	if r == 0 {
+		return 0, 0, callErr
+	}
+	return totalBytes - available, totalBytes, nil
+}
@@ -0,0 +1,192 @@
+package sysinfo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Output formats of an info command, for -info-format
+const (
+	FormatText          = "text"           // lines shown as they are
+	FormatFastfetchJSON = "fastfetch-json" // fastfetch --format json, styled by brrtfetch
+)
+
+// Info command of -info-format fastfetch-json when -info isn't given
+const FastfetchJSONCommand = "fastfetch --format json"
+
+// fastfetchModule is one entry of fastfetch's JSON output
+type fastfetchModule struct {
+	Type   string          `json:"type"`
+	Result json.RawMessage `json:"result"`
+	Error  string          `json:"error"`
+}
+
+// fastfetchJSONLines runs commandLine, which prints what fastfetch --format
+// json does, and turns the modules into lines styled with theme like the
+// built-in ones. Modules fastfetch couldn't detect and the ones brrtfetch
+// doesn't know how to show are left out.
+func fastfetchJSONLines(commandLine string, theme Theme, opts CommandOptions) ([]string, error) {
+	parts := strings.Fields(commandLine)
+	if len(parts) == 0 {
+		return nil, nil
+	}
+	ctx := context.Background()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	// No pseudo-terminal, the JSON has no colors to keep
+	cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
+	cmd.WaitDelay = time.Second
+	out, err := cmd.Output()
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return nil, fmt.Errorf("%s timed out after %v", parts[0], opts.Timeout)
+	case err != nil:
+		return nil, fmt.Errorf("%s failed: %v", parts[0], err)
+	}
+
+	var modules []fastfetchModule
+	if err := json.Unmarshal(out, &modules); err != nil {
+		return nil, fmt.Errorf("%s: no fastfetch JSON: %v", parts[0], err)
+	}
+	var lines []string
+	for _, m := range modules {
+		if m.Error != "" || len(m.Result) == 0 {
+			continue
+		}
+		lines = append(lines, fastfetchModuleLines(m, theme)...)
+	}
+	return lines, nil
+}
+
+// keyValue formats a line like the built-in modules do, with the icon of
+// the built-in module of the same name
+func keyValue(theme Theme, key, value string) string {
+	return styledKey(theme, strings.ToLower(key), key, value) + " " + style(theme.Value, value)
+}
+
+// fastfetchModuleLines shows the result of one fastfetch module
+func fastfetchModuleLines(m fastfetchModule, theme Theme) []string {
+	switch m.Type {
+	case "Title":
+		var r struct{ UserName, HostName string }
+		if json.Unmarshal(m.Result, &r) != nil || r.UserName == "" {
+			return nil
+		}
+		title := r.UserName + "@" + r.HostName
+		return []string{style(theme.Title, title), style(theme.Separator, strings.Repeat("-", len(title)))}
+	case "Separator", "Break":
+		return nil
+	case "Kernel":
+		var r struct{ Name, Release string }
+		if json.Unmarshal(m.Result, &r) != nil || r.Name == "" {
+			return nil
+		}
+		return []string{keyValue(theme, m.Type, strings.TrimSpace(r.Name+" "+r.Release))}
+	case "Colors":
+		return collectModule("colors", theme)
+	case "Uptime":
+		var r struct{ Uptime int64 }
+		if json.Unmarshal(m.Result, &r) != nil || r.Uptime == 0 {
+			return nil
+		}
+		return []string{keyValue(theme, m.Type, formatUptime(time.Duration(r.Uptime)*time.Millisecond))}
+	case "CPU":
+		var r struct {
+			CPU       string
+			Cores     struct{ Online int }
+			Frequency struct{ Base, Max float64 }
+		}
+		if json.Unmarshal(m.Result, &r) != nil || r.CPU == "" {
+			return nil
+		}
+		value := r.CPU
+		if r.Cores.Online > 0 {
+			value += fmt.Sprintf(" (%d)", r.Cores.Online)
+		}
+		if freq := r.Frequency.Max; freq > 0 {
+			value += fmt.Sprintf(" @ %.2f GHz", freq/1000)
+		}
+		return []string{keyValue(theme, m.Type, value)}
+	case "Memory", "Swap":
+		var r struct{ Total, Used uint64 }
+		if json.Unmarshal(m.Result, &r) != nil || r.Total == 0 {
+			return nil
+		}
+		return []string{keyValue(theme, m.Type, formatUsage(r.Used, r.Total))}
+	case "Disk":
+		var disks []struct {
+			Mountpoint string
+			Bytes      struct{ Total, Used uint64 }
+		}
+		if json.Unmarshal(m.Result, &disks) != nil {
+			return nil
+		}
+		var lines []string
+		for _, d := range disks {
+			if d.Bytes.Total > 0 {
+				lines = append(lines, keyValue(theme, "Disk ("+d.Mountpoint+")", formatUsage(d.Bytes.Used, d.Bytes.Total)))
+			}
+		}
+		return lines
+	case "Packages":
+		var r map[string]interface{}
+		if json.Unmarshal(m.Result, &r) != nil {
+			return nil
+		}
+		if all, ok := r["all"].(float64); ok && all > 0 {
+			return []string{keyValue(theme, m.Type, fmt.Sprint(all))}
+		}
+		return nil
+	}
+
+	// Everything else by the fields most modules have in common, lists
+	// get a line per entry
+	var list []json.RawMessage
+	if json.Unmarshal(m.Result, &list) != nil {
+		list = []json.RawMessage{m.Result}
+	}
+	var lines []string
+	for _, item := range list {
+		if value := fastfetchValue(item); value != "" {
+			lines = append(lines, keyValue(theme, m.Type, value))
+		}
+	}
+	return lines
+}
+
+// fastfetchValue finds something to show in a result fastfetchModuleLines
+// doesn't know: a plain string or number, or the name in an object
+func fastfetchValue(raw json.RawMessage) string {
+	var value interface{}
+	if json.Unmarshal(raw, &value) != nil {
+		return ""
+	}
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprint(v)
+	case map[string]interface{}:
+		name := ""
+		for _, field := range []string{"prettyName", "name", "result"} {
+			if s, ok := v[field].(string); ok && s != "" {
+				name = s
+				break
+			}
+		}
+		for _, field := range []string{"version", "release", "architecture"} {
+			if s, ok := v[field].(string); ok && s != "" && name != "" && !strings.Contains(name, s) {
+				name += " " + s
+			}
+		}
+		return name
+	}
+	return ""
+}
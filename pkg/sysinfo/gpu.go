@@ -0,0 +1,204 @@
+package sysinfo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Where Linux lists the PCI devices
+var pciDevicesDir = "/sys/bus/pci/devices"
+
+// Copies of the PCI ID database, for the names of vendors and devices
+var pciIDsPaths = []string{"/usr/share/hwdata/pci.ids", "/usr/share/misc/pci.ids", "/usr/share/pci.ids"}
+
+// Vendors of graphics chips by PCI vendor ID, for when there is no pci.ids
+var gpuVendors = map[string]string{
+	"10de": "NVIDIA", "1002": "AMD", "8086": "Intel", "1af4": "Virtio",
+	"15ad": "VMware", "1234": "QEMU", "1414": "Microsoft", "80ee": "VirtualBox",
+}
+
+// gpu is a graphics card or chip
+type gpu struct {
+	vendor, model string
+	vram          uint64 // Bytes, 0 when unknown
+}
+
+func (g gpu) String() string {
+	s := strings.TrimSpace(g.vendor + " " + g.model)
+	if g.vram > 0 {
+		s += " (" + formatBytes(g.vram) + ")"
+	}
+	return s
+}
+
+// collectGPU shows every GPU on a line of its own
+func collectGPU() (string, error) {
+	var gpus []gpu
+	var err error
+	switch runtime.GOOS {
+	case "linux":
+		gpus, err = linuxGPUs()
+	case "darwin":
+		gpus, err = macGPUs()
+	default:
+		err = errors.New("gpu detection is not supported on this system")
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(gpus) == 0 {
+		return "", errors.New("no gpu found")
+	}
+	names := make([]string, len(gpus))
+	for i, g := range gpus {
+		names[i] = g.String()
+	}
+	return strings.Join(names, "\n"), nil
+}
+
+// linuxGPUs finds the display controllers on the PCI bus, PCI class 0x03
+func linuxGPUs() ([]gpu, error) {
+	devices, err := filepath.Glob(filepath.Join(pciDevicesDir, "*"))
+	if err != nil {
+		return nil, err
+	}
+	read := func(dir, name string) string {
+		data, _ := os.ReadFile(filepath.Join(dir, name))
+		return strings.TrimPrefix(strings.TrimSpace(string(data)), "0x")
+	}
+	var gpus []gpu
+	for _, dir := range devices {
+		if !strings.HasPrefix(read(dir, "class"), "03") {
+			continue
+		}
+		vendorID, deviceID := read(dir, "vendor"), read(dir, "device")
+		g := gpu{vendor: gpuVendors[vendorID]}
+		if vendor, model, ok := lookupPCIIDs(vendorID, deviceID); ok {
+			g.model = model
+			if g.vendor == "" {
+				g.vendor = vendor
+			}
+		} else if vendor, model, ok := lspciNames(filepath.Base(dir)); ok {
+			g.model = shortGPUModel(model)
+			if g.vendor == "" {
+				g.vendor = vendor
+			}
+		} else {
+			g.model = "device " + deviceID
+		}
+		if g.vendor == "" {
+			g.vendor = "vendor " + vendorID
+		}
+		// AMD drivers tell how much video memory there is
+		if vram, err := strconv.ParseUint(read(dir, "mem_info_vram_total"), 10, 64); err == nil {
+			g.vram = vram
+		}
+		gpus = append(gpus, g)
+	}
+	return gpus, nil
+}
+
+// lookupPCIIDs finds the names of a vendor and device in pci.ids. Vendors
+// start a line, their devices follow indented by a tab:
+//
+//	10de  NVIDIA Corporation
+//		2484  GA104 [GeForce RTX 3070]
+func lookupPCIIDs(vendorID, deviceID string) (vendor, model string, ok bool) {
+	for _, path := range pciIDsPaths {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		inVendor := false
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "#") || line == "":
+			case !strings.HasPrefix(line, "\t"):
+				if inVendor {
+					return vendor, "", false
+				}
+				if strings.HasPrefix(line, vendorID+"  ") {
+					inVendor, vendor = true, strings.TrimSpace(line[len(vendorID):])
+				}
+			case inVendor && strings.HasPrefix(line, "\t"+deviceID+"  "):
+				return vendor, shortGPUModel(strings.TrimSpace(line[len(deviceID)+1:])), true
+			}
+		}
+		return "", "", false
+	}
+	return "", "", false
+}
+
+// lspciNames asks lspci for the names of the device in slot, for systems
+// that keep the PCI ID database elsewhere. Its machine readable output is
+// quoted fields:
+//
+//	00:02.0 "VGA compatible controller" "Intel Corporation" "UHD Graphics 620" ...
+func lspciNames(slot string) (vendor, model string, ok bool) {
+	out, err := commandOutput("lspci", "-mm", "-s", slot)
+	if err != nil {
+		return "", "", false
+	}
+	fields := strings.Split(out, `"`)
+	if len(fields) < 6 || fields[5] == "" {
+		return "", "", false
+	}
+	return fields[3], fields[5], true
+}
+
+// shortGPUModel keeps the marketing name of models like
+// "GA104 [GeForce RTX 3070]"
+func shortGPUModel(model string) string {
+	if start := strings.Index(model, "["); start >= 0 && strings.HasSuffix(model, "]") {
+		return model[start+1 : len(model)-1]
+	}
+	return model
+}
+
+// macGPUs asks system_profiler for the graphics chips Metal knows about
+func macGPUs() ([]gpu, error) {
+	out, err := commandOutput("system_profiler", "SPDisplaysDataType")
+	if err != nil {
+		return nil, err
+	}
+	var gpus []gpu
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case key == "Chipset Model":
+			gpus = append(gpus, gpu{model: value})
+		case key == "Vendor" && len(gpus) > 0:
+			// "Apple (0x106b)" or "sppci_vendor_Apple"
+			vendor, _, _ := strings.Cut(strings.TrimPrefix(value, "sppci_vendor_"), " (")
+			if !strings.HasPrefix(gpus[len(gpus)-1].model, vendor) {
+				gpus[len(gpus)-1].vendor = vendor
+			}
+		case strings.HasPrefix(key, "VRAM") && len(gpus) > 0:
+			var n uint64
+			var unit string
+			if _, err := fmt.Sscanf(value, "%d %s", &n, &unit); err == nil {
+				switch unit {
+				case "GB":
+					n <<= 30
+				case "MB":
+					n <<= 20
+				}
+				gpus[len(gpus)-1].vram = n
+			}
+		}
+	}
+	return gpus, nil
+}
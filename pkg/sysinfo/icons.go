@@ -0,0 +1,136 @@
+package sysinfo
+
+import (
+	"strings"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/term"
+)
+
+// Icon sets of -icons
+const (
+	IconsNone  = "none"  // Keys without icons
+	IconsNerd  = "nerd"  // Nerd Font glyphs, needs a patched font
+	IconsEmoji = "emoji" // Emoji most fonts have
+	IconsASCII = "ascii" // Plain characters for any terminal
+)
+
+// Icons is the set of icons put in front of the keys, set it before
+// collecting. Templates use it for {icon.<module>} too.
+var Icons = IconsNone
+
+// Icons of every module by set
+var iconSets = map[string]map[string]string{
+	IconsNerd: {
+		"title":     "", // nf-fa-user
+		"os":        "", // nf-fa-linux
+		"kernel":    "", // nf-fa-gear
+		"hostname":  "", // nf-fa-desktop
+		"uptime":    "", // nf-fa-hourglass_half
+		"shell":     "", // nf-fa-terminal
+		"terminal":  "", // nf-oct-terminal
+		"cpu":       "", // nf-oct-cpu
+		"gpu":       "󰢮", // nf-md-expansion_card
+		"memory":    "", // nf-fa-memory
+		"network":   "󰌗", // nf-md-lan
+		"public-ip": "", // nf-fa-globe
+		"net-speed": "󰛳", // nf-md-swap_vertical
+		"weather":   "󰖙", // nf-md-weather_partly_cloudy
+		"media":     "",  // nf-fa-music
+		"disk":      "", // nf-fa-hdd_o
+		"swap":      "", // nf-fa-exchange
+		"cpu-usage": "", // nf-oct-cpu
+		"temp":      "", // nf-fa-thermometer_half
+		"load":      "", // nf-fa-dashboard
+		"time":      "", // nf-fa-clock_o
+	},
+	IconsEmoji: {
+		"title": "👤", "os": "🐧", "kernel": "🧩", "hostname": "🏠",
+		"uptime": "⏳", "shell": "🐚", "terminal": "💻", "cpu": "🧠",
+		"gpu": "🎮", "memory": "💾", "network": "🌐", "public-ip": "🌍",
+		"net-speed": "📶", "weather": "⛅", "media": "🎵", "disk": "💽",
+		"swap": "🔁", "cpu-usage": "📈", "temp": "🔥", "load": "📊",
+		"time": "🕒",
+	},
+	IconsASCII: {
+		"title": "@", "os": "#", "kernel": "%", "hostname": "~", "uptime": "+", "shell": "$",
+		"terminal": ">", "cpu": "&", "gpu": "&", "memory": "=", "network": "^", "public-ip": "^",
+		"net-speed": "^", "weather": "*", "media": "~", "disk": "=", "swap": "=", "cpu-usage": "&",
+		"temp": "!", "load": "&", "time": "+",
+	},
+}
+
+// valueIcon is the icon of a value that contains match, in lowercase
+type valueIcon struct {
+	match, icon string
+}
+
+// Icons of what a module found, e.g. the distribution, checked in order
+// before the icon of the module itself
+var valueIcons = map[string]map[string][]valueIcon{
+	IconsNerd: {
+		"os": {
+			{"arch", ""},        // nf-linux-archlinux
+			{"endeavouros", ""}, // nf-linux-endeavour
+			{"manjaro", ""},     // nf-linux-manjaro
+			{"ubuntu", ""},      // nf-linux-ubuntu
+			{"pop!_os", ""},     // nf-linux-pop_os
+			{"mint", ""},        // nf-linux-linuxmint
+			{"elementary", ""},  // nf-linux-elementary
+			{"kali", ""},        // nf-linux-kali_linux
+			{"raspbian", ""},    // nf-linux-raspberry_pi
+			{"debian", ""},      // nf-linux-debian
+			{"fedora", ""},      // nf-linux-fedora
+			{"centos", ""},      // nf-linux-centos
+			{"opensuse", ""},    // nf-linux-opensuse
+			{"gentoo", ""},      // nf-linux-gentoo
+			{"alpine", ""},      // nf-linux-alpine
+			{"nixos", ""},       // nf-linux-nixos
+			{"freebsd", ""},     // nf-linux-freebsd
+			{"macos", ""},       // nf-fa-apple
+			{"windows", ""},     // nf-fa-windows
+		},
+		"shell": {
+			{"fish", "󰈺"},       // nf-md-fish
+			{"pwsh", "󰨊"},       // nf-md-powershell
+			{"powershell", "󰨊"}, // nf-md-powershell
+		},
+	},
+	IconsEmoji: {
+		"os": {
+			{"macos", "🍎"},
+			{"windows", "🪟"},
+			{"freebsd", "😈"},
+		},
+		"shell": {
+			{"fish", "🐟"},
+		},
+	},
+}
+
+// keyIcon returns the icon of Icons for module name that found value,
+// empty without one
+func keyIcon(name, value string) string {
+	value = strings.ToLower(value)
+	for _, v := range valueIcons[Icons][name] {
+		if strings.Contains(value, v.match) {
+			return v.icon
+		}
+	}
+	return iconSets[Icons][name]
+}
+
+// styledKey returns the key and delimiter styled with theme, after the icon
+// of module name for value and padded to theme.KeyWidth
+func styledKey(theme Theme, name, key, value string) string {
+	if theme.UpperKeys {
+		key = strings.ToUpper(key)
+	}
+	if icon := keyIcon(name, value); icon != "" {
+		key = icon + " " + key
+	}
+	padding := ""
+	if width := term.VisibleWidth(key + theme.Delimiter); width < theme.KeyWidth {
+		padding = strings.Repeat(" ", theme.KeyWidth-width)
+	}
+	return style(theme.Key, key) + style(theme.Separator, theme.Delimiter) + padding
+}
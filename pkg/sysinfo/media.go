@@ -0,0 +1,139 @@
+package sysinfo
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// MediaOptions configures the media module
+type MediaOptions struct {
+	// Longest the song may be in characters, longer ones scroll by a step
+	// every time the module is collected. 0 = no limit.
+	Width int
+}
+
+// Media configures the media module, set it before collecting
+var Media = MediaOptions{Width: 40}
+
+// Prefix of the D-Bus names of MPRIS media players
+const mprisPrefix = "org.mpris.MediaPlayer2."
+
+// Where the marquee of a long song is, and which song that is
+var (
+	mediaMu     sync.Mutex
+	mediaSong   string
+	mediaScroll int
+)
+
+// collectMedia shows "artist - title" of the player that is playing, or
+// of the first one there is. Songs longer than Media.Width scroll.
+func collectMedia() (string, error) {
+	song, err := mprisSong()
+	if err != nil {
+		// Not every system has dbus-send, playerctl asks the same players
+		if song, err = commandOutput("playerctl", "metadata", "--format", "{{artist}} - {{title}}"); err != nil {
+			return "", err
+		}
+	}
+	song = strings.Trim(strings.TrimSpace(song), "- ")
+	if song == "" {
+		return "", errors.New("nothing playing")
+	}
+	return marquee(song), nil
+}
+
+// marquee returns the part of song that is shown now, moving on a step
+// every call when it doesn't fit in Media.Width
+func marquee(song string) string {
+	runes := []rune(song)
+	if Media.Width <= 0 || len(runes) <= Media.Width {
+		return song
+	}
+	mediaMu.Lock()
+	defer mediaMu.Unlock()
+	if song != mediaSong {
+		mediaSong, mediaScroll = song, 0
+	}
+	loop := append(runes, []rune(" · ")...)
+	window := make([]rune, Media.Width)
+	for i := range window {
+		window[i] = loop[(mediaScroll+i)%len(loop)]
+	}
+	mediaScroll = (mediaScroll + 1) % len(loop)
+	return string(window)
+}
+
+// mprisSong asks the MPRIS players on the session bus for their song
+// through dbus-send
+func mprisSong() (string, error) {
+	out, err := commandOutput("dbus-send", "--session", "--print-reply", "--dest=org.freedesktop.DBus",
+		"/org/freedesktop/DBus", "org.freedesktop.DBus.ListNames")
+	if err != nil {
+		return "", err
+	}
+	var players []string
+	for _, s := range dbusStrings(out) {
+		if strings.HasPrefix(s, mprisPrefix) {
+			players = append(players, s)
+		}
+	}
+	if len(players) == 0 {
+		return "", errors.New("no media player")
+	}
+
+	property := func(player, name string) []string {
+		out, err := commandOutput("dbus-send", "--session", "--print-reply", "--dest="+player,
+			"/org/mpris/MediaPlayer2", "org.freedesktop.DBus.Properties.Get",
+			"string:org.mpris.MediaPlayer2.Player", "string:"+name)
+		if err != nil {
+			return nil
+		}
+		return dbusStrings(out)
+	}
+	chosen := players[0]
+	for _, player := range players {
+		if status := property(player, "PlaybackStatus"); len(status) > 0 && status[0] == "Playing" {
+			chosen = player
+			break
+		}
+	}
+
+	// The metadata is a dict of "xesam:title" to a string, "xesam:artist" to
+	// a list of them. Every string follows the key it belongs to.
+	var artists []string
+	title := ""
+	key := ""
+	for _, s := range property(chosen, "Metadata") {
+		switch {
+		case strings.HasPrefix(s, "xesam:") || strings.HasPrefix(s, "mpris:"):
+			key = s
+		case key == "xesam:title":
+			title, key = s, ""
+		case key == "xesam:artist":
+			artists = append(artists, s)
+		}
+	}
+	if title == "" {
+		return "", errors.New("nothing playing")
+	}
+	if len(artists) == 0 {
+		return title, nil
+	}
+	return strings.Join(artists, ", ") + " - " + title, nil
+}
+
+// dbusStrings picks the strings out of dbus-send --print-reply output,
+// lines like `string "org.mpris.MediaPlayer2.spotify"`
+func dbusStrings(out string) []string {
+	var strs []string
+	for _, line := range strings.Split(out, "\n") {
+		i := strings.Index(line, `string "`)
+		if i < 0 {
+			continue
+		}
+		s := line[i+len(`string "`):]
+		strs = append(strs, strings.TrimSuffix(strings.TrimRight(s, " "), `"`))
+	}
+	return strs
+}
@@ -0,0 +1,181 @@
+package sysinfo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NetworkOptions configures the network modules
+type NetworkOptions struct {
+	// Address that answers with the public IP as plain text. The public-ip
+	// module stays empty without one, so nothing is sent anywhere unless
+	// asked for.
+	PublicIPURL string
+}
+
+// Network configures the network modules, set it before collecting
+var Network NetworkOptions
+
+// How long the public IP endpoint gets to answer
+const publicIPTimeout = 3 * time.Second
+
+// activeInterface returns the interface of the default route, or the first
+// one that is up and has an address where there is no routing table to read
+func activeInterface() (*net.Interface, error) {
+	if data, err := os.ReadFile("/proc/net/route"); err == nil {
+		// "Iface Destination Gateway ...", the default route goes to 00000000
+		for _, line := range strings.Split(string(data), "\n")[1:] {
+			fields := strings.Fields(line)
+			if len(fields) > 1 && fields[1] == "00000000" {
+				return net.InterfaceByName(fields[0])
+			}
+		}
+	}
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	for i, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		if addrs, err := iface.Addrs(); err == nil && len(addrs) > 0 {
+			return &ifaces[i], nil
+		}
+	}
+	return nil, errors.New("no active network interface")
+}
+
+// collectNetwork shows the active interface with its IPv4 and IPv6
+// addresses, "eth0 192.168.1.20, 2a02:1810::1"
+func collectNetwork() (string, error) {
+	iface, err := activeInterface()
+	if err != nil {
+		return "", err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", err
+	}
+	var v4, v6 []string
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			v4 = append(v4, ipNet.IP.String())
+		} else {
+			v6 = append(v6, ipNet.IP.String())
+		}
+	}
+	if len(v4)+len(v6) == 0 {
+		return iface.Name, nil
+	}
+	return iface.Name + " " + strings.Join(append(v4, v6...), ", "), nil
+}
+
+// collectPublicIP asks Network.PublicIPURL for the address the internet sees
+func collectPublicIP() (string, error) {
+	if Network.PublicIPURL == "" {
+		return "", errors.New("no public IP endpoint set")
+	}
+	client := http.Client{Timeout: publicIPTimeout}
+	resp, err := client.Get(Network.PublicIPURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", Network.PublicIPURL, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return "", err
+	}
+	ip := strings.TrimSpace(string(body))
+	if net.ParseIP(ip) == nil {
+		return "", fmt.Errorf("%s: no IP address in the answer", Network.PublicIPURL)
+	}
+	return ip, nil
+}
+
+// netBytes is what an interface received and sent, at a moment
+type netBytes struct {
+	rx, tx uint64
+	at     time.Time
+}
+
+// The counters of the last throughput measurement by interface, live
+// updates measure the time since then
+var (
+	lastNetMu      sync.Mutex
+	lastNetSamples = map[string]netBytes{}
+)
+
+// readNetBytes reads the counters of iface from /proc/net/dev
+func readNetBytes(iface string) (netBytes, error) {
+	data, err := os.ReadFile("/proc/net/dev")
+	if err != nil {
+		return netBytes{}, err
+	}
+	// "  eth0: rxbytes rxpackets ... (8 fields) txbytes ..."
+	for _, line := range strings.Split(string(data), "\n") {
+		name, counters, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(name) != iface {
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) < 9 {
+			break
+		}
+		rx, err1 := strconv.ParseUint(fields[0], 10, 64)
+		tx, err2 := strconv.ParseUint(fields[8], 10, 64)
+		if err1 != nil || err2 != nil {
+			break
+		}
+		return netBytes{rx: rx, tx: tx, at: time.Now()}, nil
+	}
+	return netBytes{}, fmt.Errorf("no counters for %s in /proc/net/dev", iface)
+}
+
+// collectNetSpeed shows how fast the active interface receives and sends,
+// since the last time it was asked or over a short moment the first time
+func collectNetSpeed() (string, error) {
+	if runtime.GOOS != "linux" {
+		return "", errors.New("network throughput is unknown on this system")
+	}
+	iface, err := activeInterface()
+	if err != nil {
+		return "", err
+	}
+	lastNetMu.Lock()
+	defer lastNetMu.Unlock()
+	prev, ok := lastNetSamples[iface.Name]
+	if !ok {
+		if prev, err = readNetBytes(iface.Name); err != nil {
+			return "", err
+		}
+		time.Sleep(cpuSampleTime)
+	}
+	now, err := readNetBytes(iface.Name)
+	if err != nil {
+		return "", err
+	}
+	lastNetSamples[iface.Name] = now
+	seconds := now.at.Sub(prev.at).Seconds()
+	if seconds <= 0 || now.rx < prev.rx || now.tx < prev.tx {
+		return "", errors.New("network counters went back")
+	}
+	rate := func(n uint64) string { return formatBytes(uint64(float64(n)/seconds)) + "/s" }
+	return "↓ " + rate(now.rx-prev.rx) + "  ↑ " + rate(now.tx-prev.tx), nil
+}
@@ -0,0 +1,76 @@
+package sysinfo
+
+import "strings"
+
+// Styles of the colors module
+const (
+	PaletteBlocks   = "blocks"   // Boxes of three spaces, as neofetch draws them
+	PaletteCircles  = "circles"  // ●
+	PaletteSquares  = "squares"  // ■
+	PaletteLines    = "lines"    // ▂▂▂
+	PaletteGradient = "gradient" // One column per color, without gaps
+)
+
+// Glyphs of the styles drawn in the foreground color
+var paletteGlyphs = map[string]string{
+	PaletteCircles: "● ",
+	PaletteSquares: "■ ",
+	PaletteLines:   "▂▂▂",
+}
+
+// Number of colors on a row of the colors module, 16 colors become the
+// normal and the bright row
+const paletteRow = 8
+
+// PaletteOptions configures the colors module
+type PaletteOptions struct {
+	Style string
+}
+
+// Palette configures the colors module, set it before collecting
+var Palette = PaletteOptions{Style: PaletteBlocks}
+
+// ValidatePaletteStyle checks a style of the colors module
+func ValidatePaletteStyle(style string) bool {
+	_, ok := paletteGlyphs[style]
+	return ok || style == PaletteBlocks || style == PaletteGradient
+}
+
+// colorBar draws the blocks of theme in Palette.Style after an empty line,
+// like other fetchers end with
+func colorBar(theme Theme) []string {
+	if len(theme.Blocks) == 0 {
+		return nil
+	}
+	lines := []string{""}
+	var bar strings.Builder
+	for i, block := range theme.Blocks {
+		if i > 0 && i%paletteRow == 0 && Palette.Style != PaletteGradient {
+			lines = append(lines, bar.String())
+			bar.Reset()
+		}
+		switch Palette.Style {
+		case PaletteGradient:
+			bar.WriteString(style(block, " "))
+		case PaletteBlocks:
+			bar.WriteString(style(block, "   "))
+		default:
+			bar.WriteString(style(foreground(block), paletteGlyphs[Palette.Style]))
+		}
+	}
+	return append(lines, bar.String())
+}
+
+// foreground turns the SGR parameters of a background color into those of
+// the same color as foreground
+func foreground(params string) string {
+	switch {
+	case strings.HasPrefix(params, "48;"):
+		return "38;" + params[3:]
+	case len(params) == 2 && params[0] == '4':
+		return "3" + params[1:]
+	case len(params) == 3 && strings.HasPrefix(params, "10"):
+		return "9" + params[2:]
+	}
+	return params
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !freebsd && !openbsd && !netbsd && !dragonfly
+
+package sysinfo
+
+import "context"
+
+// runInPTY isn't available on this platform, the info command runs through
+// `script` or `unbuffer` instead
+func runInPTY(ctx context.Context, name string, args ...string) (string, error) {
+	return "", errPTYUnsupported
+}
@@ -0,0 +1,52 @@
+//go:build linux || darwin || freebsd || openbsd || netbsd || dragonfly
+
+package sysinfo
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/creack/pty"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/term"
+)
+
+// runInPTY runs a command with a new pseudo-terminal as its stdin and
+// stdout, so it behaves like it would in a real terminal (colors included),
+// and returns what it printed. Stderr is thrown away.
+func runInPTY(ctx context.Context, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), "TERM=xterm-256color")
+
+	// Same size as our own terminal, fetchers cut lines to fit
+	var size *pty.Winsize
+	if cols, rows, err := term.Size(os.Stdout); err == nil {
+		size = &pty.Winsize{Rows: uint16(rows), Cols: uint16(cols)}
+	}
+	master, err := pty.StartWithSize(cmd, size)
+	if err != nil {
+		return "", err
+	}
+	defer master.Close()
+
+	var out bytes.Buffer
+	copied := make(chan struct{})
+	go func() {
+		io.Copy(&out, master) // Ends with EIO when the child closed its end
+		close(copied)
+	}()
+
+	err = cmd.Wait()
+	select {
+	case <-copied:
+	case <-time.After(time.Second):
+		// Something the command started still holds the pty open
+		master.Close()
+		<-copied
+	}
+	return out.String(), err
+}
@@ -0,0 +1,65 @@
+package sysinfo
+
+import (
+	"strings"
+	"sync"
+)
+
+// Source is one of the commands whose output is shown in the sysinfo column
+type Source struct {
+	Command string
+	Default bool   // The default command, which falls back to the built-in modules
+	Offset  int    // Spaces in front of every line
+	Color   string // SGR parameters the lines are drawn in, empty keeps their own
+	Format  string // What the command prints, FormatText when empty
+}
+
+// Merge gathers the lines of all sources at the same time and puts them
+// below each other, in order, with a separator line in between. The error
+// is the first one of a source that failed, its lines are the error
+// message in that case.
+func Merge(sources []Source, modules string, theme Theme, separator string, opts CommandOptions) ([]string, error) {
+	parts := make([][]string, len(sources))
+	errs := make([]error, len(sources))
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func(i int, source Source) {
+			defer wg.Done()
+			sourceOpts := opts
+			sourceOpts.Format = source.Format
+			parts[i], errs[i] = Lines(source.Command, modules, source.Default, theme, sourceOpts)
+			parts[i] = source.apply(parts[i])
+		}(i, source)
+	}
+	wg.Wait()
+
+	var lines []string
+	var firstErr error
+	for i, part := range parts {
+		if errs[i] != nil && firstErr == nil {
+			firstErr = errs[i]
+		}
+		if len(part) == 0 {
+			continue
+		}
+		if len(lines) > 0 {
+			lines = append(lines, separator)
+		}
+		lines = append(lines, part...)
+	}
+	return lines, firstErr
+}
+
+// apply indents and colors the lines of the source. Resets within a line
+// go back to the source's color instead of the terminal's.
+func (s Source) apply(lines []string) []string {
+	indent := strings.Repeat(" ", s.Offset)
+	for i, line := range lines {
+		if s.Color != "" {
+			line = "\x1b[" + s.Color + "m" + strings.ReplaceAll(line, sysInfoReset, "\x1b[0;"+s.Color+"m") + sysInfoReset
+		}
+		lines[i] = indent + line
+	}
+	return lines
+}
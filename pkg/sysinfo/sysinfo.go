@@ -0,0 +1,626 @@
+// Package sysinfo collects the lines shown next to the art, either from an
+// external fetcher run under a pseudo-terminal or from the built-in modules.
+package sysinfo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/term"
+)
+
+// -info value that selects the built-in modules instead of a command
+const Native = "native"
+
+// Modules shown by default when using the built-in sysinfo
+const DefaultModules = "title,os,kernel,hostname,uptime,shell,terminal,cpu,memory"
+
+// ANSI styling of errors in place of the sysinfo
+const (
+	sysInfoErrorColor = "\x1b[1;31m"
+	sysInfoReset      = "\x1b[0m"
+)
+
+// Theme is the styling of the built-in sysinfo, as SGR parameters like
+// "1;34". Empty ones leave the text as it is.
+type Theme struct {
+	Title     string
+	Key       string
+	Value     string
+	Separator string // The delimiter after keys and the line under the title
+
+	Delimiter string // Put between keys and values, before a space
+	KeyWidth  int    // Columns the keys with their delimiter are padded to, so the values line up
+	UpperKeys bool   // Keys in capitals
+
+	// Background colors of the blocks the colors module shows, 8 on a row
+	Blocks []string
+}
+
+// DefaultTheme styles the sysinfo in the terminal's blue, the colors module
+// shows its 8 basic colors
+var DefaultTheme = Theme{
+	Title:     "1;34",
+	Key:       "1;34",
+	Delimiter: ":",
+	Blocks:    []string{"40", "41", "42", "43", "44", "45", "46", "47"},
+}
+
+// style wraps text in the SGR parameters
+func style(params, text string) string {
+	if params == "" {
+		return text
+	}
+	return "\x1b[" + params + "m" + text + sysInfoReset
+}
+
+// A Module collects one piece of system information natively, without
+// depending on an external fetcher.
+type Module struct {
+	Key     string
+	Collect func() (string, error)
+}
+
+// Built-in modules that aren't a single key and value, collectModule
+// handles them itself
+var specialModules = map[string]bool{"title": true, "colors": true, "disk": true}
+
+// All built-in modules by the name used in -modules
+var sysInfoModules = map[string]Module{
+	"os":        {Key: "OS", Collect: collectOS},
+	"kernel":    {Key: "Kernel", Collect: collectKernel},
+	"hostname":  {Key: "Hostname", Collect: os.Hostname},
+	"uptime":    {Key: "Uptime", Collect: collectUptime},
+	"shell":     {Key: "Shell", Collect: collectShell},
+	"terminal":  {Key: "Terminal", Collect: collectTerminal},
+	"cpu":       {Key: "CPU", Collect: collectCPU},
+	"gpu":       {Key: "GPU", Collect: collectGPU},
+	"network":   {Key: "Network", Collect: collectNetwork},
+	"public-ip": {Key: "Public IP", Collect: collectPublicIP},
+	"net-speed": {Key: "Traffic", Collect: collectNetSpeed},
+	"media":     {Key: "Media", Collect: collectMedia},
+	"weather":   {Key: "Weather", Collect: collectWeather},
+	"cpu-usage": {Key: "CPU Usage", Collect: collectCPUUsage},
+	"temp":      {Key: "Temp", Collect: collectTemperature},
+	"memory":    {Key: "Memory", Collect: collectMemory},
+	"swap":      {Key: "Swap", Collect: collectSwap},
+	"load":      {Key: "Load", Collect: collectLoad},
+	"time":      {Key: "Time", Collect: collectTime},
+}
+
+// AddModule makes a module of its own available under name, e.g. one made
+// with CommandModule. Built-in modules can't be replaced.
+func AddModule(name string, module Module) error {
+	if _, ok := sysInfoModules[name]; ok || specialModules[name] {
+		return fmt.Errorf("module %q already exists", name)
+	}
+	if name == "" || strings.Contains(name, ",") {
+		return fmt.Errorf("%q can't be used as a module name", name)
+	}
+	sysInfoModules[name] = module
+	return nil
+}
+
+// ValidateModules checks a comma separated module list before anything runs
+func ValidateModules(list string) error {
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := sysInfoModules[name]; !ok && !specialModules[name] && name != "" {
+			return fmt.Errorf("unknown module %q", name)
+		}
+	}
+	return nil
+}
+
+// Collect runs the modules in the given order and formats them as
+// "Key: value" lines styled with theme. Modules that can't find their
+// information on this system are left out.
+func Collect(list string, theme Theme) []string {
+	var lines []string
+	for _, name := range strings.Split(list, ",") {
+		lines = append(lines, collectModule(strings.TrimSpace(name), theme)...)
+	}
+	return lines
+}
+
+// collectModule returns the lines of a single module, none when it has
+// nothing to show
+func collectModule(name string, theme Theme) []string {
+	switch name {
+	case "title":
+		title := collectTitle()
+		return []string{style(theme.Title, title), style(theme.Separator, strings.Repeat("-", len(title)))}
+	case "colors":
+		return colorBar(theme)
+	case "disk":
+		return collectDisks(theme)
+	}
+
+	module, ok := sysInfoModules[name]
+	if !ok {
+		return nil
+	}
+	value, err := module.Collect()
+	if err != nil || value == "" {
+		return nil
+	}
+	// Values of several lines continue below the start of the first one,
+	// modules without a key are shown as they are
+	values := strings.Split(value, "\n")
+	if module.Key == "" {
+		return values
+	}
+	key := styledKey(theme, name, module.Key, values[0])
+	lines := []string{key + " " + style(theme.Value, values[0])}
+	indent := strings.Repeat(" ", term.VisibleWidth(key)+1)
+	for _, v := range values[1:] {
+		lines = append(lines, indent+style(theme.Value, v))
+	}
+	return lines
+}
+
+// Watch collects the modules in list again every interval and sends
+// the new lines to updates. Only the modules in live are run again, the
+// others keep the lines they had the first time.
+func Watch(list, live string, interval time.Duration, theme Theme, updates chan []string) {
+	isLive := map[string]bool{}
+	for _, name := range strings.Split(live, ",") {
+		isLive[strings.TrimSpace(name)] = true
+	}
+
+	names := strings.Split(list, ",")
+	parts := make([][]string, len(names))
+	for i, name := range names {
+		names[i] = strings.TrimSpace(name)
+		parts[i] = collectModule(names[i], theme)
+	}
+
+	for range time.Tick(interval) {
+		var lines []string
+		for i, name := range names {
+			if isLive[name] {
+				parts[i] = collectModule(name, theme)
+			}
+			lines = append(lines, parts[i]...)
+		}
+
+		// Drop an update the player didn't pick up yet, this one is newer
+		select {
+		case <-updates:
+		default:
+		}
+		updates <- lines
+	}
+}
+
+// Lines gets the sysinfo either from the external command or from the
+// built-in modules. Those, and the output of a command in
+// FormatFastfetchJSON, are styled with theme. When the command fails or
+// takes longer than its timeout the lines are a short error message instead.
+func Lines(infoCommand, modules string, commandIsDefault bool, theme Theme, opts CommandOptions) ([]string, error) {
+	if UsesNative(infoCommand, commandIsDefault) {
+		return Collect(modules, theme), nil
+	}
+	var lines []string
+	var err error
+	if opts.Format == FormatFastfetchJSON {
+		lines, err = fastfetchJSONLines(infoCommand, theme, opts)
+	} else {
+		lines, err = getCommandOutputLines(infoCommand, opts)
+	}
+	if err != nil {
+		return []string{sysInfoErrorColor + "Error" + sysInfoReset + ": " + err.Error()}, err
+	}
+	return lines, nil
+}
+
+// UsesNative tells whether the built-in modules provide the sysinfo. The
+// default fastfetch command falls back to them when fastfetch isn't
+// installed.
+func UsesNative(infoCommand string, commandIsDefault bool) bool {
+	if infoCommand == Native {
+		return true
+	}
+	if commandIsDefault {
+		if _, err := exec.LookPath("fastfetch"); err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func collectTitle() string {
+	host, _ := os.Hostname()
+	return userName() + "@" + host
+}
+
+func userName() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// DistroIDs names the running system for picking a logo, most specific
+// first: the ID of /etc/os-release followed by the distros of its ID_LIKE
+// (e.g. "linuxmint", "ubuntu", "debian"), "macos" or "windows"
+func DistroIDs() []string {
+	switch runtime.GOOS {
+	case "linux":
+		release, err := readKeyValueFile("/etc/os-release", "=")
+		if err != nil {
+			return nil
+		}
+		var ids []string
+		if release["ID"] != "" {
+			ids = append(ids, release["ID"])
+		}
+		return append(ids, strings.Fields(release["ID_LIKE"])...)
+	case "darwin":
+		return []string{"macos"}
+	default:
+		return []string{runtime.GOOS}
+	}
+}
+
+func collectOS() (string, error) {
+	name, err := osName()
+	if err != nil {
+		return "", err
+	}
+	return name + " " + runtime.GOARCH, nil
+}
+
+// osName returns the name and version of the system, without architecture
+func osName() (string, error) {
+	switch runtime.GOOS {
+	case "linux":
+		release, err := readKeyValueFile("/etc/os-release", "=")
+		if err != nil {
+			return "", err
+		}
+		name := release["PRETTY_NAME"]
+		if name == "" {
+			name = release["NAME"]
+		}
+		return name, nil
+	case "darwin":
+		name, err := commandOutput("sw_vers", "-productName")
+		if err != nil {
+			return "", err
+		}
+		version, _ := commandOutput("sw_vers", "-productVersion")
+		return name + " " + version, nil
+	default:
+		return runtime.GOOS, nil
+	}
+}
+
+func collectKernel() (string, error) {
+	if release, err := os.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+		return "Linux " + strings.TrimSpace(string(release)), nil
+	}
+	return commandOutput("uname", "-sr")
+}
+
+func collectUptime() (string, error) {
+	var seconds float64
+	switch runtime.GOOS {
+	case "linux":
+		data, err := os.ReadFile("/proc/uptime")
+		if err != nil {
+			return "", err
+		}
+		fields := strings.Fields(string(data))
+		if len(fields) == 0 {
+			return "", errors.New("empty /proc/uptime")
+		}
+		if seconds, err = strconv.ParseFloat(fields[0], 64); err != nil {
+			return "", err
+		}
+	default:
+		// BSDs and macOS: "{ sec = 1700000000, usec = 0 } Tue Nov ..."
+		out, err := commandOutput("sysctl", "-n", "kern.boottime")
+		if err != nil {
+			return "", err
+		}
+		var boot int64
+		if _, err := fmt.Sscanf(out, "{ sec = %d", &boot); err != nil {
+			return "", err
+		}
+		seconds = time.Since(time.Unix(boot, 0)).Seconds()
+	}
+	return formatUptime(time.Duration(seconds) * time.Second), nil
+}
+
+// formatUptime prints a duration the way fetchers usually do: "2 days, 3 hours, 4 mins"
+func formatUptime(d time.Duration) string {
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	mins := int(d.Minutes()) % 60
+
+	var parts []string
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("%d %s", n, unit)
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+	if days > 0 {
+		parts = append(parts, plural(days, "day"))
+	}
+	if hours > 0 {
+		parts = append(parts, plural(hours, "hour"))
+	}
+	if mins > 0 || len(parts) == 0 {
+		parts = append(parts, plural(mins, "min"))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func collectShell() (string, error) {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		return "", errors.New("SHELL is not set")
+	}
+	return filepath.Base(shell), nil
+}
+
+func collectTerminal() (string, error) {
+	for _, env := range []string{"TERM_PROGRAM", "TERMINAL", "TERM"} {
+		if value := os.Getenv(env); value != "" {
+			return value, nil
+		}
+	}
+	return "", errors.New("unknown terminal")
+}
+
+func collectCPU() (string, error) {
+	model, err := cpuModel()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s (%d)", model, runtime.NumCPU()), nil
+}
+
+// cpuModel returns the name of the processor
+func cpuModel() (string, error) {
+	var model string
+	switch runtime.GOOS {
+	case "linux":
+		info, err := readKeyValueFile("/proc/cpuinfo", ":")
+		if err != nil {
+			return "", err
+		}
+		model = info["model name"]
+		if model == "" {
+			model = info["Hardware"] // ARM boards
+		}
+	case "darwin":
+		model, _ = commandOutput("sysctl", "-n", "machdep.cpu.brand_string")
+	default:
+		model, _ = commandOutput("sysctl", "-n", "hw.model")
+	}
+	if model == "" {
+		return "", errors.New("unknown cpu")
+	}
+	return strings.Join(strings.Fields(model), " "), nil
+}
+
+// cpuFrequency returns the highest clock speed of the processor in MHz
+func cpuFrequency() (float64, error) {
+	switch runtime.GOOS {
+	case "linux":
+		if data, err := os.ReadFile("/sys/devices/system/cpu/cpu0/cpufreq/cpuinfo_max_freq"); err == nil {
+			if khz, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64); err == nil {
+				return khz / 1000, nil
+			}
+		}
+		// Virtual machines often have no cpufreq, the current speed will do
+		info, err := readKeyValueFile("/proc/cpuinfo", ":")
+		if err != nil {
+			return 0, err
+		}
+		return strconv.ParseFloat(info["cpu MHz"], 64)
+	default:
+		out, err := commandOutput("sysctl", "-n", "hw.cpufrequency")
+		if err != nil {
+			return 0, err
+		}
+		hz, err := strconv.ParseFloat(out, 64)
+		return hz / 1e6, err
+	}
+}
+
+func collectMemory() (string, error) {
+	used, total, err := memoryUsage()
+	if err == errUsageUnknown {
+		return formatBytes(total), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return formatUsage(used, total), nil
+}
+
+// Returned by memoryUsage on systems where only the total is known
+var errUsageUnknown = errors.New("memory usage is unknown on this system")
+
+// memoryUsage returns how much memory is in use and how much there is, in
+// bytes. Outside of Linux the total comes with errUsageUnknown.
+func memoryUsage() (used, total uint64, err error) {
+	switch runtime.GOOS {
+	case "linux":
+		info, err := readKeyValueFile("/proc/meminfo", ":")
+		if err != nil {
+			return 0, 0, err
+		}
+		total := parseKiB(info["MemTotal"])
+		available := parseKiB(info["MemAvailable"])
+		if total == 0 {
+			return 0, 0, errors.New("no MemTotal in /proc/meminfo")
+		}
+		return total - available, total, nil
+	default:
+		out, err := commandOutput("sysctl", "-n", "hw.memsize")
+		if err != nil {
+			out, err = commandOutput("sysctl", "-n", "hw.physmem")
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		total, err := strconv.ParseUint(out, 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		return 0, total, errUsageUnknown
+	}
+}
+
+func collectSwap() (string, error) {
+	used, total, err := swapUsage()
+	if err != nil {
+		return "", err
+	}
+	if total == 0 {
+		return "Disabled", nil
+	}
+	return formatUsage(used, total), nil
+}
+
+// swapUsage returns how much swap is in use and how much there is, in bytes
+func swapUsage() (used, total uint64, err error) {
+	switch runtime.GOOS {
+	case "linux":
+		info, err := readKeyValueFile("/proc/meminfo", ":")
+		if err != nil {
+			return 0, 0, err
+		}
+		if _, ok := info["SwapTotal"]; !ok {
+			return 0, 0, errors.New("no SwapTotal in /proc/meminfo")
+		}
+		total := parseKiB(info["SwapTotal"])
+		return total - parseKiB(info["SwapFree"]), total, nil
+	case "darwin":
+		// "total = 2048.00M  used = 1024.00M  free = 1024.00M  (encrypted)"
+		out, err := commandOutput("sysctl", "-n", "vm.swapusage")
+		if err != nil {
+			return 0, 0, err
+		}
+		var totalMiB, usedMiB float64
+		if _, err := fmt.Sscanf(out, "total = %fM used = %fM", &totalMiB, &usedMiB); err != nil {
+			return 0, 0, err
+		}
+		return uint64(usedMiB * 1024 * 1024), uint64(totalMiB * 1024 * 1024), nil
+	default:
+		return 0, 0, errors.New("swap usage is unknown on this system")
+	}
+}
+
+func collectLoad() (string, error) {
+	load, err := loadAverages()
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(load, " "), nil
+}
+
+// loadAverages returns the load averages of the last 1, 5 and 15 minutes
+func loadAverages() ([]string, error) {
+	var out string
+	switch runtime.GOOS {
+	case "linux":
+		data, err := os.ReadFile("/proc/loadavg")
+		if err != nil {
+			return nil, err
+		}
+		out = string(data)
+	default:
+		// BSDs and macOS: "{ 1.23 1.45 1.67 }"
+		var err error
+		if out, err = commandOutput("sysctl", "-n", "vm.loadavg"); err != nil {
+			return nil, err
+		}
+		out = strings.Trim(out, "{ }")
+	}
+	fields := strings.Fields(out)
+	if len(fields) < 3 {
+		return nil, errors.New("unexpected load average format")
+	}
+	return fields[:3], nil
+}
+
+func collectTime() (string, error) {
+	return time.Now().Format("15:04:05"), nil
+}
+
+// formatUsage prints "used / total (percent%)"
+func formatUsage(used, total uint64) string {
+	return fmt.Sprintf("%s / %s (%d%%)", formatBytes(used), formatBytes(total), used*100/total)
+}
+
+// formatBytes prints a size in binary units
+func formatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+// parseKiB reads values like "16318480 kB" from /proc files
+func parseKiB(value string) uint64 {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return 0
+	}
+	n, _ := strconv.ParseUint(fields[0], 10, 64)
+	return n * 1024
+}
+
+// readKeyValueFile reads files like /etc/os-release or /proc/meminfo into a
+// map, the first occurrence of a key wins.
+func readKeyValueFile(path, sep string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), sep)
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if _, seen := values[key]; !seen {
+			values[key] = strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return values, scanner.Err()
+}
+
+// commandOutput runs a small helper command and returns its trimmed output
+func commandOutput(name string, args ...string) (string, error) {
+	out, err := exec.Command(name, args...).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
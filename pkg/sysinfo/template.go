@@ -0,0 +1,238 @@
+package sysinfo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/ferrebarrat/brrtfetch/pkg/term"
+)
+
+// Fields a template can use besides the value of every module by its name,
+// like {cpu} or {uptime}
+var templateFields = map[string]func() (string, error){
+	"user": func() (string, error) { return userName(), nil },
+	"host": func() (string, error) { return os.Hostname() },
+
+	"os.name": osName,
+	"os.arch": func() (string, error) { return runtime.GOARCH, nil },
+
+	"cpu.model": cpuModel,
+	"cpu.cores": func() (string, error) { return strconv.Itoa(runtime.NumCPU()), nil },
+	"cpu.usage": collectCPUUsage,
+	"cpu.temp":  collectTemperature,
+	"cpu.freq": func() (string, error) {
+		mhz, err := cpuFrequency()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%.2f GHz", mhz/1000), nil
+	},
+
+	"memory.used": func() (string, error) {
+		used, _, err := memoryUsage()
+		return formatBytes(used), err
+	},
+	"memory.total": func() (string, error) {
+		_, total, err := memoryUsage()
+		if err == errUsageUnknown {
+			err = nil
+		}
+		return formatBytes(total), err
+	},
+	"memory.percent": func() (string, error) {
+		used, total, err := memoryUsage()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d%%", used*100/total), nil
+	},
+
+	"swap.used": func() (string, error) {
+		used, _, err := swapUsage()
+		return formatBytes(used), err
+	},
+	"swap.total": func() (string, error) {
+		_, total, err := swapUsage()
+		return formatBytes(total), err
+	},
+	"swap.percent": func() (string, error) {
+		used, total, err := swapUsage()
+		if err == nil && total == 0 {
+			err = errors.New("no swap")
+		}
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d%%", used*100/total), nil
+	},
+
+	"load.1":  loadField(0),
+	"load.5":  loadField(1),
+	"load.15": loadField(2),
+}
+
+// loadField returns one of the load averages as a template field
+func loadField(i int) func() (string, error) {
+	return func() (string, error) {
+		load, err := loadAverages()
+		if err != nil {
+			return "", err
+		}
+		return load[i], nil
+	}
+}
+
+// Hyperlinks makes the link tags of templates clickable (OSC 8), set it
+// before parsing them. Without, only the text of a link is shown.
+var Hyperlinks = false
+
+// A Template is a line of text with fields in braces that are filled in
+// from the built-in modules every time it's collected:
+//
+//	{icon.cpu} {cpu.model} ({cpu.cores}c) @ {cpu.freq}
+//
+// {#rrggbb} switches to a color and {/} back to the terminal's,
+// {link:https://...} makes the text up to {/link} a hyperlink, {{ and }}
+// are literal braces.
+type Template struct {
+	parts   []templatePart
+	colored bool // Has color tags, the line ends with a reset
+	linked  bool // Has hyperlinks, the line ends with the last one closed
+}
+
+// templatePart is literal text, a field or an escape sequence
+type templatePart struct {
+	text  string
+	field func() (string, error)
+}
+
+// ParseTemplate checks and parses a template. color turns the value of a
+// color tag, '#rrggbb', into SGR parameters for the terminal.
+func ParseTemplate(text string, color func(value string) (string, error)) (*Template, error) {
+	t := &Template{}
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			t.parts = append(t.parts, templatePart{text: literal.String()})
+			literal.Reset()
+		}
+	}
+	for i := 0; i < len(text); i++ {
+		switch {
+		case strings.HasPrefix(text[i:], "{{"), strings.HasPrefix(text[i:], "}}"):
+			literal.WriteByte(text[i])
+			i++
+		case text[i] == '}':
+			return nil, fmt.Errorf("unexpected } at %d, use }} for a brace", i)
+		case text[i] == '{':
+			end := strings.IndexByte(text[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated { at %d", i)
+			}
+			tag := strings.TrimSpace(text[i+1 : i+end])
+			i += end
+			part, err := parseTemplateTag(tag, color)
+			if err != nil {
+				return nil, err
+			}
+			if part.field == nil {
+				literal.WriteString(part.text)
+				t.colored = t.colored || strings.HasPrefix(tag, "#")
+				t.linked = t.linked || part.text != "" && strings.HasPrefix(tag, "link:")
+				continue
+			}
+			flush()
+			t.parts = append(t.parts, part)
+		default:
+			literal.WriteByte(text[i])
+		}
+	}
+	flush()
+	return t, nil
+}
+
+// parseTemplateTag resolves what's between the braces of a template
+func parseTemplateTag(tag string, color func(value string) (string, error)) (templatePart, error) {
+	switch {
+	case tag == "/":
+		return templatePart{text: sysInfoReset}, nil
+	case strings.HasPrefix(tag, "link:"):
+		url := strings.TrimSpace(strings.TrimPrefix(tag, "link:"))
+		if url == "" {
+			return templatePart{}, fmt.Errorf("{%s} has no URL", tag)
+		}
+		if strings.ContainsAny(url, "\x1b\a") {
+			return templatePart{}, fmt.Errorf("{%s}: control characters in the URL", tag)
+		}
+		if !Hyperlinks {
+			return templatePart{}, nil
+		}
+		return templatePart{text: term.HyperlinkStart(url)}, nil
+	case tag == "/link":
+		if !Hyperlinks {
+			return templatePart{}, nil
+		}
+		return templatePart{text: term.HyperlinkEnd}, nil
+	case strings.HasPrefix(tag, "#"):
+		params, err := color(tag)
+		if err != nil {
+			return templatePart{}, fmt.Errorf("{%s}: %v", tag, err)
+		}
+		return templatePart{text: "\x1b[" + params + "m"}, nil
+	case strings.HasPrefix(tag, "icon."):
+		// The icon set of -icons, Nerd Font ones when the keys have none
+		set := Icons
+		if set == IconsNone {
+			set = IconsNerd
+		}
+		icon, ok := iconSets[set][strings.TrimPrefix(tag, "icon.")]
+		if !ok {
+			return templatePart{}, fmt.Errorf("no icon for {%s}", tag)
+		}
+		return templatePart{text: icon}, nil
+	}
+	if field, ok := templateFields[tag]; ok {
+		return templatePart{field: field}, nil
+	}
+	if module, ok := sysInfoModules[tag]; ok {
+		return templatePart{field: module.Collect}, nil
+	}
+	if tag == "title" {
+		return templatePart{field: func() (string, error) { return collectTitle(), nil }}, nil
+	}
+	return templatePart{}, fmt.Errorf("unknown field {%s}", tag)
+}
+
+// Execute fills in the fields. A field that can't be found on this system
+// fails the whole line, like modules without their information are left
+// out.
+func (t *Template) Execute() (string, error) {
+	var line strings.Builder
+	for _, part := range t.parts {
+		if part.field == nil {
+			line.WriteString(part.text)
+			continue
+		}
+		value, err := part.field()
+		if err != nil {
+			return "", err
+		}
+		line.WriteString(value)
+	}
+	if t.colored {
+		line.WriteString(sysInfoReset)
+	}
+	if t.linked {
+		line.WriteString(term.HyperlinkEnd)
+	}
+	return line.String(), nil
+}
+
+// TemplateModule is a module whose value is the filled in template
+func TemplateModule(key string, t *Template) Module {
+	return Module{Key: key, Collect: t.Execute}
+}
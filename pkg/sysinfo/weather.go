@@ -0,0 +1,288 @@
+package sysinfo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Weather services the weather module can ask
+const (
+	WeatherWttr      = "wttr"       // wttr.in, finds the location from the IP when none is given
+	WeatherOpenMeteo = "open-meteo" // open-meteo.com, needs a location
+)
+
+// Units of the weather module
+const (
+	UnitsMetric   = "metric"   // °C and km/h
+	UnitsImperial = "imperial" // °F and mph
+)
+
+// WeatherOptions configures the weather module
+type WeatherOptions struct {
+	Backend  string
+	Location string // A place name or "latitude,longitude", empty for wttr's guess
+	Units    string
+
+	// Format of the line, with {icon}, {condition}, {temp}, {feels},
+	// {humidity}, {wind} and {location}
+	Format string
+
+	Timeout time.Duration // How long the service gets to answer
+
+	// The last weather is kept in CacheFile and reused for CacheTTL, and for
+	// as long as the service can't be reached. Empty = no cache.
+	CacheFile string
+	CacheTTL  time.Duration
+}
+
+// Weather configures the weather module, set it before collecting
+var Weather = WeatherOptions{
+	Backend: WeatherWttr,
+	Units:   UnitsMetric,
+	Format:  "{icon} {temp} {condition}",
+	Timeout: 3 * time.Second,
+}
+
+// Addresses of the weather services
+var (
+	wttrURL      = "https://wttr.in/"
+	openMeteoURL = "https://api.open-meteo.com/v1/forecast"
+	geocodingURL = "https://geocoding-api.open-meteo.com/v1/search"
+)
+
+// conditions is the current weather, in Weather.Units
+type conditions struct {
+	Condition string
+	Temp      float64
+	Feels     float64
+	Humidity  float64
+	Wind      float64
+	Location  string
+}
+
+// weatherCache is what's stored in Weather.CacheFile
+type weatherCache struct {
+	Options string // The options it was fetched with
+	Saved   time.Time
+	Now     conditions
+}
+
+// collectWeather shows the current weather in Weather.Format
+func collectWeather() (string, error) {
+	// Everything but the format changes what's fetched
+	options := fmt.Sprintf("%s|%s|%s", Weather.Backend, Weather.Location, Weather.Units)
+	var cached weatherCache
+	haveCache := false
+	if Weather.CacheFile != "" {
+		if data, err := os.ReadFile(Weather.CacheFile); err == nil && json.Unmarshal(data, &cached) == nil && cached.Options == options {
+			haveCache = true
+			if time.Since(cached.Saved) < Weather.CacheTTL {
+				return formatWeather(cached.Now), nil
+			}
+		}
+	}
+
+	now, err := fetchWeather()
+	if err != nil {
+		// Offline, yesterday's weather is better than none
+		if haveCache {
+			return formatWeather(cached.Now), nil
+		}
+		return "", err
+	}
+	if Weather.CacheFile != "" {
+		if data, err := json.Marshal(weatherCache{Options: options, Saved: time.Now(), Now: now}); err == nil {
+			os.MkdirAll(filepath.Dir(Weather.CacheFile), 0o755)
+			os.WriteFile(Weather.CacheFile, data, 0o644)
+		}
+	}
+	return formatWeather(now), nil
+}
+
+// formatWeather fills in Weather.Format
+func formatWeather(now conditions) string {
+	tempUnit, windUnit := "°C", "km/h"
+	if Weather.Units == UnitsImperial {
+		tempUnit, windUnit = "°F", "mph"
+	}
+	return strings.NewReplacer(
+		"{icon}", weatherIcon(now.Condition),
+		"{condition}", now.Condition,
+		"{temp}", fmt.Sprintf("%.0f%s", now.Temp, tempUnit),
+		"{feels}", fmt.Sprintf("%.0f%s", now.Feels, tempUnit),
+		"{humidity}", fmt.Sprintf("%.0f%%", now.Humidity),
+		"{wind}", fmt.Sprintf("%.0f %s", now.Wind, windUnit),
+		"{location}", now.Location,
+	).Replace(Weather.Format)
+}
+
+// weatherIcon picks an emoji for a description of the weather
+func weatherIcon(condition string) string {
+	c := strings.ToLower(condition)
+	switch {
+	case strings.Contains(c, "thunder"):
+		return "⛈"
+	case strings.Contains(c, "snow"), strings.Contains(c, "sleet"), strings.Contains(c, "ice"):
+		return "❄"
+	case strings.Contains(c, "rain"), strings.Contains(c, "drizzle"), strings.Contains(c, "shower"):
+		return "🌧"
+	case strings.Contains(c, "fog"), strings.Contains(c, "mist"), strings.Contains(c, "haze"):
+		return "🌫"
+	case strings.Contains(c, "partly"):
+		return "⛅"
+	case strings.Contains(c, "cloud"), strings.Contains(c, "overcast"):
+		return "☁"
+	}
+	return "☀"
+}
+
+// fetchWeather asks the service of Weather.Backend
+func fetchWeather() (conditions, error) {
+	switch Weather.Backend {
+	case WeatherWttr:
+		return fetchWttr()
+	case WeatherOpenMeteo:
+		return fetchOpenMeteo()
+	}
+	return conditions{}, fmt.Errorf("unknown weather backend %q", Weather.Backend)
+}
+
+// getJSON fetches rawURL and decodes the JSON answer into v
+func getJSON(rawURL string, v interface{}) error {
+	client := http.Client{Timeout: Weather.Timeout}
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Request.URL.Host, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// fetchWttr reads the current condition of wttr.in's JSON format
+func fetchWttr() (conditions, error) {
+	var answer struct {
+		CurrentCondition []struct {
+			TempC          string `json:"temp_C"`
+			TempF          string `json:"temp_F"`
+			FeelsLikeC     string
+			FeelsLikeF     string
+			Humidity       string `json:"humidity"`
+			WindspeedKmph  string
+			WindspeedMiles string
+			WeatherDesc    []struct{ Value string } `json:"weatherDesc"`
+		} `json:"current_condition"`
+		NearestArea []struct {
+			AreaName []struct{ Value string } `json:"areaName"`
+		} `json:"nearest_area"`
+	}
+	if err := getJSON(wttrURL+url.PathEscape(Weather.Location)+"?format=j1", &answer); err != nil {
+		return conditions{}, err
+	}
+	if len(answer.CurrentCondition) == 0 {
+		return conditions{}, errors.New("wttr.in: no current weather")
+	}
+	cur := answer.CurrentCondition[0]
+	number := func(s string) float64 {
+		n, _ := strconv.ParseFloat(s, 64)
+		return n
+	}
+	now := conditions{Temp: number(cur.TempC), Feels: number(cur.FeelsLikeC), Humidity: number(cur.Humidity), Wind: number(cur.WindspeedKmph), Location: Weather.Location}
+	if Weather.Units == UnitsImperial {
+		now.Temp, now.Feels, now.Wind = number(cur.TempF), number(cur.FeelsLikeF), number(cur.WindspeedMiles)
+	}
+	if len(cur.WeatherDesc) > 0 {
+		now.Condition = strings.TrimSpace(cur.WeatherDesc[0].Value)
+	}
+	if len(answer.NearestArea) > 0 && len(answer.NearestArea[0].AreaName) > 0 && now.Location == "" {
+		now.Location = answer.NearestArea[0].AreaName[0].Value
+	}
+	return now, nil
+}
+
+// fetchOpenMeteo looks up the location when it's a name, then asks
+// open-meteo for the weather there
+func fetchOpenMeteo() (conditions, error) {
+	if Weather.Location == "" {
+		return conditions{}, errors.New("open-meteo needs a location")
+	}
+	lat, lon, name := "", "", Weather.Location
+	if a, b, ok := strings.Cut(Weather.Location, ","); ok {
+		if _, err := strconv.ParseFloat(strings.TrimSpace(a), 64); err == nil {
+			lat, lon = strings.TrimSpace(a), strings.TrimSpace(b)
+		}
+	}
+	if lat == "" {
+		var places struct {
+			Results []struct {
+				Name                string
+				Latitude, Longitude float64
+			}
+		}
+		if err := getJSON(geocodingURL+"?count=1&name="+url.QueryEscape(Weather.Location), &places); err != nil {
+			return conditions{}, err
+		}
+		if len(places.Results) == 0 {
+			return conditions{}, fmt.Errorf("open-meteo doesn't know %q", Weather.Location)
+		}
+		place := places.Results[0]
+		lat, lon, name = fmt.Sprint(place.Latitude), fmt.Sprint(place.Longitude), place.Name
+	}
+
+	query := url.Values{
+		"latitude":  {lat},
+		"longitude": {lon},
+		"current":   {"temperature_2m,apparent_temperature,relative_humidity_2m,weather_code,wind_speed_10m"},
+	}
+	if Weather.Units == UnitsImperial {
+		query.Set("temperature_unit", "fahrenheit")
+		query.Set("wind_speed_unit", "mph")
+	}
+	var answer struct {
+		Current struct {
+			Temperature float64 `json:"temperature_2m"`
+			Apparent    float64 `json:"apparent_temperature"`
+			Humidity    float64 `json:"relative_humidity_2m"`
+			Code        int     `json:"weather_code"`
+			Wind        float64 `json:"wind_speed_10m"`
+		}
+	}
+	if err := getJSON(openMeteoURL+"?"+query.Encode(), &answer); err != nil {
+		return conditions{}, err
+	}
+	cur := answer.Current
+	return conditions{Condition: wmoCondition(cur.Code), Temp: cur.Temperature, Feels: cur.Apparent, Humidity: cur.Humidity, Wind: cur.Wind, Location: name}, nil
+}
+
+// wmoCondition describes a WMO weather code, as open-meteo reports them
+func wmoCondition(code int) string {
+	switch {
+	case code == 0:
+		return "Clear"
+	case code <= 2:
+		return "Partly cloudy"
+	case code == 3:
+		return "Overcast"
+	case code <= 48:
+		return "Fog"
+	case code <= 57:
+		return "Drizzle"
+	case code <= 67, code >= 80 && code <= 82:
+		return "Rain"
+	case code <= 77, code == 85, code == 86:
+		return "Snow"
+	case code >= 95:
+		return "Thunderstorm"
+	}
+	return "Unknown"
+}
@@ -0,0 +1,288 @@
+package term
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// VisibleWidth counts the terminal columns s takes up on screen. Escape
+// sequences (colors, cursor movement, hyperlinks, sixel images) take none,
+// wide characters like CJK and most emoji take two and combining marks none.
+func VisibleWidth(s string) int {
+	width := 0
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			i += EscapeLength(s[i:])
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		width += RuneWidth(r)
+	}
+	return width
+}
+
+// MaxVisibleWidth returns the width of the widest line on screen
+func MaxVisibleWidth(lines []string) int {
+	width := 0
+	for _, line := range lines {
+		if w := VisibleWidth(line); w > width {
+			width = w
+		}
+	}
+	return width
+}
+
+// StripEscapes removes every escape sequence from s, leaving only the text
+func StripEscapes(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			i += EscapeLength(s[i:])
+			continue
+		}
+		j := strings.IndexByte(s[i:], '\x1b')
+		if j < 0 {
+			j = len(s) - i
+		}
+		b.WriteString(s[i : i+j])
+		i += j
+	}
+	return b.String()
+}
+
+// TruncateWidth cuts s after maxWidth columns. Escape sequences are kept
+// even past the cut, so colors are still reset and images still drawn.
+func TruncateWidth(s string, maxWidth int) string {
+	if VisibleWidth(s) <= maxWidth {
+		return s
+	}
+
+	var b strings.Builder
+	width := 0
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			n := EscapeLength(s[i:])
+			b.WriteString(s[i : i+n])
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if w := RuneWidth(r); width+w <= maxWidth {
+			b.WriteString(s[i : i+size])
+			width += w
+		} else {
+			width = maxWidth // A wide character that doesn't fit ends the line
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// WrapWidth breaks s into lines of at most width columns, at the last space
+// that fits when there is one. Colors and a hyperlink that are on at a
+// break end with the line and carry on in the next one, which starts with
+// indent.
+func WrapWidth(s string, width int, indent string) []string {
+	var lines []string
+	var line, sgr strings.Builder // sgr holds the colors that are on
+	link := ""                    // The hyperlink that's open
+	lineWidth, limit := 0, width
+	spaceAt, spaceSGR, spaceLink := -1, "", "" // The last space of the line and the colors and link there
+	rest := s
+	for rest != "" {
+		if rest[0] == '\x1b' {
+			n := EscapeLength(rest)
+			seq := rest[:n]
+			line.WriteString(seq)
+			if l, ok := hyperlinkOf(seq); ok {
+				link = l
+			}
+			if strings.HasPrefix(seq, "\x1b[") && strings.HasSuffix(seq, "m") {
+				if seq == "\x1b[0m" || seq == "\x1b[m" {
+					sgr.Reset()
+				} else {
+					sgr.WriteString(seq)
+				}
+			}
+			rest = rest[n:]
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(rest)
+		w := RuneWidth(r)
+		if lineWidth+w > limit && lineWidth > 0 {
+			text := line.String()
+			next := rest
+			carried, carriedLink := sgr.String(), link
+			if spaceAt > 0 {
+				// Break at the space, what came after it moves down
+				next = text[spaceAt+1:] + rest
+				text, carried, carriedLink = text[:spaceAt], spaceSGR, spaceLink
+			}
+			if carried != "" {
+				text += "\x1b[0m"
+			}
+			if carriedLink != "" {
+				text += HyperlinkEnd
+			}
+			lines = append(lines, text)
+			line.Reset()
+			line.WriteString(indent)
+			line.WriteString(carried)
+			line.WriteString(carriedLink)
+			sgr.Reset()
+			sgr.WriteString(carried)
+			link = carriedLink
+			lineWidth, limit = 0, width-VisibleWidth(indent)
+			if limit < 1 {
+				limit = 1
+			}
+			spaceAt = -1
+			rest = strings.TrimLeft(next, " ")
+			continue
+		}
+		if r == ' ' && lineWidth > 0 {
+			spaceAt, spaceSGR, spaceLink = line.Len(), sgr.String(), link
+		}
+		line.WriteString(rest[:size])
+		lineWidth += w
+		rest = rest[size:]
+	}
+	return append(lines, line.String())
+}
+
+// EscapeLength returns the length in bytes of the escape sequence s starts
+// with. Unterminated sequences run to the end of s.
+func EscapeLength(s string) int {
+	if len(s) < 2 {
+		return len(s)
+	}
+
+	switch s[1] {
+	case '[':
+		// CSI: parameters and intermediates, up to the final byte @ to ~
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return i + 1
+			}
+		}
+		return len(s)
+	case ']', 'P', 'X', '^', '_':
+		// OSC, DCS (sixel), SOS, PM and APC strings end with ST (ESC \),
+		// OSC also with BEL
+		for i := 2; i < len(s); i++ {
+			if s[i] == '\a' && s[1] == ']' {
+				return i + 1
+			}
+			if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return len(s)
+	}
+
+	// Intermediates like the ( of ESC ( B, then one final byte
+	i := 1
+	for i < len(s) && s[i] >= 0x20 && s[i] <= 0x2f {
+		i++
+	}
+	if i < len(s) {
+		i++
+	}
+	return i
+}
+
+// RuneWidth returns how many columns r takes up in a terminal
+func RuneWidth(r rune) int {
+	switch {
+	case r < 0x20 || (r >= 0x7f && r < 0xa0):
+		return 0 // Control characters
+	case r == 0x200b || r == 0x200d || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0 // Combining marks and zero width characters
+	case isWide(r):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// Ranges of East Asian wide and fullwidth characters and emoji, which
+// terminals draw two columns wide
+var wideRanges = [][2]rune{
+	{0x1100, 0x115f},   // Hangul Jamo
+	{0x231a, 0x231b},   // Watch, hourglass
+	{0x2329, 0x232a},   // Angle brackets
+	{0x23e9, 0x23ec},   // Media controls
+	{0x23f0, 0x23f0},   // Alarm clock
+	{0x23f3, 0x23f3},   // Hourglass
+	{0x25fd, 0x25fe},   // Small squares
+	{0x2614, 0x2615},   // Umbrella, hot beverage
+	{0x2648, 0x2653},   // Zodiac
+	{0x267f, 0x267f},   // Wheelchair
+	{0x2693, 0x2693},   // Anchor
+	{0x26a1, 0x26a1},   // High voltage
+	{0x26aa, 0x26ab},   // Circles
+	{0x26bd, 0x26be},   // Soccer, baseball
+	{0x26c4, 0x26c5},   // Snowman, sun behind cloud
+	{0x26ce, 0x26ce},   // Ophiuchus
+	{0x26d4, 0x26d4},   // No entry
+	{0x26ea, 0x26ea},   // Church
+	{0x26f2, 0x26f3},   // Fountain, golf
+	{0x26f5, 0x26f5},   // Sailboat
+	{0x26fa, 0x26fa},   // Tent
+	{0x26fd, 0x26fd},   // Fuel pump
+	{0x2705, 0x2705},   // Check mark
+	{0x270a, 0x270b},   // Fists
+	{0x2728, 0x2728},   // Sparkles
+	{0x274c, 0x274c},   // Cross mark
+	{0x274e, 0x274e},   // Cross mark
+	{0x2753, 0x2755},   // Question marks
+	{0x2757, 0x2757},   // Exclamation mark
+	{0x2795, 0x2797},   // Plus, minus, division
+	{0x27b0, 0x27b0},   // Curly loop
+	{0x27bf, 0x27bf},   // Double curly loop
+	{0x2b1b, 0x2b1c},   // Large squares
+	{0x2b50, 0x2b50},   // Star
+	{0x2b55, 0x2b55},   // Circle
+	{0x2e80, 0x303e},   // CJK radicals, symbols and punctuation
+	{0x3041, 0x33ff},   // Kana, Bopomofo, CJK compatibility
+	{0x3400, 0x4dbf},   // CJK extension A
+	{0x4e00, 0x9fff},   // CJK unified ideographs
+	{0xa000, 0xa4cf},   // Yi
+	{0xa960, 0xa97f},   // Hangul Jamo extended A
+	{0xac00, 0xd7a3},   // Hangul syllables
+	{0xf900, 0xfaff},   // CJK compatibility ideographs
+	{0xfe10, 0xfe19},   // Vertical forms
+	{0xfe30, 0xfe6f},   // CJK compatibility forms, small forms
+	{0xff00, 0xff60},   // Fullwidth forms
+	{0xffe0, 0xffe6},   // Fullwidth signs
+	{0x16fe0, 0x18cff}, // Tangut, Khitan
+	{0x1b000, 0x1b2ff}, // Kana supplement and extended
+	{0x1f004, 0x1f004}, // Mahjong tile
+	{0x1f0cf, 0x1f0cf}, // Playing card
+	{0x1f18e, 0x1f18e}, // AB button
+	{0x1f191, 0x1f19a}, // Squared words
+	{0x1f200, 0x1f2ff}, // Enclosed ideographic supplement
+	{0x1f300, 0x1f64f}, // Pictographs, emoticons
+	{0x1f680, 0x1f6ff}, // Transport and map symbols
+	{0x1f7e0, 0x1f7eb}, // Colored circles and squares
+	{0x1f90c, 0x1f9ff}, // Supplemental symbols and pictographs
+	{0x1fa70, 0x1faff}, // Symbols and pictographs extended A
+	{0x20000, 0x3fffd}, // CJK extensions B and up
+}
+
+func isWide(r rune) bool {
+	if r < wideRanges[0][0] {
+		return false
+	}
+	for _, wr := range wideRanges {
+		if r < wr[0] {
+			return false
+		}
+		if r <= wr[1] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+package term
+
+import "os"
+
+// DetectCellAspect works out the width / height of a cell from the pixel
+// size of the terminal f is connected to. Not every terminal reports one.
+func DetectCellAspect(f *os.File) (float64, bool) {
+	cols, rows, err := Size(f)
+	if err != nil || cols <= 0 || rows <= 0 {
+		return 0, false
+	}
+	width, height, err := PixelSize(f)
+	if err != nil || width <= 0 || height <= 0 {
+		return 0, false
+	}
+	aspect := float64(width*rows) / float64(height*cols)
+	if aspect < 0.2 || aspect > 2 {
+		return 0, false // Not a size a real font has
+	}
+	return aspect, true
+}
@@ -0,0 +1,104 @@
+package term
+
+import (
+	"image/color"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Colors are the default colors of the terminal, as far as it tells
+type Colors struct {
+	Foreground, Background       color.RGBA
+	HasForeground, HasBackground bool
+}
+
+// Dark reports whether the terminal has a dark background, like most do. An
+// unknown background counts as dark.
+func (c Colors) Dark() bool {
+	bg := c.Background
+	return !c.HasBackground || 0.2126*float64(bg.R)+0.7152*float64(bg.G)+0.0722*float64(bg.B) < 128
+}
+
+// QueryColors asks the terminal for its background (OSC 11) and
+// foreground (OSC 10) colors. Terminals that don't support it stay silent,
+// the foreground is only asked for when the background was answered so they
+// only cost one timeout.
+func QueryColors(t *Terminal) Colors {
+	var c Colors
+	reply, _ := t.query("\033]11;?\a", '\a')
+	if c.Background, c.HasBackground = parseOSCColor(reply); c.HasBackground {
+		reply, _ = t.query("\033]10;?\a", '\a')
+		c.Foreground, c.HasForeground = parseOSCColor(reply)
+	}
+	return c
+}
+
+// TruecolorSupported sets a 24-bit background and asks the terminal to
+// report it back (DECRQSS). Terminals that round it to a palette color, or
+// don't know the request, don't give back the same color.
+func TruecolorSupported(t *Terminal) bool {
+	reply, _ := t.query("\033[48;2;1;2;3m\033P$qm\033\\\033[0m", '\\')
+	if !strings.Contains(reply, "1$r") {
+		return false
+	}
+	for _, set := range []string{"48;2;1;2;3", "48:2:1:2:3", "48:2::1:2:3"} {
+		if strings.Contains(reply, set) {
+			return true
+		}
+	}
+	return false
+}
+
+// The 16 basic colors as xterm draws them, in the order COLORFGBG numbers
+// them
+var basicColors = [16]color.RGBA{
+	{0, 0, 0, 255}, {205, 0, 0, 255}, {0, 205, 0, 255}, {205, 205, 0, 255},
+	{0, 0, 238, 255}, {205, 0, 205, 255}, {0, 205, 205, 255}, {229, 229, 229, 255},
+	{127, 127, 127, 255}, {255, 0, 0, 255}, {0, 255, 0, 255}, {255, 255, 0, 255},
+	{92, 92, 255, 255}, {255, 0, 255, 255}, {0, 255, 255, 255}, {255, 255, 255, 255},
+}
+
+// EnvColors reads the colors from COLORFGBG, which rxvt, Konsole and a few
+// other terminals set to "fg;bg" with numbers of the 16 basic colors. It's
+// the fallback for terminals that don't answer QueryColors, the colors are
+// only as exact as the terminal's palette is standard.
+func EnvColors() Colors {
+	var c Colors
+	fields := strings.Split(os.Getenv("COLORFGBG"), ";")
+	if len(fields) < 2 {
+		return c
+	}
+	if n, err := strconv.Atoi(fields[0]); err == nil && n >= 0 && n < len(basicColors) {
+		c.Foreground, c.HasForeground = basicColors[n], true
+	}
+	// rxvt puts "default" in between when it has a background image
+	if n, err := strconv.Atoi(fields[len(fields)-1]); err == nil && n >= 0 && n < len(basicColors) {
+		c.Background, c.HasBackground = basicColors[n], true
+	}
+	return c
+}
+
+// parseOSCColor reads the color from an OSC 10/11 reply such as
+// "\033]11;rgb:1e1e/1e1e/2e2e\a". Every channel has 1 to 4 hex digits.
+func parseOSCColor(reply string) (color.RGBA, bool) {
+	_, spec, ok := strings.Cut(reply, "rgb:")
+	if !ok {
+		return color.RGBA{}, false
+	}
+	spec = strings.TrimRight(spec, "\a\033\\")
+	parts := strings.Split(spec, "/")
+	if len(parts) != 3 {
+		return color.RGBA{}, false
+	}
+	var channels [3]uint8
+	for i, part := range parts {
+		v, err := strconv.ParseUint(part, 16, 16)
+		if err != nil || len(part) == 0 || len(part) > 4 {
+			return color.RGBA{}, false
+		}
+		full := uint64(1)<<(4*len(part)) - 1
+		channels[i] = uint8(v * 255 / full)
+	}
+	return color.RGBA{channels[0], channels[1], channels[2], 255}, true
+}
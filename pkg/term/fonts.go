@@ -0,0 +1,68 @@
+package term
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// NerdFontInstalled looks for a Nerd Font in the font directories of this
+// machine. known is false when the terminal may draw with fonts of another
+// one, as over SSH, and found can't tell.
+func NerdFontInstalled() (found, known bool) {
+	if os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_TTY") != "" {
+		return false, false
+	}
+	home, _ := os.UserHomeDir()
+	var dirs []string
+	switch runtime.GOOS {
+	case "windows":
+		dirs = []string{
+			filepath.Join(os.Getenv("LOCALAPPDATA"), "Microsoft", "Windows", "Fonts"),
+			filepath.Join(os.Getenv("WINDIR"), "Fonts"),
+		}
+	case "darwin":
+		dirs = []string{filepath.Join(home, "Library", "Fonts"), "/Library/Fonts"}
+	default:
+		dataHome := os.Getenv("XDG_DATA_HOME")
+		if dataHome == "" {
+			dataHome = filepath.Join(home, ".local", "share")
+		}
+		dirs = []string{filepath.Join(dataHome, "fonts"), filepath.Join(home, ".fonts"), "/usr/share/fonts", "/usr/local/share/fonts"}
+	}
+
+	for _, dir := range dirs {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			// Named like "JetBrainsMonoNerdFont-Regular.ttf" or, before
+			// Nerd Fonts 3, "Hack Nerd Font Complete.ttf"
+			if strings.Contains(strings.ToLower(strings.ReplaceAll(d.Name(), " ", "")), "nerdfont") {
+				found = true
+				return filepath.SkipAll
+			}
+			return nil
+		})
+		if found {
+			break
+		}
+	}
+	return found, true
+}
+
+// UnicodeSupported reports whether the terminal likely draws more than
+// ASCII: not the Linux console, and a UTF-8 locale when one is set
+func UnicodeSupported() bool {
+	if os.Getenv("TERM") == "linux" {
+		return false
+	}
+	for _, env := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := strings.ToLower(os.Getenv(env)); locale != "" {
+			return strings.Contains(locale, "utf-8") || strings.Contains(locale, "utf8")
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package term
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// HyperlinkEnd ends the link HyperlinkStart began
+const HyperlinkEnd = "\x1b]8;;\x1b\\"
+
+// HyperlinkStart makes the text that follows a link to url (OSC 8), until
+// HyperlinkEnd. Terminals without hyperlinks ignore both and show the text.
+func HyperlinkStart(url string) string {
+	return "\x1b]8;;" + url + "\x1b\\"
+}
+
+// hyperlinkOf returns the link an OSC 8 sequence starts, "" for one that
+// ends a link. ok is false for every other sequence.
+func hyperlinkOf(seq string) (link string, ok bool) {
+	if !strings.HasPrefix(seq, "\x1b]8;") {
+		return "", false
+	}
+	body := strings.TrimSuffix(strings.TrimSuffix(seq[4:], "\a"), "\x1b\\")
+	if i := strings.IndexByte(body, ';'); i < 0 || i == len(body)-1 {
+		return "", true // No URI, the link ends
+	}
+	return seq, true
+}
+
+// HyperlinksSupported reports whether the terminal likely makes OSC 8 links
+// clickable. There's no query for it, so it goes by the environment of
+// terminals known to have them. Others could print the links literally.
+func HyperlinksSupported() bool {
+	if os.Getenv("TMUX") != "" || strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		return false // Only passed on by recent versions, when configured
+	}
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "ghostty", "Hyper", "Tabby", "rio":
+		return true
+	}
+	if os.Getenv("WT_SESSION") != "" || os.Getenv("KITTY_WINDOW_ID") != "" || os.Getenv("KONSOLE_VERSION") != "" {
+		return true
+	}
+	if vte, err := strconv.Atoi(os.Getenv("VTE_VERSION")); err == nil && vte >= 5000 {
+		return true // GNOME Terminal, Tilix and other VTE terminals since 0.50
+	}
+	switch term := os.Getenv("TERM"); {
+	case term == "xterm-kitty", term == "alacritty", term == "xterm-ghostty", term == "wezterm",
+		strings.HasPrefix(term, "foot"), strings.HasPrefix(term, "contour"):
+		return true
+	}
+	return false
+}
@@ -0,0 +1,19 @@
+package term
+
+import "strings"
+
+// KittySupported asks whether the terminal draws images with the kitty
+// graphics protocol. The query is followed by a DA1 request every terminal
+// answers, those without the protocol only answer that. Inside tmux the
+// query goes to the terminal tmux runs in.
+func KittySupported(t *Terminal) bool {
+	query := "\033_Gi=31,s=1,v=1,a=q,t=d,f=24;AAAA\033\\"
+	if InTmux() {
+		query = TmuxPassthrough(query)
+	}
+	reply, err := t.query(query+"\033[c", 'c')
+	if err != nil {
+		return false
+	}
+	return strings.Contains(reply, "_Gi=31;OK")
+}
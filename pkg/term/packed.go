@@ -0,0 +1,63 @@
+package term
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"strings"
+)
+
+// PackedFrames keeps frames compressed in memory, for long animations that
+// would take a lot of it otherwise. A frame is only unpacked when it's
+// drawn. The first frame is the dictionary of all others, frames of an
+// animation look alike so most of them shrink to a small part of their size.
+// The zero value is empty and ready to use.
+type PackedFrames struct {
+	dict   []byte
+	frames [][]byte
+	size   int64
+}
+
+// PackFrames compresses frames
+func PackFrames(frames [][]string) *PackedFrames {
+	packed := &PackedFrames{}
+	for _, frame := range frames {
+		packed.Append(frame)
+	}
+	return packed
+}
+
+// Append compresses frame and adds it after the others
+func (f *PackedFrames) Append(frame []string) {
+	raw := []byte(strings.Join(frame, "\n"))
+	if f.dict == nil {
+		f.dict = raw
+	}
+	var b bytes.Buffer
+	w, _ := flate.NewWriterDict(&b, flate.BestSpeed, f.dict) // Only fails for invalid levels
+	w.Write(raw)
+	w.Close()
+	f.frames = append(f.frames, b.Bytes())
+	f.size += int64(b.Len())
+}
+
+// Len returns the number of frames
+func (f *PackedFrames) Len() int {
+	return len(f.frames)
+}
+
+// Size returns the bytes the compressed frames take, the dictionary included
+func (f *PackedFrames) Size() int64 {
+	return f.size + int64(len(f.dict))
+}
+
+// Frame unpacks frame i
+func (f *PackedFrames) Frame(i int) []string {
+	r := flate.NewReaderDict(bytes.NewReader(f.frames[i]), f.dict)
+	defer r.Close()
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil // Can't happen, the frames were compressed right here
+	}
+	return strings.Split(string(raw), "\n")
+}
@@ -0,0 +1,498 @@
+// Package term talks to the terminal: raw mode and size, escape sequence
+// aware string widths, color and sixel queries, and the Player that draws
+// frames in time.
+package term
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// Keys understood during playback, as delivered by Terminal.Keys
+const (
+	keyPause  = " "
+	keyFaster = "+"
+	keySlower = "-"
+	keyNext   = "right"
+	keyPrev   = "left"
+	keyQuit   = "q"
+)
+
+// Commands a Player takes from Control, for scripts and signals
+const (
+	CommandPause  = "pause"
+	CommandResume = "resume"
+	CommandToggle = "toggle" // Pause or resume, like the space key
+	CommandNext   = "next"   // Jump to the next of Starts
+	CommandSpeed  = "speed"  // Play at Command.Speed
+	CommandReload = "reload" // Stop with ErrReload
+)
+
+// A Command controls a running Player from outside
+type Command struct {
+	Name  string
+	Speed float64 // For CommandSpeed
+}
+
+// ErrReload is returned by Run when CommandReload asked to start over
+var ErrReload = errors.New("reload requested")
+
+// Playback speed limits and how much +/- change it
+const (
+	minSpeed  = 0.1
+	maxSpeed  = 10
+	speedStep = 1.25
+)
+
+// Quality levels of adaptive playback, each one draws less than the one
+// before
+const (
+	qualityFull     = iota
+	qualityNoColor  // escape sequences are left out
+	qualityHalfRate // and only every other frame is drawn
+)
+
+// How long drawing has to stay fast before adaptive playback tries the next
+// better quality. Every time that turns out too slow again it waits twice as
+// long.
+const adaptiveRestoreAfter = 5 * time.Second
+
+// Frames to leave on screen after playback, for Player.Exit
+const (
+	ExitAuto    = "auto"    // the one playback ended on, the first one when it was interrupted
+	ExitFirst   = "first"   // the first frame of the animation
+	ExitLast    = "last"    // the last frame of the animation
+	ExitCurrent = "current" // the one on screen when playback stopped
+	ExitNone    = "none"    // nothing
+)
+
+// Frame is a frame ready to be drawn, as handed out while streaming
+type Frame struct {
+	Lines []string
+	Delay time.Duration
+	First bool  // Set on the first frame of every loop
+	Err   error // Set when rendering failed, the stream ends after it
+}
+
+// Player draws the frames at the right time and reacts to keys and terminal
+// resizes while doing so.
+type Player struct {
+	Writer *bufio.Writer
+	Screen Screen
+
+	// Prerendered frames, or Stream when they didn't fit in memory. Packed
+	// are prerendered frames kept compressed, used instead of Frames when
+	// set.
+	Frames [][]string
+	Packed *PackedFrames
+	Delays []time.Duration
+	Stream <-chan Frame
+
+	// Loops is how many times the animation plays before Run returns, 0 =
+	// forever
+	Loops int
+
+	// Duration stops playback after this much wall-clock time, 0 = never
+	Duration time.Duration
+
+	// FrameSkip keeps playback on the timeline of the animation when drawing
+	// takes longer than the frames last: frames whose time already passed
+	// are skipped instead of slowing the whole animation down
+	FrameSkip bool
+
+	// Exit picks the frame ExitFrame returns, one of the Exit constants
+	Exit string
+
+	// AnyKeyQuits ends playback on every key instead of only q
+	AnyKeyQuits bool
+
+	// Control delivers Commands while playing. Starts are the frames where
+	// the inputs of a playlist start, for CommandNext.
+	Control <-chan Command
+	Starts  []int
+
+	// Adaptive lowers the quality when drawing can't keep up with the
+	// animation, first dropping the colors and then every other frame. The
+	// quality comes back once drawing is fast again.
+	Adaptive bool
+
+	// Info delivers new sysinfo lines, OnInfo is called with them and has to
+	// compose the frames again
+	Info   <-chan []string
+	OnInfo func(p *Player, sysInfo []string)
+
+	// OnResize is called after the terminal changed size and may replace
+	// the frames, it returns true when it did.
+	OnResize func(p *Player, cols, rows int) bool
+
+	// Suspend delivers Ctrl-Z, OnSuspend hands the terminal back, stops the
+	// process and takes the terminal again once it is continued
+	Suspend   <-chan os.Signal
+	OnSuspend func(p *Player)
+
+	cols   int
+	rows   int
+	index  int
+	lines  []string
+	delay  time.Duration
+	paused bool
+	speed  float64
+	played int
+	exit   atomic.Value // []string, the frame of ExitAuto
+	first  atomic.Value // []string
+	shown  atomic.Value // []string, the frame on screen
+
+	// Adaptive playback, see adapt
+	quality      int
+	drawCost     time.Duration // Average time drawing a frame takes
+	lowered      time.Time     // When the quality was last lowered
+	raised       time.Time     // When it was last raised
+	restoreAfter time.Duration
+}
+
+// ExitFrame returns the frame to leave on screen after playback as chosen by
+// Exit. By default that's the last one drawn when every loop was played or
+// the duration ran out, otherwise the first. It is nil until the first frame
+// was drawn and always with ExitNone.
+func (p *Player) ExitFrame() []string {
+	var lines []string
+	switch p.Exit {
+	case ExitNone:
+		return nil
+	case ExitFirst:
+		lines, _ = p.first.Load().([]string)
+	case ExitCurrent:
+		lines, _ = p.shown.Load().([]string)
+	case ExitLast:
+		// A stream never knows its last frame, the current one comes closest
+		if n := p.frameCount(); p.Stream == nil && n > 0 {
+			return p.frame(n - 1)
+		}
+		lines, _ = p.shown.Load().([]string)
+	default:
+		lines, _ = p.exit.Load().([]string)
+	}
+	return lines
+}
+
+// Run plays the animation until the quit key (any key with AnyKeyQuits) is
+// pressed, all loops were played or the duration ran out. keys is nil when
+// there is no terminal to read them from.
+// The error is from a stream that failed to render a frame.
+func (p *Player) Run(keys <-chan string, resized <-chan os.Signal) error {
+	p.speed = 1
+	p.cols, p.rows, _ = Size(os.Stdout)
+
+	var timeUp <-chan time.Time
+	if p.Duration > 0 {
+		limit := time.NewTimer(p.Duration)
+		defer limit.Stop()
+		timeUp = limit.C
+	}
+	if err := p.load(0); err != nil {
+		return err
+	}
+
+	// When the current frame was due, frames are timed from there instead
+	// of from when drawing them finished so slow writes don't add up
+	var due time.Time
+	var hold time.Duration // Time of the frame skipped at half rate
+	p.restoreAfter = adaptiveRestoreAfter
+	for {
+		p.draw()
+		if p.index == 0 {
+			p.exit.Store(p.lines)
+			p.first.Store(p.lines)
+		}
+
+		// Keys and resizes don't move the animation along, keep waiting for
+		// the same deadline after handling them
+		if !p.FrameSkip || p.paused || due.IsZero() {
+			due = time.Now()
+		}
+		deadline := due.Add(hold + p.wait())
+		hold = 0
+		step := 0
+		for step == 0 {
+			var tick <-chan time.Time
+			var timer *time.Timer
+			if !p.paused {
+				timer = time.NewTimer(time.Until(deadline))
+				tick = timer.C
+			}
+
+			redraw := false
+			select {
+			case <-tick:
+				step = 1
+			case <-timeUp:
+				if timer != nil {
+					timer.Stop()
+				}
+				p.exit.Store(p.lines)
+				return nil
+			case key, ok := <-keys:
+				if !ok {
+					keys = nil
+					break
+				}
+				if p.AnyKeyQuits {
+					key = keyQuit
+				}
+				switch key {
+				case keyQuit:
+					if timer != nil {
+						timer.Stop()
+					}
+					return nil
+				case keyPause:
+					p.paused = !p.paused
+					deadline = time.Now().Add(p.wait())
+				case keyFaster:
+					p.speed = clampSpeed(p.speed * speedStep)
+				case keySlower:
+					p.speed = clampSpeed(p.speed / speedStep)
+				case keyNext:
+					p.paused = true
+					step = 1
+				case keyPrev:
+					p.paused = true
+					step = -1
+				}
+			case cmd := <-p.Control:
+				switch cmd.Name {
+				case CommandPause, CommandResume, CommandToggle:
+					paused := cmd.Name == CommandPause || (cmd.Name == CommandToggle && !p.paused)
+					if p.paused && !paused {
+						deadline = time.Now().Add(p.wait())
+					}
+					p.paused = paused
+				case CommandSpeed:
+					p.speed = clampSpeed(cmd.Speed)
+				case CommandNext:
+					step = p.nextStart()
+				case CommandReload:
+					if timer != nil {
+						timer.Stop()
+					}
+					return ErrReload
+				}
+			case lines := <-p.Info:
+				p.OnInfo(p, lines)
+				if p.Stream == nil {
+					p.load(0) // Pick up the current frame with the new sysinfo
+				}
+				redraw = true
+			case <-resized:
+				cols, rows, _ := Size(os.Stdout)
+				p.cols, p.rows = cols, rows
+				p.Screen.Clear(p.Writer) // Wipe lines the old size wrapped
+				if p.OnResize != nil && p.OnResize(p, cols, rows) {
+					p.index, p.lines = 0, nil
+					if err := p.load(0); err != nil {
+						return err
+					}
+				}
+				redraw = true
+			case <-p.Suspend:
+				if timer != nil {
+					timer.Stop()
+				}
+				p.OnSuspend(p)
+				p.cols, p.rows, _ = Size(os.Stdout)
+				p.Screen.Clear(p.Writer)
+				// Carry on with the same frame, as if no time had passed
+				deadline = time.Now().Add(p.wait())
+				redraw = true
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			if redraw {
+				p.draw()
+			}
+		}
+		if done, err := p.advance(step); done || err != nil {
+			return err
+		}
+
+		// Drawing fell behind, skip the frames that should have been over by now
+		due = deadline
+		for p.FrameSkip && step == 1 && !p.paused && p.delay > 0 && time.Since(due) > p.wait() {
+			due = due.Add(p.wait())
+			if done, err := p.advance(1); done || err != nil {
+				return err
+			}
+		}
+		if p.quality >= qualityHalfRate && step == 1 && !p.paused {
+			hold = p.wait()
+			if done, err := p.advance(1); done || err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// nextStart returns the step to the next of Starts, the first one after
+// the last. 0 when there is nowhere to go, streams only move on by
+// themselves.
+func (p *Player) nextStart() int {
+	if len(p.Starts) < 2 || p.Stream != nil {
+		return 0
+	}
+	for _, start := range p.Starts {
+		if start > p.index {
+			return start - p.index
+		}
+	}
+	return p.frameCount() - p.index + p.Starts[0]
+}
+
+// draw draws the current frame at the current quality
+func (p *Player) draw() {
+	lines := p.lines
+	if p.quality >= qualityNoColor {
+		lines = make([]string, len(p.lines))
+		for i, line := range p.lines {
+			lines[i] = StripEscapes(line)
+		}
+	}
+	start := time.Now()
+	p.Screen.Draw(p.Writer, lines, p.cols, p.rows)
+	p.shown.Store(p.lines)
+	if p.Adaptive && !p.paused {
+		p.adapt(time.Since(start))
+	}
+}
+
+// adapt lowers the quality when drawing takes most of the time a frame is
+// shown and raises it again after drawing stayed quick for a while. A
+// quality that turned out too slow right after it came back is tried again
+// later and later, so playback doesn't keep flipping between two of them.
+func (p *Player) adapt(cost time.Duration) {
+	// Average over the last frames, a single slow write changes nothing
+	p.drawCost = (7*p.drawCost + cost) / 8
+	budget := p.wait()
+	now := time.Now()
+	switch {
+	case p.drawCost > budget*3/4 && p.quality < qualityHalfRate:
+		if now.Sub(p.raised) < p.restoreAfter {
+			p.restoreAfter *= 2
+		}
+		p.quality++
+		p.lowered = now
+		p.drawCost = 0
+		p.Screen.Invalidate()
+	case p.drawCost < budget/4 && p.quality > qualityFull && now.Sub(p.lowered) > p.restoreAfter:
+		p.quality--
+		p.raised = now
+		p.lowered = now // The next step up waits as long again
+		p.drawCost = 0
+		p.Screen.Invalidate()
+	}
+}
+
+// advance moves step frames away from the current one. done is set when
+// that ended the last loop, the frame before stays on screen then.
+func (p *Player) advance(step int) (done bool, err error) {
+	last := p.lines
+	if err := p.load(step); err != nil {
+		return false, err
+	}
+
+	// Only count loops the animation played by itself, not stepped through
+	if !p.paused && step == 1 && p.index == 0 {
+		p.played++
+		if p.Loops > 0 && p.played >= p.Loops {
+			p.exit.Store(last)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// wait is how long the current frame stays at the current speed
+func (p *Player) wait() time.Duration {
+	return time.Duration(float64(p.delay) / p.speed)
+}
+
+// load makes the frame step frames away the current one. Streams are
+// rendered on the fly, so they can only move forward.
+func (p *Player) load(step int) error {
+	if p.Stream != nil {
+		if p.lines != nil && step <= 0 {
+			return nil
+		}
+		frame := <-p.Stream
+		if frame.Err != nil {
+			return frame.Err
+		}
+		p.index++
+		if frame.First {
+			p.index = 0
+		}
+		p.lines, p.delay = frame.Lines, frame.Delay
+		return nil
+	}
+
+	n := p.frameCount()
+	p.index = ((p.index+step)%n + n) % n
+	p.lines, p.delay = p.frame(p.index), p.Delays[p.index]
+	return nil
+}
+
+// frameCount returns the number of prerendered frames, packed or not
+func (p *Player) frameCount() int {
+	if p.Packed != nil {
+		return p.Packed.Len()
+	}
+	return len(p.Frames)
+}
+
+// frame returns prerendered frame i, unpacked when it's packed
+func (p *Player) frame(i int) []string {
+	if p.Packed != nil {
+		return p.Packed.Frame(i)
+	}
+	return p.Frames[i]
+}
+
+func clampSpeed(speed float64) float64 {
+	if speed < minSpeed {
+		return minSpeed
+	}
+	if speed > maxSpeed {
+		return maxSpeed
+	}
+	return speed
+}
+
+// parseKeys turns the bytes read from the terminal into key names. Printable
+// keys are themselves, arrow keys are "up", "down", "right" and "left".
+func parseKeys(b []byte) []string {
+	var keys []string
+	for i := 0; i < len(b); {
+		if b[i] == 0x1b && i+2 < len(b) && (b[i+1] == '[' || b[i+1] == 'O') {
+			switch b[i+2] {
+			case 'A':
+				keys = append(keys, "up")
+			case 'B':
+				keys = append(keys, "down")
+			case 'C':
+				keys = append(keys, "right")
+			case 'D':
+				keys = append(keys, "left")
+			}
+			i += 3
+			continue
+		}
+		r, size := utf8.DecodeRune(b[i:])
+		keys = append(keys, string(r))
+		i += size
+	}
+	return keys
+}
@@ -0,0 +1,370 @@
+package term
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Unchanged cells shorter than this between two changes are simply written
+// again, that's cheaper than jumping over them with a cursor move.
+const diffMaxGap = 6
+
+// A Cell is one character on screen together with the SGR sequences (colors,
+// bold, ...) active for it since the last reset, and the OSC 8 sequence of
+// the hyperlink it's part of.
+type Cell struct {
+	Style string
+	Char  string
+	Link  string
+}
+
+// Screen remembers what the terminal currently shows so a frame only has to
+// send the cells that changed since the previous one. Over SSH or on slow
+// terminals that's a fraction of the bytes of a full redraw.
+type Screen struct {
+	Diff  bool     // only send the cells that changed
+	Sync  bool     // wrap frames in synchronized output mode (DEC 2026)
+	cells [][]Cell // nil when the screen content is unknown
+
+	// Inline draws at the cursor position instead of the top of the screen,
+	// moving the cursor relative to where it is. The screen around the
+	// frame is left alone.
+	Inline bool
+	row    int // Row of the cursor in the frame, inline only
+	height int // Rows drawn so far, inline only
+}
+
+// SyncSupported asks the terminal whether it knows synchronized output mode
+// (DECRQM for private mode 2026). A reply of 1 or 2 means set or reset, 0 and
+// 4 mean unknown or permanently off. Terminals without DECRQM stay silent.
+func SyncSupported(t *Terminal) bool {
+	reply, err := t.query("\033[?2026$p", 'y')
+	if err != nil {
+		return false
+	}
+	var mode, state int
+	if _, err := fmt.Sscanf(reply, "\033[?%d;%d$y", &mode, &state); err != nil {
+		return false
+	}
+	return mode == 2026 && (state == 1 || state == 2)
+}
+
+// Invalidate forgets the screen content, the next frame is drawn in full
+func (s *Screen) Invalidate() {
+	s.cells = nil
+}
+
+// Release moves the cursor below an inline frame and forgets the frame, the
+// next one starts at the cursor again. What's on screen stays there.
+func (s *Screen) Release(writer *bufio.Writer) {
+	if s.Inline && s.height > 0 {
+		s.moveTo(writer, s.height-1, 0)
+		writer.WriteString("\x1b[0m\n")
+	}
+	s.row, s.height = 0, 0
+	s.Invalidate()
+}
+
+// Clear wipes the screen, or inline everything from the top of the frame
+// down, and forgets its content
+func (s *Screen) Clear(writer *bufio.Writer) {
+	if s.Inline {
+		s.moveTo(writer, 0, 0)
+		writer.WriteString("\x1b[J")
+		s.height = 0
+	} else {
+		writer.WriteString("\033[2J")
+	}
+	s.Invalidate()
+}
+
+// Draw writes a frame from the top left corner. Lines that don't fit in the
+// terminal are left out and lines that are too wide are cut off, otherwise
+// the screen would scroll and every following frame would be drawn shifted.
+// maxCols and maxRows <= 0 mean unknown.
+func (s *Screen) Draw(writer *bufio.Writer, lines []string, maxCols, maxRows int) {
+	if maxRows > 0 && len(lines) > maxRows {
+		lines = lines[:maxRows]
+	}
+	if maxCols > 0 {
+		clipped := make([]string, len(lines))
+		for i, line := range lines {
+			clipped[i] = TruncateWidth(line, maxCols)
+		}
+		lines = clipped
+	}
+
+	// The terminal holds back everything between these, so a half written
+	// frame is never visible
+	if s.Sync {
+		writer.WriteString("\033[?2026h")
+	}
+
+	if !s.Diff {
+		s.drawFull(writer, lines)
+	} else {
+		next := make([][]Cell, len(lines))
+		for y, line := range lines {
+			next[y] = ParseCells(line)
+		}
+		// Inline frames can only grow by scrolling, which a full redraw does
+		if s.cells == nil || (s.Inline && len(lines) > s.height) {
+			s.drawFull(writer, lines)
+		} else {
+			s.drawDiff(writer, s.cells, next)
+		}
+		s.cells = next
+	}
+
+	if s.Sync {
+		writer.WriteString("\033[?2026l")
+	}
+	writer.Flush()
+}
+
+// drawFull rewrites every line
+func (s *Screen) drawFull(writer *bufio.Writer, lines []string) {
+	s.moveTo(writer, 0, 0)
+	for i, line := range lines {
+		if i > 0 {
+			writer.WriteByte('\n')
+		}
+		writer.WriteString(line)
+	}
+	if s.Inline && len(lines) > 0 {
+		s.row = len(lines) - 1
+		if len(lines) > s.height {
+			s.height = len(lines)
+		}
+	}
+}
+
+// drawDiff moves the cursor to every run of changed cells and only writes
+// those, switching styles and hyperlinks only when they differ from the ones
+// active.
+func (s *Screen) drawDiff(writer *bufio.Writer, prev, next [][]Cell) {
+	active := "\x00" // Unknown, forces the first style to be written
+	activeLink := ""
+	setLink := func(link string) {
+		switch {
+		case link == activeLink:
+			return
+		case link == "":
+			writer.WriteString(HyperlinkEnd)
+		default:
+			writer.WriteString(link)
+		}
+		activeLink = link
+	}
+	for y, row := range next {
+		var old []Cell
+		if y < len(prev) {
+			old = prev[y]
+		}
+		same := func(x int) bool {
+			return x < len(old) && old[x] == row[x]
+		}
+
+		for x := 0; x < len(row); {
+			if same(x) {
+				x++
+				continue
+			}
+
+			s.moveTo(writer, y, x)
+			for x < len(row) {
+				if same(x) {
+					// Keep writing through short unchanged gaps
+					gap := x
+					for gap < len(row) && gap-x < diffMaxGap && same(gap) {
+						gap++
+					}
+					if gap == len(row) || gap-x == diffMaxGap {
+						break
+					}
+				}
+				if row[x].Style != active {
+					writer.WriteString("\x1b[0m")
+					writer.WriteString(row[x].Style)
+					active = row[x].Style
+				}
+				setLink(row[x].Link)
+				writer.WriteString(row[x].Char)
+				x++
+			}
+		}
+
+		// Links don't go past the end of a line
+		setLink("")
+
+		// The old line was longer, wipe what's left of it
+		if len(old) > len(row) {
+			s.moveTo(writer, y, len(row))
+			writer.WriteString("\x1b[0m\x1b[K")
+			active = ""
+		}
+	}
+
+	// Lines the new frame doesn't have anymore
+	for y := len(next); y < len(prev); y++ {
+		s.moveTo(writer, y, 0)
+		writer.WriteString("\x1b[2K")
+	}
+
+	writer.WriteString("\x1b[0m")
+}
+
+// moveTo puts the cursor on a cell of the frame, counted from 0
+func (s *Screen) moveTo(writer *bufio.Writer, row, col int) {
+	if !s.Inline {
+		writer.WriteString("\x1b[")
+		writer.WriteString(strconv.Itoa(row + 1))
+		writer.WriteByte(';')
+		writer.WriteString(strconv.Itoa(col + 1))
+		writer.WriteByte('H')
+		return
+	}
+
+	writer.WriteByte('\r')
+	if row < s.row {
+		fmt.Fprintf(writer, "\x1b[%dA", s.row-row)
+	} else if row > s.row {
+		fmt.Fprintf(writer, "\x1b[%dB", row-s.row)
+	}
+	if col > 0 {
+		fmt.Fprintf(writer, "\x1b[%dC", col)
+	}
+	s.row = row
+}
+
+// ParseCells splits a rendered line into cells. SGR sequences are collected
+// as the style of the characters that follow them, cursor forward sequences
+// (used by some fetchers for alignment) become blank cells, hyperlinks are
+// kept with the cells they cover and any other escape sequence is dropped.
+// Wide characters are followed by an empty cell for their second column,
+// combining marks join the cell before them.
+func ParseCells(line string) []Cell {
+	var cells []Cell
+	var sgr Style
+	style, link := "", ""
+	for i := 0; i < len(line); {
+		if line[i] != 0x1b {
+			r, size := utf8.DecodeRuneInString(line[i:])
+			switch width := RuneWidth(r); {
+			case width == 0 && len(cells) > 0 && !unicode.IsControl(r):
+				cells[len(cells)-1].Char += line[i : i+size]
+			case width == 2:
+				cells = append(cells, Cell{Style: style, Char: line[i : i+size], Link: link}, Cell{Style: style, Link: link})
+			case width == 1:
+				cells = append(cells, Cell{Style: style, Char: line[i : i+size], Link: link})
+			}
+			i += size
+			continue
+		}
+
+		n := EscapeLength(line[i:])
+		if l, ok := hyperlinkOf(line[i : i+n]); ok {
+			link = l
+			i += n
+			continue
+		}
+		if n < 3 || line[i+1] != '[' {
+			i += n // Not a CSI sequence
+			continue
+		}
+
+		j := i + n - 1
+		params := line[i+2 : j]
+		switch line[j] {
+		case 'm':
+			sgr.Apply(params)
+			style = sgr.String()
+		case 'C':
+			n, err := strconv.Atoi(params)
+			if err != nil || n < 1 {
+				n = 1
+			}
+			for k := 0; k < n; k++ {
+				cells = append(cells, Cell{Style: style, Char: " ", Link: link})
+			}
+		}
+		i = j + 1
+	}
+	return cells
+}
+
+// Style is the SGR state built up by a line. Colors are kept apart from
+// the other attributes, so a new color replaces the previous one instead of
+// piling up: art only switches colors without resetting in between.
+type Style struct {
+	Attrs string // bold, underline, ... in the order they were set
+	FG    string // SGR parameters of the colors, e.g. "38;5;196", "" = default
+	BG    string
+}
+
+// Apply updates the style with the parameters of an SGR sequence
+func (s *Style) Apply(params string) {
+	fields := strings.Split(params, ";")
+	for i := 0; i < len(fields); i++ {
+		p := fields[i]
+		code, err := strconv.Atoi(p)
+		switch {
+		case p == "" || code == 0 && err == nil:
+			*s = Style{}
+		case code == 38 || code == 48:
+			// 38;5;n or 38;2;r;g;b
+			end := i + 3
+			if i+1 < len(fields) && fields[i+1] == "2" {
+				end = i + 5
+			}
+			if end > len(fields) {
+				end = len(fields)
+			}
+			color := strings.Join(fields[i:end], ";")
+			if code == 38 {
+				s.FG = color
+			} else {
+				s.BG = color
+			}
+			i = end - 1
+		case strings.HasPrefix(p, "38:"):
+			s.FG = p
+		case strings.HasPrefix(p, "48:"):
+			s.BG = p
+		case code == 39:
+			s.FG = "" // default color
+		case code == 49:
+			s.BG = ""
+		case code >= 30 && code <= 37, code >= 90 && code <= 97:
+			s.FG = p
+		case code >= 40 && code <= 47, code >= 100 && code <= 107:
+			s.BG = p
+		case s.Attrs == "":
+			s.Attrs = p
+		default:
+			s.Attrs += ";" + p
+		}
+	}
+}
+
+// String returns a single SGR sequence setting the style, "" for none
+func (s Style) String() string {
+	params := s.Attrs
+	for _, color := range []string{s.FG, s.BG} {
+		switch {
+		case color == "":
+		case params == "":
+			params = color
+		default:
+			params += ";" + color
+		}
+	}
+	if params == "" {
+		return ""
+	}
+	return "\x1b[" + params + "m"
+}
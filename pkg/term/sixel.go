@@ -0,0 +1,44 @@
+package term
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Cell size in pixels assumed when the terminal doesn't report it
+const (
+	defaultCellWidth  = 10
+	defaultCellHeight = 20
+)
+
+// SixelSupported asks the terminal for its primary device attributes (DA1).
+// Terminals that can draw sixel graphics list attribute 4 in the reply.
+func SixelSupported(t *Terminal) bool {
+	reply, err := t.query("\033[c", 'c')
+	return err == nil && sixelAttribute(reply)
+}
+
+// sixelAttribute tells whether a DA1 reply lists sixel graphics
+func sixelAttribute(reply string) bool {
+	reply = strings.TrimPrefix(reply, "\033[?")
+	reply = strings.TrimSuffix(reply, "c")
+	for _, attr := range strings.Split(reply, ";") {
+		if attr == "4" {
+			return true
+		}
+	}
+	return false
+}
+
+// CellPixelSize asks the terminal how many pixels a single character cell
+// covers (XTWINOPS 16), falling back to a common default.
+func CellPixelSize(t *Terminal) (int, int) {
+	reply, err := t.query("\033[16t", 't')
+	if err == nil {
+		var h, w int
+		if _, err := fmt.Sscanf(reply, "\033[6;%d;%dt", &h, &w); err == nil && w > 0 && h > 0 {
+			return w, h
+		}
+	}
+	return defaultCellWidth, defaultCellHeight
+}
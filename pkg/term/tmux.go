@@ -0,0 +1,26 @@
+package term
+
+import (
+	"os"
+	"strings"
+)
+
+// InTmux reports whether brrtfetch runs inside tmux
+func InTmux() bool {
+	return os.Getenv("TMUX") != ""
+}
+
+// TmuxPassthrough wraps seq so tmux hands it to the terminal it runs in
+// as it is, instead of interpreting it. tmux 3.3 and later only do that
+// with `set -g allow-passthrough on`.
+func TmuxPassthrough(seq string) string {
+	return "\033Ptmux;" + strings.ReplaceAll(seq, "\033", "\033\033") + "\033\\"
+}
+
+// TmuxSixelSupported asks the terminal tmux runs in for its primary device
+// attributes, like SixelSupported asks tmux itself. Passed through images
+// only show up when that one can draw sixel.
+func TmuxSixelSupported(t *Terminal) bool {
+	reply, err := t.query(TmuxPassthrough("\033[c"), 'c')
+	return err == nil && sixelAttribute(reply)
+}
@@ -0,0 +1,58 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd || windows)
+
+package term
+
+import (
+	"errors"
+	"os"
+)
+
+// Terminal is not supported on this platform, every query fails so callers
+// fall back to their defaults.
+type Terminal struct{}
+
+func Open() (*Terminal, error) {
+	return nil, errors.New("terminal queries are not supported on this platform")
+}
+
+func (t *Terminal) query(request string, terminator byte) (string, error) {
+	return "", errors.New("terminal queries are not supported on this platform")
+}
+
+func (t *Terminal) Keys() (<-chan string, error) {
+	return nil, errors.New("key presses are not supported on this platform")
+}
+
+func (t *Terminal) Close() error {
+	return nil
+}
+
+func Size(f *os.File) (cols, rows int, err error) {
+	return 0, 0, errors.New("terminal size is not supported on this platform")
+}
+
+func PixelSize(f *os.File) (width, height int, err error) {
+	return 0, 0, errors.New("terminal size is not supported on this platform")
+}
+
+// IsTerminal can't tell on this platform, so output is assumed to be one
+func IsTerminal(f *os.File) bool {
+	return true
+}
+
+// EnableVirtualTerminal does nothing, escape sequences are assumed to work
+func EnableVirtualTerminal(f *os.File) error {
+	return nil
+}
+
+// NotifyResize does nothing, there is no resize signal on this platform
+func NotifyResize(c chan<- os.Signal) {}
+
+// NotifySuspend does nothing, there is no suspend signal on this platform
+func NotifySuspend(c chan<- os.Signal) {}
+
+// NotifyControl does nothing, there are no user signals on this platform
+func NotifyControl(toggle, next chan<- os.Signal) {}
+
+// Suspend does nothing, processes can't be suspended on this platform
+func Suspend(t *Terminal) {}
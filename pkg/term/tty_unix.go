@@ -0,0 +1,214 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package term
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// Terminal is the controlling terminal, opened separately from stdout so
+// queries still work when the output is redirected.
+type Terminal struct {
+	file  *os.File
+	state syscall.Termios
+}
+
+// Open opens /dev/tty and remembers its current attributes so they
+// can be restored later.
+func Open() (*Terminal, error) {
+	f, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	t := &Terminal{file: f}
+	if err := ioctlTermios(f.Fd(), ioctlGetTermios, &t.state); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// makeRaw disables echo and line buffering. Reads return after at most
+// timeoutDs tenths of a second, even when nothing was typed.
+func (t *Terminal) makeRaw(timeoutDs uint8) error {
+	raw := t.state
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP |
+		syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 0
+	raw.Cc[syscall.VTIME] = timeoutDs
+	return ioctlTermios(t.file.Fd(), ioctlSetTermios, &raw)
+}
+
+// Keys switches the terminal to delivering single key presses without echo,
+// Ctrl-C and friends keep sending their signals. The presses are parsed by
+// parseKeys, the channel is closed when reading fails.
+func (t *Terminal) Keys() (<-chan string, error) {
+	mode := t.state
+	mode.Lflag &^= syscall.ECHO | syscall.ICANON
+	mode.Cc[syscall.VMIN] = 1
+	mode.Cc[syscall.VTIME] = 0
+	if err := ioctlTermios(t.file.Fd(), ioctlSetTermios, &mode); err != nil {
+		return nil, err
+	}
+
+	keys := make(chan string, 16)
+	go func() {
+		defer close(keys)
+		buf := make([]byte, 64)
+		for {
+			n, err := t.file.Read(buf)
+			if err != nil {
+				return
+			}
+			for _, key := range parseKeys(buf[:n]) {
+				keys <- key
+			}
+		}
+	}()
+	return keys, nil
+}
+
+// restore puts back the attributes the terminal had when it was opened
+func (t *Terminal) restore() error {
+	return ioctlTermios(t.file.Fd(), ioctlSetTermios, &t.state)
+}
+
+// Close restores the terminal and releases /dev/tty
+func (t *Terminal) Close() error {
+	err := t.restore()
+	if cerr := t.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// query writes an escape sequence and collects the reply up to and including
+// the terminator byte. Terminals that don't understand the query simply stay
+// silent, so a read timeout is reported as an error.
+func (t *Terminal) query(request string, terminator byte) (string, error) {
+	if err := t.makeRaw(2); err != nil {
+		return "", err
+	}
+	defer t.restore()
+
+	if _, err := t.file.WriteString(request); err != nil {
+		return "", err
+	}
+
+	var reply []byte
+	buf := make([]byte, 64)
+	for {
+		n, err := t.file.Read(buf)
+		if err != nil {
+			return string(reply), err
+		}
+		if n == 0 {
+			return string(reply), errors.New("terminal did not answer")
+		}
+		for _, b := range buf[:n] {
+			reply = append(reply, b)
+			if b == terminator {
+				return string(reply), nil
+			}
+		}
+	}
+}
+
+// Size returns the size in cells of the terminal f is connected to
+func Size(f *os.File) (cols, rows int, err error) {
+	ws, err := getWinsize(f)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(ws.Col), int(ws.Row), nil
+}
+
+// PixelSize returns the size in pixels of the terminal f is
+// connected to. Terminals that don't fill it in report 0 x 0.
+func PixelSize(f *os.File) (width, height int, err error) {
+	ws, err := getWinsize(f)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(ws.Xpixel), int(ws.Ypixel), nil
+}
+
+type winsize struct {
+	Row, Col, Xpixel, Ypixel uint16
+}
+
+func getWinsize(f *os.File) (winsize, error) {
+	var ws winsize
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 {
+		return ws, errno
+	}
+	return ws, nil
+}
+
+// IsTerminal reports whether f is connected to a terminal
+func IsTerminal(f *os.File) bool {
+	var state syscall.Termios
+	return ioctlTermios(f.Fd(), ioctlGetTermios, &state) == nil
+}
+
+// EnableVirtualTerminal does nothing, Unix terminals always understand
+// escape sequences
+func EnableVirtualTerminal(f *os.File) error {
+	return nil
+}
+
+// NotifyResize delivers SIGWINCH to c whenever the terminal changes size
+func NotifyResize(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGWINCH)
+}
+
+// NotifySuspend delivers SIGTSTP to c instead of stopping right away, so the
+// terminal can be put back first
+func NotifySuspend(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGTSTP)
+}
+
+// NotifyControl delivers SIGUSR1 to toggle and SIGUSR2 to next, the
+// shortcuts for pausing and skipping to the next GIF of a running instance
+func NotifyControl(toggle, next chan<- os.Signal) {
+	signal.Notify(toggle, syscall.SIGUSR1)
+	signal.Notify(next, syscall.SIGUSR2)
+}
+
+// Suspend stops brrtfetch the way Ctrl-Z would have and returns once
+// it is continued. Meanwhile t, if any, has the attributes it was opened with.
+func Suspend(t *Terminal) {
+	var mode syscall.Termios
+	if t != nil {
+		ioctlTermios(t.file.Fd(), ioctlGetTermios, &mode)
+		t.restore()
+	}
+
+	// Another thread may be the one that stops, so kill can return before
+	// the process did. Waiting for SIGCONT makes sure it was stopped.
+	continued := make(chan os.Signal, 1)
+	signal.Notify(continued, syscall.SIGCONT)
+	syscall.Kill(syscall.Getpid(), syscall.SIGSTOP)
+	<-continued
+	signal.Stop(continued)
+
+	if t != nil {
+		ioctlTermios(t.file.Fd(), ioctlSetTermios, &mode)
+	}
+}
+
+func ioctlTermios(fd uintptr, request uintptr, t *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(unsafe.Pointer(t)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
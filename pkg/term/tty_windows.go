@@ -0,0 +1,234 @@
+//go:build windows
+
+package term
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Console modes, see SetConsoleMode
+const (
+	enableProcessedInput            = 0x0001
+	enableLineInput                 = 0x0002
+	enableEchoInput                 = 0x0004
+	enableVirtualTerminalInput      = 0x0200
+	enableProcessedOutput           = 0x0001
+	enableVirtualTerminalProcessing = 0x0004
+)
+
+var (
+	kernel32                       = syscall.NewLazyDLL("kernel32.dll")
+	procSetConsoleMode             = kernel32.NewProc("SetConsoleMode")
+	procGetConsoleScreenBufferInfo = kernel32.NewProc("GetConsoleScreenBufferInfo")
+)
+
+// How often the console size is checked, Windows has no resize signal
+const resizePollInterval = 250 * time.Millisecond
+
+// Terminal is the console, opened separately from stdout so queries still
+// work when the output is redirected.
+type Terminal struct {
+	in, out *os.File
+	inMode  uint32
+}
+
+// Open opens the console input and output and remembers the input
+// mode so it can be restored later. Escape sequences are switched on for the
+// output, they are what every query is made of.
+func Open() (*Terminal, error) {
+	in, err := os.OpenFile("CONIN$", os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	out, err := os.OpenFile("CONOUT$", os.O_RDWR, 0)
+	if err != nil {
+		in.Close()
+		return nil, err
+	}
+	t := &Terminal{in: in, out: out}
+	if err := syscall.GetConsoleMode(syscall.Handle(in.Fd()), &t.inMode); err != nil {
+		in.Close()
+		out.Close()
+		return nil, err
+	}
+	if err := EnableVirtualTerminal(out); err != nil {
+		in.Close()
+		out.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// setInputMode turns off line input and echo and delivers keys as escape
+// sequences. With signals off Ctrl-C is read as a key instead.
+func (t *Terminal) setInputMode(signals bool) error {
+	mode := t.inMode&^(enableLineInput|enableEchoInput|enableProcessedInput) | enableVirtualTerminalInput
+	if signals {
+		mode |= enableProcessedInput
+	}
+	return setConsoleMode(t.in.Fd(), mode)
+}
+
+// restore puts back the input mode the console had when it was opened
+func (t *Terminal) restore() error {
+	return setConsoleMode(t.in.Fd(), t.inMode)
+}
+
+// Close restores the console and releases it
+func (t *Terminal) Close() error {
+	err := t.restore()
+	if cerr := t.in.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := t.out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// query writes an escape sequence and collects the reply up to and including
+// the terminator byte. Consoles that don't understand the query simply stay
+// silent, so waiting too long is reported as an error.
+func (t *Terminal) query(request string, terminator byte) (string, error) {
+	if err := t.setInputMode(false); err != nil {
+		return "", err
+	}
+	defer t.restore()
+
+	if _, err := t.out.WriteString(request); err != nil {
+		return "", err
+	}
+
+	var reply []byte
+	buf := make([]byte, 64)
+	for {
+		event, err := syscall.WaitForSingleObject(syscall.Handle(t.in.Fd()), 200)
+		if err != nil {
+			return string(reply), err
+		}
+		if event != syscall.WAIT_OBJECT_0 {
+			return string(reply), errors.New("terminal did not answer")
+		}
+		n, err := t.in.Read(buf)
+		if err != nil {
+			return string(reply), err
+		}
+		for _, b := range buf[:n] {
+			reply = append(reply, b)
+			if b == terminator {
+				return string(reply), nil
+			}
+		}
+	}
+}
+
+// Keys switches the console to delivering single key presses without echo,
+// Ctrl-C keeps interrupting. The presses are parsed by parseKeys, the channel
+// is closed when reading fails.
+func (t *Terminal) Keys() (<-chan string, error) {
+	if err := t.setInputMode(true); err != nil {
+		return nil, err
+	}
+
+	keys := make(chan string, 16)
+	go func() {
+		defer close(keys)
+		buf := make([]byte, 64)
+		for {
+			n, err := t.in.Read(buf)
+			if err != nil {
+				return
+			}
+			for _, key := range parseKeys(buf[:n]) {
+				keys <- key
+			}
+		}
+	}()
+	return keys, nil
+}
+
+// consoleScreenBufferInfo is CONSOLE_SCREEN_BUFFER_INFO
+type consoleScreenBufferInfo struct {
+	size              [2]int16
+	cursorPosition    [2]int16
+	attributes        uint16
+	window            [4]int16 // Left, top, right, bottom
+	maximumWindowSize [2]int16
+}
+
+// Size returns the size in cells of the console window f is
+// connected to
+func Size(f *os.File) (cols, rows int, err error) {
+	var info consoleScreenBufferInfo
+	r, _, e := procGetConsoleScreenBufferInfo.Call(f.Fd(), uintptr(unsafe.Pointer(&info)))
+	if r == 0 {
+		return 0, 0, e
+	}
+	return int(info.window[2]-info.window[0]) + 1, int(info.window[3]-info.window[1]) + 1, nil
+}
+
+// PixelSize is unknown, the console doesn't tell
+func PixelSize(f *os.File) (width, height int, err error) {
+	return 0, 0, errors.New("the console has no pixel size")
+}
+
+// IsTerminal reports whether f is connected to a console
+func IsTerminal(f *os.File) bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(f.Fd()), &mode) == nil
+}
+
+// EnableVirtualTerminal makes the console interpret ANSI escape sequences
+// written to f instead of printing them
+func EnableVirtualTerminal(f *os.File) error {
+	var mode uint32
+	if err := syscall.GetConsoleMode(syscall.Handle(f.Fd()), &mode); err != nil {
+		return err
+	}
+	return setConsoleMode(f.Fd(), mode|enableProcessedOutput|enableVirtualTerminalProcessing)
+}
+
+// resizeSignal is delivered by NotifyResize, Windows has no SIGWINCH
+type resizeSignal struct{}
+
+func (resizeSignal) String() string { return "resize" }
+func (resizeSignal) Signal()        {}
+
+// NotifyResize watches the console size and sends to c whenever it changed
+func NotifyResize(c chan<- os.Signal) {
+	go func() {
+		cols, rows, _ := Size(os.Stdout)
+		for range time.Tick(resizePollInterval) {
+			newCols, newRows, err := Size(os.Stdout)
+			if err != nil || (newCols == cols && newRows == rows) {
+				continue
+			}
+			cols, rows = newCols, newRows
+			select {
+			case c <- resizeSignal{}:
+			default:
+			}
+		}
+	}()
+}
+
+// NotifySuspend does nothing, consoles have no Ctrl-Z job control
+func NotifySuspend(c chan<- os.Signal) {}
+
+// NotifyControl does nothing, Windows has no SIGUSR1 and SIGUSR2
+func NotifyControl(toggle, next chan<- os.Signal) {}
+
+// Suspend does nothing, see NotifySuspend
+func Suspend(t *Terminal) {}
+
+func setConsoleMode(fd uintptr, mode uint32) error {
+	r, _, err := procSetConsoleMode.Call(fd, uintptr(mode))
+	if r == 0 {
+		return err
+	}
+	return nil
+}